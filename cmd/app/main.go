@@ -2,19 +2,43 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/elgntt/segmentation-service/internal/api"
 	"github.com/elgntt/segmentation-service/internal/config"
+	"github.com/elgntt/segmentation-service/internal/grpcapi"
 	"github.com/elgntt/segmentation-service/internal/pkg/db"
 	"github.com/elgntt/segmentation-service/internal/repository"
 	"github.com/elgntt/segmentation-service/internal/service"
+	"github.com/elgntt/segmentation-service/internal/worker"
+
+	"google.golang.org/grpc"
+)
+
+// Keys of the advisory locks electing the replica that runs a worker.
+const (
+	expirationWorkerLockKey  int64 = 1001
+	activationWorkerLockKey  int64 = 1002
+	purgeWorkerLockKey       int64 = 1003
+	reportFilesWorkerLockKey int64 = 1004
 )
 
+// reportJobsPollInterval is how often an idle report jobs worker looks for new jobs.
+const reportJobsPollInterval = 5 * time.Second
+
 // @title Segmentation Service
 // @version 1.0
 // @description API Dynamic User Segmentation service
+// @description Every route accepts two optional headers that are recorded in the history of the changes it makes:
+// @description "X-Actor-ID" is the id of the caller, "X-Request-ID" is a correlation id. Both are limited to 255 characters.
+// @description The request id is generated if it is not sent and is returned in the "X-Request-ID" response header.
 
 // @host localhost:8080
 // @BasePath /
@@ -25,54 +49,159 @@ func main() {
 		log.Fatal(err)
 	}
 
-	ctx := context.Background()
+	segmentCfg, err := config.GetSegmentConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reportCfg, err := config.GetReportConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	workerCfg, err := config.GetWorkerConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	httpCfg, err := config.GetHTTPConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	grpcCfg, err := config.GetGRPCConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	pool, err := db.OpenDB(ctx, dbCfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer pool.Close()
 
 	historyRepo := repository.NewHistoryRepo(pool)
 	segmentRepo := repository.NewSegmentRepo(pool)
 	userRepo := repository.NewUserRepo(pool)
+	reportJobRepo := repository.NewReportJobRepo(pool)
+	reportFileRepo := repository.NewReportFileRepo(pool)
+	transactor := repository.NewTransactor(pool)
 
 	historyService := service.NewHistoryService(
 		historyRepo,
 		segmentRepo,
+		reportJobRepo,
+		reportFileRepo,
+		transactor,
+		reportCfg,
+	)
+	segmentService := service.NewSegmentService(
+		segmentRepo,
+		historyRepo,
+		userRepo,
+		transactor,
+		segmentCfg,
+	)
+	userService := service.NewUserService(
+		userRepo,
+		segmentRepo,
+		historyRepo,
+		transactor,
 	)
 	r := api.New(
-		service.NewUserService(
-			userRepo,
-			segmentRepo,
-			historyRepo,
-		),
+		userService,
+		historyService,
+		segmentService,
+		httpCfg,
+	)
+	grpcServer := grpcapi.New(
+		userService,
+		segmentService,
 		historyService,
-		service.NewSegmentService(
-			segmentRepo,
-			historyRepo,
-			userRepo,
-		),
+		grpcCfg.RequestTimeout,
 	)
 
-	go ClearExpiredSegmentsWorker(ctx, historyService)
+	// the leader locks share one connection outside the pool
+	lockSession := repository.NewLockSession(pool)
+	defer lockSession.Close(context.Background())
 
-	serverCfg := config.GetServerConfig()
+	workers := []*worker.Worker{
+		worker.New("expiration", workerCfg.ExpirationInterval, repository.NewAdvisoryLock(lockSession, expirationWorkerLockKey),
+			func(ctx context.Context) (int, error) {
+				return historyService.DeleteExpiredUserSegments(ctx, workerCfg.BatchSize)
+			}),
+		worker.New("activation", workerCfg.ActivationInterval, repository.NewAdvisoryLock(lockSession, activationWorkerLockKey),
+			func(ctx context.Context) (int, error) {
+				return historyService.ActivateScheduledUserSegments(ctx, workerCfg.BatchSize)
+			}),
+		worker.New("purge", workerCfg.PurgeInterval, repository.NewAdvisoryLock(lockSession, purgeWorkerLockKey), segmentService.PurgeDeletedSegments),
+		worker.New("report_files", 10*time.Minute, repository.NewAdvisoryLock(lockSession, reportFilesWorkerLockKey), historyService.DeleteExpiredReportFiles),
+	}
 
-	log.Println("Server has been successfully started on the port:" + serverCfg.HTTPPort)
-	log.Fatal(r.Run(serverCfg.HTTPPort))
-}
+	// report jobs are claimed with SKIP LOCKED, so every replica runs its own pool
+	for i := 0; i < reportCfg.Workers; i++ {
+		workers = append(workers, worker.New("report_jobs", reportJobsPollInterval, nil, historyService.ProcessReportJobs))
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker.Worker) {
+			defer wg.Done()
+			w.Run(ctx)
+		}(w)
+	}
 
-func ClearExpiredSegmentsWorker(ctx context.Context, s *service.HistoryService) {
-	workerInterval := time.NewTicker(1 * time.Minute)
-
-	for {
-		select {
-		case <-workerInterval.C:
-			err := s.DeleteExpiredUserSegments(ctx)
-			if err != nil {
-				log.Println("Worker err:", err)
-			}
-			log.Println("Success")
+	serverCfg := config.GetServerConfig()
+	server := &http.Server{
+		Addr:    serverCfg.HTTPPort,
+		Handler: r,
+	}
+	go func() {
+		log.Println("Server has been successfully started on the port:" + serverCfg.HTTPPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	grpcListener, err := net.Listen("tcp", serverCfg.GRPCPort)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		log.Println("gRPC server has been successfully started on the port:" + serverCfg.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal(err)
 		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), workerCfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server shutdown err:", err)
 	}
+	gracefulStopGRPC(shutdownCtx, grpcServer)
+	wg.Wait()
+}
+
+// gracefulStopGRPC waits for the calls in progress to finish and cancels them when ctx is done.
+func gracefulStopGRPC(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
 
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Println("gRPC server shutdown err:", ctx.Err())
+		s.Stop()
+	}
 }
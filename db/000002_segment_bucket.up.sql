@@ -0,0 +1,10 @@
+-- segment_bucket deterministically maps a user to one of 10000 buckets of a segment.
+-- A user belongs to a rollout of N percent when its bucket is below N * 100, so raising
+-- the percent only adds users and lowering it only removes them.
+CREATE OR REPLACE FUNCTION segment_bucket(segment_id INT, user_id INT) RETURNS INT AS $$
+    SELECT ((hashtextextended(segment_id || ':' || user_id, 0) % 10000 + 10000) % 10000)::INT
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- auto_joined marks memberships made by the auto-join rollout. Lowering the rollout percent removes only these,
+-- memberships added through the API stay.
+ALTER TABLE users_segments ADD COLUMN auto_joined BOOLEAN NOT NULL DEFAULT FALSE;
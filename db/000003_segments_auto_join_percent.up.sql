@@ -0,0 +1 @@
+ALTER TABLE segments ADD COLUMN auto_join_percent INT NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+INSERT INTO users (id)
+SELECT DISTINCT user_id
+FROM users_segments
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE users_segments
+    ADD CONSTRAINT users_segments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
@@ -0,0 +1,9 @@
+ALTER TABLE segments
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN owner       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN tags        TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX segments_owner_idx ON segments (owner);
+CREATE INDEX segments_tags_idx ON segments USING GIN (tags);
@@ -0,0 +1,5 @@
+ALTER TABLE segments ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- A slug only has to be unique among segments that are not archived.
+ALTER TABLE segments DROP CONSTRAINT segments_slug_key;
+CREATE UNIQUE INDEX segments_slug_active_idx ON segments (slug) WHERE deleted_at IS NULL;
@@ -0,0 +1,20 @@
+ALTER TABLE user_segment_history ADD COLUMN segment_id INT;
+
+UPDATE user_segment_history h
+SET segment_id = (
+    SELECT s.id
+    FROM segments s
+    WHERE s.slug = h.segment_slug
+    ORDER BY s.deleted_at DESC NULLS FIRST
+    LIMIT 1
+);
+
+CREATE INDEX user_segment_history_segment_id_idx ON user_segment_history (segment_id);
+
+-- Old slugs of renamed segments, they keep resolving to the segment until expires_at.
+CREATE TABLE segment_slug_aliases (
+    slug       VARCHAR(255) PRIMARY KEY,
+    segment_id INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
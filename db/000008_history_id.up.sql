@@ -0,0 +1,3 @@
+ALTER TABLE user_segment_history ADD COLUMN id BIGSERIAL PRIMARY KEY;
+
+CREATE INDEX user_segment_history_operation_time_id_idx ON user_segment_history (operation_time, id);
@@ -0,0 +1,16 @@
+CREATE TABLE report_jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    params       JSONB NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'pending',
+    attempts     INT NOT NULL DEFAULT 0,
+    rows_total   BIGINT,
+    rows_written BIGINT NOT NULL DEFAULT 0,
+    file_name    TEXT,
+    error        TEXT,
+    run_after    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX report_jobs_status_run_after_idx ON report_jobs (status, run_after);
@@ -0,0 +1,2 @@
+-- Membership at a point in time is the last adding/removal event of each user and segment before it.
+CREATE INDEX user_segment_history_user_segment_time_idx ON user_segment_history (user_id, segment_id, operation_time, id);
@@ -0,0 +1,5 @@
+-- Where a change came from: api, ttl_expiry, segment_deleted, auto_join or import. NULL for entries recorded before.
+ALTER TABLE user_segment_history ADD COLUMN source VARCHAR(32);
+-- The X-Actor-ID and X-Request-ID headers of the request that made the change.
+ALTER TABLE user_segment_history ADD COLUMN actor_id VARCHAR(255);
+ALTER TABLE user_segment_history ADD COLUMN request_id VARCHAR(255);
@@ -0,0 +1,14 @@
+-- Keep in sync with model.Operation.
+CREATE TYPE history_operation AS ENUM (
+    'adding',
+    'removal',
+    'restored',
+    'expiration_changed',
+    'renamed',
+    'segment_created',
+    'segment_deleted'
+);
+
+ALTER TABLE user_segment_history
+    ALTER COLUMN operation TYPE history_operation USING operation::history_operation,
+    ALTER COLUMN operation SET NOT NULL;
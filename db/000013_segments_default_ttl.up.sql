@@ -0,0 +1,2 @@
+-- Expiration of memberships added without an explicit expiration time, NULL if they do not expire.
+ALTER TABLE segments ADD COLUMN default_ttl INTERVAL;
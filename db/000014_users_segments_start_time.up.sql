@@ -0,0 +1,5 @@
+-- Start of a scheduled membership. The activation worker sets it to NULL after recording the adding entry, so NULL means the
+-- membership has been activated.
+ALTER TABLE users_segments ADD COLUMN start_time TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX users_segments_start_time_idx ON users_segments (start_time) WHERE start_time IS NOT NULL;
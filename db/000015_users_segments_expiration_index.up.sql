@@ -0,0 +1,2 @@
+-- Lets the expiration worker pick expired memberships in batches without scanning the whole table.
+CREATE INDEX users_segments_expiration_time_idx ON users_segments (expiration_time) WHERE expiration_time IS NOT NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE report_files (
+    name       TEXT PRIMARY KEY,
+    size       BIGINT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX report_files_updated_at_idx ON report_files (updated_at);
+
+CREATE TABLE report_file_parts (
+    file_name TEXT NOT NULL REFERENCES report_files (name) ON DELETE CASCADE,
+    part      INT NOT NULL,
+    data      BYTEA NOT NULL,
+    PRIMARY KEY (file_name, part)
+);
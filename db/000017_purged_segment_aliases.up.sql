@@ -0,0 +1,3 @@
+-- Slug aliases of segments purged before the purge started deleting them.
+DELETE FROM segment_slug_aliases a
+WHERE NOT EXISTS (SELECT 1 FROM segments s WHERE s.id = a.segment_id);
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CancelReportJob
+// @Summary CancelReportJob
+// @Tags History
+// @Description Cancels a pending or running report job
+// @Produce application/json
+// @Param 	id path int true "report job id"
+// @Success 200
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /history/reports/{id}/cancel [post]
+// @Router /v2/history/reports/{id}/cancel [post]
+func (h *handler) CancelReportJob(c *gin.Context) {
+	ctx := requestContext(c)
+
+	id, err := parseReportJobId(c.Param("id"))
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	err = h.historyService.CancelReportJob(ctx, id)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMismatchesLimit = 100
+	maxMismatchesLimit     = 1000
+)
+
+type MembershipConsistencyResponse struct {
+	Consistent bool                       `json:"consistent"`
+	Mismatches []model.MembershipMismatch `json:"mismatches"`
+}
+
+// CheckMembershipConsistency
+// @Summary CheckMembershipConsistency
+// @Tags History
+// @Description Replays the whole history and diffs the resulting memberships against the live ones of not deleted segments
+// @Produce application/json
+// @Param 	limit query int false "mismatches to return, 100 by default, 1000 at most"
+// @Success 200 {object} api.MembershipConsistencyResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /history/consistency [get]
+func (h *handler) CheckMembershipConsistency(c *gin.Context) {
+	ctx := requestContext(c)
+
+	limit := defaultMismatchesLimit
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxMismatchesLimit {
+			response.WriteErrorResponse(c, ErrInvalidLimitParameter)
+			return
+		}
+	}
+
+	mismatches, err := h.historyService.CheckMembershipConsistency(ctx, limit)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MembershipConsistencyResponse{
+		Consistent: len(mismatches) == 0,
+		Mismatches: mismatches,
+	})
+}
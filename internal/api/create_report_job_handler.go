@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateReportJob
+// @Summary CreateReportJob
+// @Tags History
+// @Description Queues generation of a report file, a history report or a segment report by kind. Empty filters match all entries, format is csv by default. Poll GetReportJob for the status and the download link
+// @Accept application/json
+// @Produce application/json
+// @Param 	input body model.ReportJobParams true "report filters and format"
+// @Success 202 {object} model.ReportJobState
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /history/reports [post]
+// @Router /v2/history/reports [post]
+func (h *handler) CreateReportJob(c *gin.Context) {
+	ctx := requestContext(c)
+	request := model.ReportJobParams{}
+
+	if err := c.BindJSON(&request); err != nil {
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
+		return
+	}
+
+	if request.Format == "" {
+		request.Format = report.FormatCSV
+	}
+
+	if err := validateReportJobParams(request); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	job, err := h.historyService.CreateReportJob(ctx, request)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func validateReportJobParams(params model.ReportJobParams) error {
+	v := validation.Validator{}
+	if v.Check(len(params.UserIDs) <= maxReportJobFilter, "userIds", validation.CodeTooMany, fmt.Sprintf("at most %d users are allowed", maxReportJobFilter)) {
+		for i, userId := range params.UserIDs {
+			model.CheckUserID(&v, fmt.Sprintf("userIds[%d]", i), userId)
+		}
+	}
+	if v.Check(len(params.SegmentSlugs) <= maxReportJobFilter, "slugs", validation.CodeTooMany, fmt.Sprintf("at most %d segments are allowed", maxReportJobFilter)) {
+		for i, slug := range params.SegmentSlugs {
+			v.SegmentSlug(fmt.Sprintf("slugs[%d]", i), slug)
+		}
+	}
+	v.Check(report.IsFormat(params.Format), "format", validation.CodeInvalid, "unknown report format")
+	if _, err := report.ParseDelimiter(params.Delimiter); err != nil {
+		v.Add("delimiter", validation.CodeInvalid, "invalid csv delimiter")
+	}
+	if params.From != nil && params.To != nil {
+		v.Check(params.From.Before(*params.To), "from", validation.CodeOutOfRange, `"from" must be before "to"`)
+	}
+	v.Check(params.Limit >= 0, "limit", validation.CodeOutOfRange, "limit must not be negative")
+
+	return v.Err()
+}
@@ -1,12 +1,11 @@
 package api
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/elgntt/segmentation-service/internal/model"
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
 	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,14 +18,17 @@ import (
 // @Param input body model.AddSegment true "segment info"
 // @Success 201
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
 // @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
 // @Router /segment [post]
+// @Router /v2/segments [post]
 func (h *handler) CreateSegment(c *gin.Context) {
-	ctx := context.Background()
+	ctx := requestContext(c)
 	request := model.AddSegment{}
 
 	if err := c.BindJSON(&request); err != nil {
-		response.WriteErrorResponse(c, app_err.NewBusinessError("invalid request body"))
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
 		return
 	}
 
@@ -45,12 +47,8 @@ func (h *handler) CreateSegment(c *gin.Context) {
 }
 
 func validateReqData(segmentData model.AddSegment) error {
-	if segmentData.SegmentSlug == "" {
-		return app_err.NewBusinessError(ErrInvalidSegmentSlug)
-	}
-	if segmentData.AutoJoinPercent < 0 || segmentData.AutoJoinPercent > 100 {
-		return app_err.NewBusinessError(ErrInvalidAutoJoinPercent)
-	}
+	v := validation.Validator{}
+	segmentData.Validate(&v)
 
-	return nil
+	return v.Err()
 }
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateUser
+// @Summary CreateUser
+// @Tags User
+// @Description Registers a user and adds it to the auto-join segments its bucket falls into
+// @Produce application/json
+// @Param input body model.AddUser true "user info"
+// @Success 201
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /user [post]
+// @Router /v2/users [post]
+func (h *handler) CreateUser(c *gin.Context) {
+	ctx := requestContext(c)
+	request := model.AddUser{}
+
+	if err := c.BindJSON(&request); err != nil {
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
+		return
+	}
+
+	if !model.IsValidUserID(request.UserID) {
+		response.WriteErrorResponse(c, ErrInvalidUserId)
+		return
+	}
+
+	err := h.userService.CreateUser(ctx, request.UserID)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusCreated)
+}
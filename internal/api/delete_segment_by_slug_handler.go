@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteSegmentBySlug
+// @Summary DeleteSegmentBySlug
+// @Tags Segment
+// @Description Deletes the segment like DELETE /segment, with the slug in the path instead of the body
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Success 204
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /v2/segments/{slug} [delete]
+func (h *handler) DeleteSegmentBySlug(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	err := h.segmentService.DeleteSegment(ctx, slug)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
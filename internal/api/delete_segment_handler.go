@@ -1,10 +1,8 @@
 package api
 
 import (
-	"context"
 	"net/http"
 
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
 	response "github.com/elgntt/segmentation-service/internal/pkg/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,19 +15,21 @@ type DeleteSegmentRequest struct {
 // DeleteSegment
 // @Summary DeleteSegment
 // @Tags Segment
-// @Description Delete segment
+// @Description Delete segment. The segment is archived and can be restored within the grace period
 // @Produce application/json
 // @Param input body api.DeleteSegmentRequest true "segment info"
 // @Success 200
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
 // @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
 // @Router /segment [delete]
 func (h *handler) DeleteSegment(c *gin.Context) {
-	ctx := context.Background()
+	ctx := requestContext(c)
 	request := DeleteSegmentRequest{}
 
 	if err := c.BindJSON(&request); err != nil {
-		response.WriteErrorResponse(c, app_err.NewBusinessError("invalid request body"))
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
 		return
 	}
 
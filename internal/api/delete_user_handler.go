@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteUser
+// @Summary DeleteUser
+// @Tags User
+// @Description Deletes a user and removes it from all of its segments
+// @Produce application/json
+// @Param 	id path int true "user id"
+// @Success 200
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /user/{id} [delete]
+// @Router /v2/users/{id} [delete]
+func (h *handler) DeleteUser(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil || !model.IsValidUserID(userId) {
+		response.WriteErrorResponse(c, ErrInvalidUserId)
+		return
+	}
+
+	ctx := requestContext(c)
+	err = h.userService.DeleteUser(ctx, userId)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
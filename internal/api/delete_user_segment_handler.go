@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteUserSegment
+// @Summary DeleteUserSegment
+// @Tags User
+// @Description Removes the user from the segment, nothing happens if the user is not in the segment
+// @Produce application/json
+// @Param 	id path int true "user id"
+// @Param 	slug path string true "segment slug"
+// @Success 204
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /v2/users/{id}/segments/{slug} [delete]
+func (h *handler) DeleteUserSegment(c *gin.Context) {
+	ctx := requestContext(c)
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil || !model.IsValidUserID(userId) {
+		response.WriteErrorResponse(c, ErrInvalidUserId)
+		return
+	}
+
+	slug := c.Param("slug")
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	err = h.userService.UserSegmentAction(ctx, model.UserSegmentAction{
+		UserID:                userId,
+		SegmentsSlugsToRemove: []string{slug},
+	})
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
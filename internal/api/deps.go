@@ -2,21 +2,46 @@ package api
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
 )
 
 // todo make private
 type userService interface {
 	GetActiveUserSegments(ctx context.Context, userId int) ([]string, error)
 	UserSegmentAction(ctx context.Context, userSegment model.UserSegmentAction) error
+	CreateUser(ctx context.Context, userId int) error
+	DeleteUser(ctx context.Context, userId int) error
+	ImportUsers(ctx context.Context, usersIDs []int) (model.ImportUsersResult, error)
 }
 
 type segmentService interface {
 	CreateSegment(ctx context.Context, segmentData model.AddSegment) error
 	DeleteSegment(ctx context.Context, slug string) error
+	RestoreSegment(ctx context.Context, slug string) error
+	RenameSegment(ctx context.Context, slug, newSlug string) error
+	UpdateSegment(ctx context.Context, slug string, segmentData model.UpdateSegment) (model.Segment, error)
+	GetSegment(ctx context.Context, slug string) (model.Segment, error)
+	GetSegments(ctx context.Context, filter model.SegmentFilter) ([]model.Segment, error)
+	GetSegmentsPage(ctx context.Context, params model.SegmentsPageParams) (model.SegmentsPage, error)
+	GetSegmentMembers(ctx context.Context, slug string, cursor, limit int) (model.SegmentMembersPage, error)
 }
 
 type historyService interface {
-	GenerateCSVFile(ctx context.Context, month, year, userId int) (string, error)
+	GetHistory(ctx context.Context, filter model.HistoryFilter) (model.HistoryPage, error)
+	GenerateReportFile(ctx context.Context, month, year, userId int, opts report.Options) (string, error)
+	WriteReport(ctx context.Context, month, year, userId int, opts report.Options, w io.Writer) error
+	GenerateSegmentReportFile(ctx context.Context, kind string, filter model.HistoryFilter, limit int, opts report.Options) (string, error)
+	WriteSegmentReport(ctx context.Context, kind string, filter model.HistoryFilter, limit int, opts report.Options, w io.Writer) error
+	GetReportFile(ctx context.Context, fileName string, expiresAt int64, signature string) (model.ReportFile, error)
+	WriteReportFile(ctx context.Context, fileName string, w io.Writer) error
+	CreateReportJob(ctx context.Context, params model.ReportJobParams) (model.ReportJobState, error)
+	GetReportJob(ctx context.Context, id int64) (model.ReportJobState, error)
+	CancelReportJob(ctx context.Context, id int64) error
+	GetUserSegmentsAt(ctx context.Context, userId int, at time.Time) ([]string, error)
+	GetSegmentUsersAt(ctx context.Context, slug string, at time.Time) ([]int, error)
+	CheckMembershipConsistency(ctx context.Context, limit int) ([]model.MembershipMismatch, error)
 }
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadReportFile
+// @Summary DownloadReportFile
+// @Tags History
+// @Description Sends a report file generated by GetReportFile, GetSegmentReportFile or a report job. The link is signed and works until it expires
+// @Produce application/octet-stream
+// @Param 	name path string true "file name"
+// @Param 	expires query int true "link expiration time, unix seconds"
+// @Param 	signature query string true "link signature"
+// @Success 200 {file} file
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /assets/csv_reports/{name} [get]
+func (h *handler) DownloadReportFile(c *gin.Context) {
+	ctx := requestContext(c)
+	name := c.Param("name")
+
+	expiresAt, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		response.WriteErrorResponse(c, ErrInvalidReportLink)
+		return
+	}
+
+	file, err := h.historyService.GetReportFile(ctx, name, expiresAt, c.Query("signature"))
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", `attachment; filename="`+file.Name+`"`)
+	c.Header("Content-Length", strconv.FormatInt(file.Size, 10))
+	c.Status(http.StatusOK)
+
+	err = h.historyService.WriteReportFile(ctx, file.Name, c.Writer)
+	if err != nil {
+		if c.Writer.Written() {
+			// the status is already sent, the client gets a truncated file
+			log.Println("Sending report file err:", err)
+			return
+		}
+
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.Writer.Header().Del("Content-Length")
+		response.WriteErrorResponse(c, err)
+	}
+}
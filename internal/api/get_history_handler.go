@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+type HistoryResponse struct {
+	Entries    []model.History `json:"entries"`
+	NextCursor *string         `json:"nextCursor"`
+}
+
+// GetHistory
+// @Summary GetHistory
+// @Tags History
+// @Description Lists history entries ordered by operation time. Pass nextCursor of the response as cursor to get the next page
+// @Produce application/json
+// @Param 	userIds query string false "comma separated user ids"
+// @Param 	slugs query string false "comma separated segment slugs, previous slugs of renamed segments match too"
+// @Param 	operation query string false "adding, removal, restored, expiration_changed, renamed, segment_created or segment_deleted"
+// @Param 	from query string false "start of the time range (inclusive), RFC 3339"
+// @Param 	to query string false "end of the time range (exclusive), RFC 3339"
+// @Param 	cursor query string false "nextCursor of the previous page"
+// @Param 	limit query int false "entries per page, 100 by default, 1000 at most"
+// @Success 200 {object} api.HistoryResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /history [get]
+// @Router /v2/history [get]
+func (h *handler) GetHistory(c *gin.Context) {
+	ctx := requestContext(c)
+
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	page, err := h.historyService.GetHistory(ctx, filter)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	resp := HistoryResponse{
+		Entries: page.Entries,
+	}
+	if page.NextCursor != nil {
+		cursor := page.NextCursor.String()
+		resp.NextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func parseHistoryFilter(c *gin.Context) (model.HistoryFilter, error) {
+	filter := model.HistoryFilter{
+		Operation: model.Operation(c.Query("operation")),
+		Limit:     defaultHistoryLimit,
+	}
+
+	for _, userIdQuery := range splitQueryList(c.Query("userIds")) {
+		userId, err := strconv.Atoi(userIdQuery)
+		if err != nil || !model.IsValidUserID(userId) {
+			return model.HistoryFilter{}, ErrInvalidUserIdsParameter
+		}
+		filter.UserIDs = append(filter.UserIDs, userId)
+	}
+
+	for _, slug := range splitQueryList(c.Query("slugs")) {
+		if err := validateSegmentSlug(slug); err != nil {
+			return model.HistoryFilter{}, err
+		}
+		filter.SegmentSlugs = append(filter.SegmentSlugs, slug)
+	}
+
+	if fromQuery := c.Query("from"); fromQuery != "" {
+		from, err := time.Parse(time.RFC3339, fromQuery)
+		if err != nil {
+			return model.HistoryFilter{}, ErrInvalidFromParameter
+		}
+		filter.From = &from
+	}
+
+	if toQuery := c.Query("to"); toQuery != "" {
+		to, err := time.Parse(time.RFC3339, toQuery)
+		if err != nil {
+			return model.HistoryFilter{}, ErrInvalidToParameter
+		}
+		filter.To = &to
+	}
+
+	if cursorQuery := c.Query("cursor"); cursorQuery != "" {
+		cursor, err := model.ParseHistoryCursor(cursorQuery)
+		if err != nil {
+			return model.HistoryFilter{}, ErrInvalidCursorParameter
+		}
+		filter.After = &cursor
+	}
+
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		limit, err := strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			return model.HistoryFilter{}, ErrInvalidLimitParameter
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func splitQueryList(query string) []string {
+	if query == "" {
+		return nil
+	}
+
+	var values []string
+	for _, value := range strings.Split(query, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
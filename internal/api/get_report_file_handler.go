@@ -2,15 +2,23 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+	"github.com/elgntt/segmentation-service/internal/model"
 	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	reportModeLink   = "link"
+	reportModeStream = "stream"
+)
+
 type parameters struct {
 	Month  int
 	Year   int
@@ -24,17 +32,27 @@ type responseUrl struct {
 // GetReportFile
 // @Summary GetReportFile
 // @Tags History
-// @Description Allows you to get a link to a csv file with the user's history for the transferred month-year
+// @Description Returns the user's history for the transferred month-year as a report file. By default responds with a signed link to the file that expires after a while, with mode=stream sends the file itself
 // @Produce application/json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/vnd.apache.parquet
 // @Param 	month query int true "actual month"
 // @Param 	year query int true "actual year"
 // @Param 	userId query int true "actual userId"
+// @Param 	mode query string false "link (default) or stream"
+// @Param 	format query string false "csv (default), json, ndjson, xlsx or parquet"
+// @Param 	delimiter query string false "csv field delimiter, ; by default, tab for the tab character"
+// @Param 	header query bool false "add a header row to csv"
 // @Success 200 {object} api.responseUrl
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
 // @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
 // @Router /history/file [get]
 func (h *handler) GetReportFile(c *gin.Context) {
-	ctx := context.Background()
+	ctx := requestContext(c)
 
 	params, err := parseParameters(c.Query("month"), c.Query("year"), c.Query("userId"))
 	if err != nil {
@@ -42,7 +60,23 @@ func (h *handler) GetReportFile(c *gin.Context) {
 		return
 	}
 
-	filePath, err := h.historyService.GenerateCSVFile(ctx, params.Month, params.Year, params.UserId)
+	opts, err := parseReportOptions(c)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	switch c.DefaultQuery("mode", reportModeLink) {
+	case reportModeLink:
+	case reportModeStream:
+		h.streamReportFile(ctx, c, params, opts)
+		return
+	default:
+		response.WriteErrorResponse(c, ErrInvalidModeParameter)
+		return
+	}
+
+	filePath, err := h.historyService.GenerateReportFile(ctx, params.Month, params.Year, params.UserId, opts)
 	if err != nil {
 		response.WriteErrorResponse(c, err)
 		return
@@ -53,15 +87,58 @@ func (h *handler) GetReportFile(c *gin.Context) {
 	})
 }
 
+func (h *handler) streamReportFile(ctx context.Context, c *gin.Context, params parameters, opts report.Options) {
+	fileName := fmt.Sprintf("history_%d_%d-%02d%s", params.UserId, params.Year, params.Month, report.Extension(opts.Format))
+	c.Header("Content-Type", report.ContentType(opts.Format))
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+
+	err := h.historyService.WriteReport(ctx, params.Month, params.Year, params.UserId, opts, c.Writer)
+	if err != nil {
+		if c.Writer.Written() {
+			// the status is already sent, the client gets a truncated file
+			log.Println("Streaming report err:", err)
+			return
+		}
+
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		response.WriteErrorResponse(c, err)
+	}
+}
+
+func parseReportOptions(c *gin.Context) (report.Options, error) {
+	opts := report.Options{
+		Format: c.DefaultQuery("format", report.FormatCSV),
+	}
+	if !report.IsFormat(opts.Format) {
+		return report.Options{}, ErrInvalidFormatParameter
+	}
+
+	var err error
+	opts.Delimiter, err = report.ParseDelimiter(c.Query("delimiter"))
+	if err != nil {
+		return report.Options{}, ErrInvalidDelimiterParameter
+	}
+
+	if headerQuery := c.Query("header"); headerQuery != "" {
+		opts.Header, err = strconv.ParseBool(headerQuery)
+		if err != nil {
+			return report.Options{}, ErrInvalidHeaderParameter
+		}
+	}
+
+	return opts, nil
+}
+
 func parseParameters(monthQuery, yearQuery string, userIdQuery string) (parameters, error) {
 	if yearQuery == "" {
-		return parameters{}, app_err.NewBusinessError(ErrInvalidYearParameter)
+		return parameters{}, ErrInvalidYearParameter
 	}
 	if monthQuery == "" {
-		return parameters{}, app_err.NewBusinessError(ErrInvalidMonthParameter)
+		return parameters{}, ErrInvalidMonthParameter
 	}
 	if userIdQuery == "" {
-		return parameters{}, app_err.NewBusinessError(ErrInvalidUserIdParameter)
+		return parameters{}, ErrInvalidUserIdParameter
 	}
 
 	var err error
@@ -69,17 +146,17 @@ func parseParameters(monthQuery, yearQuery string, userIdQuery string) (paramete
 
 	params.Year, err = strconv.Atoi(yearQuery)
 	if err != nil {
-		return parameters{}, app_err.NewBusinessError(ErrInvalidYearParameter)
+		return parameters{}, ErrInvalidYearParameter
 	}
 
 	params.Month, err = strconv.Atoi(monthQuery)
 	if err != nil {
-		return parameters{}, app_err.NewBusinessError(ErrInvalidMonthParameter)
+		return parameters{}, ErrInvalidMonthParameter
 	}
 
 	params.UserId, err = strconv.Atoi(userIdQuery)
-	if err != nil {
-		return parameters{}, app_err.NewBusinessError(ErrInvalidUserIdParameter)
+	if err != nil || !model.IsValidUserID(params.UserId) {
+		return parameters{}, ErrInvalidUserIdParameter
 	}
 
 	return params, nil
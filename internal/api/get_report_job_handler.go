@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReportJob
+// @Summary GetReportJob
+// @Tags History
+// @Description Returns the status and progress of a report job. Done jobs have a download link until it expires
+// @Produce application/json
+// @Param 	id path int true "report job id"
+// @Success 200 {object} model.ReportJobState
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /history/reports/{id} [get]
+// @Router /v2/history/reports/{id} [get]
+func (h *handler) GetReportJob(c *gin.Context) {
+	ctx := requestContext(c)
+
+	id, err := parseReportJobId(c.Param("id"))
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	job, err := h.historyService.GetReportJob(ctx, id)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func parseReportJobId(idParam string) (int64, error) {
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil || id < 1 {
+		return 0, ErrInvalidReportJobId
+	}
+
+	return id, nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSegment
+// @Summary GetSegment
+// @Tags Segment
+// @Description Returns the segment with its metadata
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Success 200 {object} model.Segment
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /v2/segments/{slug} [get]
+func (h *handler) GetSegment(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	segment, err := h.segmentService.GetSegment(ctx, slug)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, segment)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSegmentMembersLimit = 100
+	maxSegmentMembersLimit     = 1000
+)
+
+// GetSegmentMembers
+// @Summary GetSegmentMembers
+// @Tags Segment
+// @Description Lists active (not expired) members of the segment ordered by userId. Pass nextCursor of the response as cursor to get the next page
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Param 	cursor query int false "userId after which the page starts"
+// @Param 	limit query int false "members per page, 100 by default, 1000 at most"
+// @Success 200 {object} model.SegmentMembersPage
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segments/{slug}/users [get]
+// @Router /v2/segments/{slug}/users [get]
+func (h *handler) GetSegmentMembers(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	cursor := 0
+	if cursorQuery := c.Query("cursor"); cursorQuery != "" {
+		var err error
+		cursor, err = strconv.Atoi(cursorQuery)
+		if err != nil || cursor < 0 {
+			response.WriteErrorResponse(c, ErrInvalidCursorParameter)
+			return
+		}
+	}
+
+	limit := defaultSegmentMembersLimit
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxSegmentMembersLimit {
+			response.WriteErrorResponse(c, ErrInvalidLimitParameter)
+			return
+		}
+	}
+
+	page, err := h.segmentService.GetSegmentMembers(ctx, slug, cursor, limit)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSegmentChurnLimit = 10
+
+type segmentReportParameters struct {
+	Kind   string
+	Filter model.HistoryFilter
+	Limit  int
+}
+
+// GetSegmentReportFile
+// @Summary GetSegmentReportFile
+// @Tags History
+// @Description Returns a report on membership changes of all users by segment for a time range. segmentDaily counts added and removed users of every segment per day, segmentChurn lists the segments with the most added and removed users. By default responds with a signed link to the file, with mode=stream sends the file itself
+// @Produce application/json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/vnd.apache.parquet
+// @Param 	kind query string false "segmentDaily (default) or segmentChurn"
+// @Param 	slugs query string false "comma separated segment slugs, all segments if empty"
+// @Param 	from query string false "start of the time range (inclusive), RFC 3339"
+// @Param 	to query string false "end of the time range (exclusive), RFC 3339"
+// @Param 	limit query int false "number of segments in a segmentChurn report, 10 by default, 0 for all"
+// @Param 	mode query string false "link (default) or stream"
+// @Param 	format query string false "csv (default), json, ndjson, xlsx or parquet"
+// @Param 	delimiter query string false "csv field delimiter, ; by default, tab for the tab character"
+// @Param 	header query bool false "add a header row to csv"
+// @Success 200 {object} api.responseUrl
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /history/segments/file [get]
+func (h *handler) GetSegmentReportFile(c *gin.Context) {
+	ctx := requestContext(c)
+
+	params, err := parseSegmentReportParameters(c)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	opts, err := parseReportOptions(c)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	switch c.DefaultQuery("mode", reportModeLink) {
+	case reportModeLink:
+	case reportModeStream:
+		h.streamSegmentReportFile(ctx, c, params, opts)
+		return
+	default:
+		response.WriteErrorResponse(c, ErrInvalidModeParameter)
+		return
+	}
+
+	filePath, err := h.historyService.GenerateSegmentReportFile(ctx, params.Kind, params.Filter, params.Limit, opts)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responseUrl{
+		URL: filePath,
+	})
+}
+
+func (h *handler) streamSegmentReportFile(ctx context.Context, c *gin.Context, params segmentReportParameters, opts report.Options) {
+	c.Header("Content-Type", report.ContentType(opts.Format))
+	c.Header("Content-Disposition", `attachment; filename="`+params.Kind+report.Extension(opts.Format)+`"`)
+
+	err := h.historyService.WriteSegmentReport(ctx, params.Kind, params.Filter, params.Limit, opts, c.Writer)
+	if err != nil {
+		if c.Writer.Written() {
+			// the status is already sent, the client gets a truncated file
+			log.Println("Streaming segment report err:", err)
+			return
+		}
+
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		response.WriteErrorResponse(c, err)
+	}
+}
+
+func parseSegmentReportParameters(c *gin.Context) (segmentReportParameters, error) {
+	params := segmentReportParameters{
+		Kind:  c.DefaultQuery("kind", model.ReportKindSegmentDaily),
+		Limit: defaultSegmentChurnLimit,
+	}
+	if params.Kind != model.ReportKindSegmentDaily && params.Kind != model.ReportKindSegmentChurn {
+		return segmentReportParameters{}, ErrInvalidKindParameter
+	}
+
+	for _, slug := range splitQueryList(c.Query("slugs")) {
+		if err := validateSegmentSlug(slug); err != nil {
+			return segmentReportParameters{}, err
+		}
+		params.Filter.SegmentSlugs = append(params.Filter.SegmentSlugs, slug)
+	}
+
+	if fromQuery := c.Query("from"); fromQuery != "" {
+		from, err := time.Parse(time.RFC3339, fromQuery)
+		if err != nil {
+			return segmentReportParameters{}, ErrInvalidFromParameter
+		}
+		params.Filter.From = &from
+	}
+
+	if toQuery := c.Query("to"); toQuery != "" {
+		to, err := time.Parse(time.RFC3339, toQuery)
+		if err != nil {
+			return segmentReportParameters{}, ErrInvalidToParameter
+		}
+		params.Filter.To = &to
+	}
+
+	if params.Filter.From != nil && params.Filter.To != nil && !params.Filter.From.Before(*params.Filter.To) {
+		return segmentReportParameters{}, ErrInvalidTimeRange
+	}
+
+	if limitQuery := c.Query("limit"); limitQuery != "" {
+		limit, err := strconv.Atoi(limitQuery)
+		if err != nil || limit < 0 {
+			return segmentReportParameters{}, ErrInvalidLimitParameter
+		}
+		params.Limit = limit
+	}
+
+	return params, nil
+}
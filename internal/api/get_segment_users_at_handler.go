@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SegmentUsersAtResponse struct {
+	Slug    string    `json:"slug"`
+	Time    time.Time `json:"time"`
+	UserIds []int     `json:"userIds"`
+}
+
+// GetSegmentUsersAt
+// @Summary GetSegmentUsersAt
+// @Tags Segment
+// @Description Reconstructs the users that were in the segment at the given moment by replaying the history. Deleted segments and previous slugs of renamed segments are found too
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Param 	time query string true "moment of time, RFC 3339"
+// @Success 200 {object} api.SegmentUsersAtResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segments/{slug}/users/at [get]
+// @Router /v2/segments/{slug}/users/at [get]
+func (h *handler) GetSegmentUsersAt(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	at, err := parseTimeParameter(c.Query("time"))
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	usersIDs, err := h.historyService.GetSegmentUsersAt(ctx, slug, at)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SegmentUsersAtResponse{
+		Slug:    slug,
+		Time:    at,
+		UserIds: usersIDs,
+	})
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SegmentsResponse struct {
+	Segments []model.Segment `json:"segments"`
+}
+
+// GetSegments
+// @Summary GetSegments
+// @Tags Segment
+// @Description Lists segments with their metadata, optionally filtered by tag or owner
+// @Produce application/json
+// @Param 	tag query string false "segment tag"
+// @Param 	owner query string false "owning team"
+// @Success 200 {object} api.SegmentsResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segment [get]
+func (h *handler) GetSegments(c *gin.Context) {
+	ctx := requestContext(c)
+
+	segments, err := h.segmentService.GetSegments(ctx, model.SegmentFilter{
+		Tag:   c.Query("tag"),
+		Owner: c.Query("owner"),
+	})
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SegmentsResponse{
+		Segments: segments,
+	})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserSegmentsAtResponse struct {
+	UserId   int       `json:"userId"`
+	Time     time.Time `json:"time"`
+	Segments []string  `json:"segments"`
+}
+
+// GetUserSegmentsAt
+// @Summary GetUserSegmentsAt
+// @Tags User
+// @Description Reconstructs the segments the user was in at the given moment by replaying the history. Segments are listed under their current slugs
+// @Produce application/json
+// @Param 	userId query int true "actual userId"
+// @Param 	time query string true "moment of time, RFC 3339"
+// @Success 200 {object} api.UserSegmentsAtResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /user/segment/at [get]
+func (h *handler) GetUserSegmentsAt(c *gin.Context) {
+	ctx := requestContext(c)
+
+	userId, err := strconv.Atoi(c.Query("userId"))
+	if err != nil || !model.IsValidUserID(userId) {
+		response.WriteErrorResponse(c, ErrInvalidUserIdParameter)
+		return
+	}
+
+	at, err := parseTimeParameter(c.Query("time"))
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	segments, err := h.historyService.GetUserSegmentsAt(ctx, userId, at)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, UserSegmentsAtResponse{
+		UserId:   userId,
+		Time:     at,
+		Segments: segments,
+	})
+}
+
+func parseTimeParameter(timeQuery string) (time.Time, error) {
+	at, err := time.Parse(time.RFC3339, timeQuery)
+	if err != nil {
+		return time.Time{}, ErrInvalidTimeParameter
+	}
+
+	return at, nil
+}
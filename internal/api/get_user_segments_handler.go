@@ -1,12 +1,10 @@
 package api
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
-
+	"github.com/elgntt/segmentation-service/internal/model"
 	response "github.com/elgntt/segmentation-service/internal/pkg/http"
 
 	"github.com/gin-gonic/gin"
@@ -26,17 +24,19 @@ type UserSegmentsResponse struct {
 // @Success 200 {object} api.UserSegmentsResponse
 // @Failure 400 {object} http.ErrorResponse
 // @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
 // @Router /user/segment/active [get]
 func (h *handler) GetUserSegments(c *gin.Context) {
 	userId, err := strconv.Atoi(c.Query("userId"))
 	if err != nil {
-		response.WriteErrorResponse(c, app_err.NewBusinessError("invalid userId parameter"))
+		response.WriteErrorResponse(c, ErrInvalidUserIdParameter)
 		return
 	}
-	if userId < 1 {
-		response.WriteErrorResponse(c, app_err.NewBusinessError(ErrInvalidUserId))
+	if !model.IsValidUserID(userId) {
+		response.WriteErrorResponse(c, ErrInvalidUserId)
+		return
 	}
-	ctx := context.Background()
+	ctx := requestContext(c)
 	userSegments, err := h.userService.GetActiveUserSegments(ctx, userId)
 	if err != nil {
 		response.WriteErrorResponse(c, err)
@@ -1,24 +1,60 @@
 package api
 
 import (
+	"github.com/elgntt/segmentation-service/internal/config"
 	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 
 	_ "github.com/elgntt/segmentation-service/docs"
 )
 
+// Codes of the request errors found by the API, the service has codes of its own.
 const (
-	ErrInvalidYearParameter   = `invalid "year" parameter`
-	ErrInvalidMonthParameter  = `invalid "month" parameter`
-	ErrInvalidSegmentSlug     = `invalid segment slug`
-	ErrInvalidUserIdParameter = `invalid "userId" parameter`
-	ErrInvalidAutoJoinPercent = `invalid "autoJoinPercent" value`
-	ErrInvalidUserId          = `invalid userId`
+	CodeValidationFailed       = validation.ErrorCode
+	CodeInvalidRequestBody     = "invalid_request_body"
+	CodeUnsupportedContentType = "unsupported_content_type"
+	CodeInvalidReportLink      = "invalid_report_link"
 )
 
+var (
+	ErrInvalidRequestBody        = app_err.NewValidationError(CodeInvalidRequestBody, `invalid request body`)
+	ErrInvalidImportBody         = app_err.NewValidationError(CodeInvalidRequestBody, `invalid import body`)
+	ErrNoUsersSpecified          = app_err.NewValidationError(CodeInvalidRequestBody, `no users specified`)
+	ErrUnsupportedContentType    = app_err.NewValidationError(CodeUnsupportedContentType, `unsupported Content-Type, expected "text/csv" or "application/x-ndjson"`)
+	ErrInvalidReportLink         = app_err.NewForbiddenError(CodeInvalidReportLink, `invalid report link`)
+	ErrInvalidYearParameter      = validation.NewFieldError(`invalid "year" parameter`, "year")
+	ErrInvalidMonthParameter     = validation.NewFieldError(`invalid "month" parameter`, "month")
+	ErrInvalidUserIdParameter    = validation.NewFieldError(`invalid "userId" parameter`, "userId")
+	ErrInvalidUserId             = validation.NewFieldError(`invalid userId`, "userId")
+	ErrInvalidPageParameter      = validation.NewFieldError(`invalid "page" parameter`, "page")
+	ErrInvalidPerPageParameter   = validation.NewFieldError(`invalid "perPage" parameter`, "perPage")
+	ErrInvalidSortByParameter    = validation.NewFieldError(`invalid "sortBy" parameter`, "sortBy")
+	ErrInvalidOrderParameter     = validation.NewFieldError(`invalid "order" parameter`, "order")
+	ErrInvalidCursorParameter    = validation.NewFieldError(`invalid "cursor" parameter`, "cursor")
+	ErrInvalidLimitParameter     = validation.NewFieldError(`invalid "limit" parameter`, "limit")
+	ErrInvalidUserIdsParameter   = validation.NewFieldError(`invalid "userIds" parameter`, "userIds")
+	ErrInvalidFromParameter      = validation.NewFieldError(`invalid "from" parameter`, "from")
+	ErrInvalidToParameter        = validation.NewFieldError(`invalid "to" parameter`, "to")
+	ErrInvalidModeParameter      = validation.NewFieldError(`invalid "mode" parameter`, "mode")
+	ErrInvalidFormatParameter    = validation.NewFieldError(`invalid "format" parameter`, "format")
+	ErrInvalidDelimiterParameter = validation.NewFieldError(`invalid "delimiter" parameter`, "delimiter")
+	ErrInvalidHeaderParameter    = validation.NewFieldError(`invalid "header" parameter`, "header")
+	ErrInvalidTimeRange          = validation.NewFieldError(`"from" must be before "to"`, "from", "to")
+	ErrInvalidReportJobId        = validation.NewFieldError(`invalid report job id`, "id")
+	ErrInvalidKindParameter      = validation.NewFieldError(`invalid "kind" parameter`, "kind")
+	ErrInvalidTimeParameter      = validation.NewFieldError(`invalid "time" parameter`, "time")
+	ErrInvalidActorIdHeader      = validation.NewFieldError(`invalid "X-Actor-ID" header`, "X-Actor-ID")
+	ErrInvalidRequestIdHeader    = validation.NewFieldError(`invalid "X-Request-ID" header`, "X-Request-ID")
+)
+
+// maxReportJobFilter limits the users and the segments in the filter of a report job.
+const maxReportJobFilter = 1000
+
 type handler struct {
 	userService
 	historyService
@@ -33,28 +69,66 @@ func NewHandler(userService userService, historyService historyService, segmentS
 	}
 }
 
-func New(us userService, hs historyService, ss segmentService) *gin.Engine {
+func New(us userService, hs historyService, ss segmentService, cfg config.HTTPConfig) *gin.Engine {
 	h := NewHandler(us, hs, ss)
 
 	r := gin.New()
-
-	r.Static("/assets/csv_reports", "./assets/csv_reports")
+	r.Use(requestMeta, requestTimeout(cfg))
 
 	r.POST("/segment", h.CreateSegment)
 	r.POST("/user/segment/action", h.UserSegmentAction)
 	r.DELETE("/segment", h.DeleteSegment)
+	r.GET("/segment", h.GetSegments)
+	r.PATCH("/segment/:slug", h.UpdateSegment)
+	r.POST("/segment/:slug/restore", h.RestoreSegment)
+	r.POST("/segment/:slug/rename", h.RenameSegment)
+	r.GET("/segments", h.ListSegments)
+	r.GET("/segments/:slug/users", h.GetSegmentMembers)
+	r.GET("/segments/:slug/users/at", h.GetSegmentUsersAt)
 	r.GET("/user/segment/active", h.GetUserSegments)
+	r.GET("/user/segment/at", h.GetUserSegmentsAt)
+	r.POST("/user", h.CreateUser)
+	r.POST("/user/import", h.ImportUsers)
+	r.DELETE("/user/:id", h.DeleteUser)
+	r.GET("/history", h.GetHistory)
 	r.GET("/history/file", h.GetReportFile)
+	r.GET("/history/segments/file", h.GetSegmentReportFile)
+	r.GET("/history/consistency", h.CheckMembershipConsistency)
+	r.POST("/history/reports", h.CreateReportJob)
+	r.GET("/history/reports/:id", h.GetReportJob)
+	r.POST("/history/reports/:id/cancel", h.CancelReportJob)
+	r.GET("/assets/csv_reports/:name", h.DownloadReportFile)
+
+	v2 := r.Group("/v2")
+	v2.GET("/segments", h.ListSegments)
+	v2.POST("/segments", h.CreateSegment)
+	v2.GET("/segments/:slug", h.GetSegment)
+	v2.PATCH("/segments/:slug", h.UpdateSegment)
+	v2.DELETE("/segments/:slug", h.DeleteSegmentBySlug)
+	v2.POST("/segments/:slug/restore", h.RestoreSegment)
+	v2.POST("/segments/:slug/rename", h.RenameSegment)
+	v2.GET("/segments/:slug/users", h.GetSegmentMembers)
+	v2.GET("/segments/:slug/users/at", h.GetSegmentUsersAt)
+	v2.POST("/users", h.CreateUser)
+	v2.POST("/users/import", h.ImportUsers)
+	v2.DELETE("/users/:id", h.DeleteUser)
+	v2.GET("/users/:id/segments", h.ListUserSegments)
+	v2.PUT("/users/:id/segments/:slug", h.PutUserSegment)
+	v2.DELETE("/users/:id/segments/:slug", h.DeleteUserSegment)
+	v2.GET("/history", h.GetHistory)
+	v2.POST("/history/reports", h.CreateReportJob)
+	v2.GET("/history/reports/:id", h.GetReportJob)
+	v2.POST("/history/reports/:id/cancel", h.CancelReportJob)
 
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	return r
 }
 
 func validateSegmentSlug(slug string) error {
-	if slug == "" {
-		return app_err.NewBusinessError(ErrInvalidSegmentSlug)
-	}
+	v := validation.Validator{}
+	v.SegmentSlug("slug", slug)
 
-	return nil
+	return v.Err()
 }
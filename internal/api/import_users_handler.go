@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	csvContentType       = "text/csv"
+	jsonLinesContentType = "application/x-ndjson"
+)
+
+// ImportUsers
+// @Summary ImportUsers
+// @Tags User
+// @Description Registers users in bulk. The body is either CSV (one userId per line, optional "userId" header) or JSON lines ({"userId": 1} per line)
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce application/json
+// @Success 200 {object} model.ImportUsersResult
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /user/import [post]
+// @Router /v2/users/import [post]
+func (h *handler) ImportUsers(c *gin.Context) {
+	ctx := requestContext(c)
+
+	var (
+		usersIDs []int
+		err      error
+	)
+	switch c.ContentType() {
+	case csvContentType:
+		usersIDs, err = parseUsersCSV(c.Request.Body)
+	case jsonLinesContentType:
+		usersIDs, err = parseUsersJSONLines(c.Request.Body)
+	default:
+		err = ErrUnsupportedContentType
+	}
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	if len(usersIDs) == 0 {
+		response.WriteErrorResponse(c, ErrNoUsersSpecified)
+		return
+	}
+
+	result, err := h.userService.ImportUsers(ctx, usersIDs)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func parseUsersCSV(body io.Reader) ([]int, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	var usersIDs []int
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidImportBody
+		}
+
+		value := strings.TrimSpace(record[0])
+		if line == 1 && value == "userId" {
+			continue
+		}
+
+		userId, err := strconv.Atoi(value)
+		if err != nil || !model.IsValidUserID(userId) {
+			return nil, invalidImportedUserIdError(line)
+		}
+		usersIDs = append(usersIDs, userId)
+	}
+
+	return usersIDs, nil
+}
+
+func parseUsersJSONLines(body io.Reader) ([]int, error) {
+	scanner := bufio.NewScanner(body)
+
+	var usersIDs []int
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		user := model.AddUser{}
+		if err := json.Unmarshal([]byte(text), &user); err != nil {
+			return nil, app_err.NewValidationError(CodeInvalidRequestBody, fmt.Sprintf("%s: line %d", ErrInvalidImportBody, line))
+		}
+
+		if !model.IsValidUserID(user.UserID) {
+			return nil, invalidImportedUserIdError(line)
+		}
+		usersIDs = append(usersIDs, user.UserID)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ErrInvalidImportBody
+	}
+
+	return usersIDs, nil
+}
+
+func invalidImportedUserIdError(line int) error {
+	return validation.NewFieldError(fmt.Sprintf("%s: line %d", ErrInvalidUserId, line), "userId")
+}
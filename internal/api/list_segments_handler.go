@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSegmentsPerPage = 20
+	maxSegmentsPerPage     = 100
+)
+
+// ListSegments
+// @Summary ListSegments
+// @Tags Segment
+// @Description Lists segments page by page together with the number of their active members
+// @Produce application/json
+// @Param 	page query int false "page number, starts with 1"
+// @Param 	perPage query int false "segments per page, 20 by default, 100 at most"
+// @Param 	sortBy query string false "sort field" Enums(slug, createdAt, membersCount)
+// @Param 	order query string false "sort order" Enums(asc, desc)
+// @Param 	tag query string false "segment tag"
+// @Param 	owner query string false "owning team"
+// @Success 200 {object} model.SegmentsPage
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segments [get]
+// @Router /v2/segments [get]
+func (h *handler) ListSegments(c *gin.Context) {
+	ctx := requestContext(c)
+
+	params, err := parseSegmentsPageParams(c)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	page, err := h.segmentService.GetSegmentsPage(ctx, params)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func parseSegmentsPageParams(c *gin.Context) (model.SegmentsPageParams, error) {
+	params := model.SegmentsPageParams{
+		SegmentFilter: model.SegmentFilter{
+			Tag:   c.Query("tag"),
+			Owner: c.Query("owner"),
+		},
+		Page:    1,
+		PerPage: defaultSegmentsPerPage,
+		SortBy:  c.DefaultQuery("sortBy", model.SegmentsSortBySlug),
+	}
+
+	var err error
+	if pageQuery := c.Query("page"); pageQuery != "" {
+		params.Page, err = strconv.Atoi(pageQuery)
+		if err != nil || params.Page < 1 {
+			return model.SegmentsPageParams{}, ErrInvalidPageParameter
+		}
+	}
+
+	if perPageQuery := c.Query("perPage"); perPageQuery != "" {
+		params.PerPage, err = strconv.Atoi(perPageQuery)
+		if err != nil || params.PerPage < 1 || params.PerPage > maxSegmentsPerPage {
+			return model.SegmentsPageParams{}, ErrInvalidPerPageParameter
+		}
+	}
+
+	switch params.SortBy {
+	case model.SegmentsSortBySlug, model.SegmentsSortByCreatedAt, model.SegmentsSortByMembersCount:
+	default:
+		return model.SegmentsPageParams{}, ErrInvalidSortByParameter
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return model.SegmentsPageParams{}, ErrInvalidOrderParameter
+	}
+
+	return params, nil
+}
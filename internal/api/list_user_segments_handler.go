@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListUserSegments
+// @Summary ListUserSegments
+// @Tags User
+// @Description Returns the active segments of the user like GET /user/segment/active, with the user id in the path
+// @Produce application/json
+// @Param 	id path int true "user id"
+// @Success 200 {object} api.UserSegmentsResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /v2/users/{id}/segments [get]
+func (h *handler) ListUserSegments(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil || !model.IsValidUserID(userId) {
+		response.WriteErrorResponse(c, ErrInvalidUserId)
+		return
+	}
+
+	ctx := requestContext(c)
+	userSegments, err := h.userService.GetActiveUserSegments(ctx, userId)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, UserSegmentsResponse{
+		UserId:   userId,
+		Segments: userSegments,
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserSegmentRequest sets the membership of the user in the segment, the body and all of its fields are optional.
+type UserSegmentRequest struct {
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	ExpirationTime *time.Time `json:"expirationTime,omitempty"`
+	TTL            *model.TTL `json:"ttl,omitempty" swaggertype:"string" example:"72h"`
+	// NoExpiration keeps the membership from expiring instead of giving it the default ttl of the segment.
+	NoExpiration bool `json:"noExpiration,omitempty"`
+}
+
+// PutUserSegment
+// @Summary PutUserSegment
+// @Tags User
+// @Description Adds the user to the segment. If the user is already in the segment, the expiration time of the membership is replaced
+// @Description and a membership that has not started yet gets the new start time, an active membership stays active.
+// @Description Without an expiration time, a ttl or "noExpiration" the membership gets the default ttl of the segment.
+// @Produce application/json
+// @Param 	id path int true "user id"
+// @Param 	slug path string true "segment slug"
+// @Param 	input body api.UserSegmentRequest false "membership"
+// @Success 204
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /v2/users/{id}/segments/{slug} [put]
+func (h *handler) PutUserSegment(c *gin.Context) {
+	ctx := requestContext(c)
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil || !model.IsValidUserID(userId) {
+		response.WriteErrorResponse(c, ErrInvalidUserId)
+		return
+	}
+
+	slug := c.Param("slug")
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	request := UserSegmentRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
+		return
+	}
+
+	action := model.UserSegmentAction{
+		UserID:                userId,
+		SegmentsSlugsToAdd:    []string{slug},
+		StartTime:             request.StartTime,
+		SegmentExpirationTime: request.ExpirationTime,
+		TTL:                   request.TTL,
+		NoExpiration:          request.NoExpiration,
+		Upsert:                true,
+	}
+	if err := validateRequestData(action); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	err = h.userService.UserSegmentAction(ctx, action)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
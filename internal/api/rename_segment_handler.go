@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RenameSegmentRequest struct {
+	NewSlug string `json:"newSlug"`
+}
+
+// RenameSegment
+// @Summary RenameSegment
+// @Tags Segment
+// @Description Changes the segment slug. Memberships and history are kept, the old slug is accepted as an alias for a while
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Param 	input body api.RenameSegmentRequest true "new slug"
+// @Success 200
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segment/{slug}/rename [post]
+// @Router /v2/segments/{slug}/rename [post]
+func (h *handler) RenameSegment(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+	request := RenameSegmentRequest{}
+
+	if err := c.BindJSON(&request); err != nil {
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
+		return
+	}
+
+	if err := validateRenameSegmentData(slug, request); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	err := h.segmentService.RenameSegment(ctx, slug, request.NewSlug)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func validateRenameSegmentData(slug string, request RenameSegmentRequest) error {
+	v := validation.Validator{}
+	v.SegmentSlug("slug", slug)
+	if v.NewSegmentSlug("newSlug", request.NewSlug) {
+		v.Check(request.NewSlug != slug, "newSlug", validation.CodeConflict, "new slug must differ from the current one")
+	}
+
+	return v.Err()
+}
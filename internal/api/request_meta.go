@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/google/uuid"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	actorIdHeader   = "X-Actor-ID"
+	requestIdHeader = "X-Request-ID"
+	requestIdKey    = "requestId"
+
+	// maxMetaHeaderLength is the size of the history columns the header values are stored in.
+	maxMetaHeaderLength = 255
+)
+
+// requestMeta checks the actor and request id headers and sends the request id back in the response,
+// generating one if the client did not send it.
+func requestMeta(c *gin.Context) {
+	if len(c.GetHeader(actorIdHeader)) > maxMetaHeaderLength {
+		response.WriteErrorResponse(c, ErrInvalidActorIdHeader)
+		c.Abort()
+		return
+	}
+
+	requestId := c.GetHeader(requestIdHeader)
+	if len(requestId) > maxMetaHeaderLength {
+		response.WriteErrorResponse(c, ErrInvalidRequestIdHeader)
+		c.Abort()
+		return
+	}
+	if requestId == "" {
+		requestId = uuid.NewString()
+	}
+
+	c.Set(requestIdKey, requestId)
+	c.Header(requestIdHeader, requestId)
+	c.Next()
+}
+
+// requestContext returns the context of the request with the actor and request ids, they are recorded in the history.
+// The context is cancelled when the client goes away or the route timeout is over.
+func requestContext(c *gin.Context) context.Context {
+	return audit.WithMeta(c.Request.Context(), audit.Meta{
+		ActorID:   c.GetHeader(actorIdHeader),
+		RequestID: c.GetString(requestIdKey),
+	})
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+
+	"github.com/elgntt/segmentation-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestTimeout sets the deadline of the request context to the timeout of the route, or to the common one if the route has none.
+func requestTimeout(cfg config.HTTPConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := cfg.RequestTimeout
+		if routeTimeout, ok := cfg.RouteTimeouts[c.Request.Method+" "+c.FullPath()]; ok {
+			timeout = routeTimeout
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RestoreSegment
+// @Summary RestoreSegment
+// @Tags Segment
+// @Description Restores a deleted segment with its memberships if it was deleted within the grace period
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Success 200
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segment/{slug}/restore [post]
+// @Router /v2/segments/{slug}/restore [post]
+func (h *handler) RestoreSegment(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+
+	if err := validateSegmentSlug(slug); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	err := h.segmentService.RestoreSegment(ctx, slug)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateSegment
+// @Summary UpdateSegment
+// @Tags Segment
+// @Description Edits segment metadata. Raising autoJoinPercent only adds users, lowering it only removes them.
+// @Description defaultTtl of "0s" removes the default expiration of memberships
+// @Produce application/json
+// @Param 	slug path string true "segment slug"
+// @Param 	input body model.UpdateSegment true "fields to change"
+// @Success 200 {object} model.Segment
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
+// @Router /segment/{slug} [patch]
+// @Router /v2/segments/{slug} [patch]
+func (h *handler) UpdateSegment(c *gin.Context) {
+	ctx := requestContext(c)
+	slug := c.Param("slug")
+	request := model.UpdateSegment{}
+
+	if err := c.BindJSON(&request); err != nil {
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
+		return
+	}
+
+	if err := validateUpdateSegmentData(slug, request); err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	segment, err := h.segmentService.UpdateSegment(ctx, slug, request)
+	if err != nil {
+		response.WriteErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, segment)
+}
+
+func validateUpdateSegmentData(slug string, segmentData model.UpdateSegment) error {
+	v := validation.Validator{}
+	v.SegmentSlug("slug", slug)
+	segmentData.Validate(&v)
+
+	return v.Err()
+}
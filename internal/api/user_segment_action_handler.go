@@ -1,13 +1,11 @@
 package api
 
 import (
-	"context"
 	"net/http"
-	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
 	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,18 +13,26 @@ import (
 // UserSegmentAction
 // @Summary GetUserSegments
 // @Tags User
-// @Description Adds and deletes some transmitted segments for some user
+// @Description Adds and deletes some transmitted segments for some user.
+// @Description "segmentExpirations" overrides "expirationTime" for single segments, null means the membership does not expire.
+// @Description "ttl" and "segmentTtls" set the expiration relative to now as a Go duration ("72h") or an ISO 8601 duration ("P7D").
+// @Description Segments without an expiration time or a ttl get the default ttl of the segment, or never expire with "noExpiration".
+// @Description "startTime" schedules the added memberships, they become active and are recorded in the history as "adding" when it comes.
+// @Description With "upsert" the expiration time of memberships the user already has is replaced and recorded in the history as "expiration_changed",
+// @Description memberships that have not started yet get the new "startTime" or start right away without it.
 // @Produce application/json
 // @Param 	input body model.UserSegmentAction true "Segments and userId"
 // @Success 200 {object} api.UserSegmentsResponse
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
 // @Failure 500 {object} http.ErrorResponse
+// @Failure 504 {object} http.ErrorResponse
 // @Router /user/segment/action [post]
 func (h *handler) UserSegmentAction(c *gin.Context) {
-	ctx := context.Background()
+	ctx := requestContext(c)
 	request := model.UserSegmentAction{}
 	if err := c.BindJSON(&request); err != nil {
-		response.WriteErrorResponse(c, app_err.NewBusinessError("invalid request body"))
+		response.WriteErrorResponse(c, ErrInvalidRequestBody)
 		return
 	}
 
@@ -45,34 +51,8 @@ func (h *handler) UserSegmentAction(c *gin.Context) {
 }
 
 func validateRequestData(request model.UserSegmentAction) error {
-	if request.UserID < 1 {
-		return app_err.NewBusinessError(ErrInvalidUserId)
-	}
-
-	if len(request.SegmentsSlugsToAdd) == 0 && len(request.SegmentsSlugsToRemove) == 0 {
-		return app_err.NewBusinessError("no segments specified")
-	}
-
-	if err := validateTime(request.SegmentExpirationTime); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func validateTime(expirationTime *time.Time) error {
-	if expirationTime == nil {
-		return nil
-	}
-
-	transmittedTime, err := time.Parse(time.RFC3339, expirationTime.Format(time.RFC3339))
-	if err != nil {
-		return err
-	}
-
-	if transmittedTime.Before(time.Now()) {
-		return app_err.NewBusinessError("invalid expiration time argument")
-	}
+	v := validation.Validator{}
+	request.Validate(&v)
 
-	return nil
+	return v.Err()
 }
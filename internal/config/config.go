@@ -1,9 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,11 +18,76 @@ type DBConfig struct {
 	PgPort     uint16
 	PgDatabase string
 	PgSSLMode  string
+	// PgMaxConns is the size of the connection pool. The leader locks of the workers use a connection of their own.
+	PgMaxConns int
 }
 
 type ServerConfig struct {
-	HTTPPort       string
+	HTTPPort string
+	GRPCPort string
+}
+
+type HTTPConfig struct {
+	// RequestTimeout bounds handling of a request, zero means no limit.
+	RequestTimeout time.Duration
+	// RouteTimeouts overrides RequestTimeout for routes keyed by the method and the route path, e.g. "GET /history/file".
+	RouteTimeouts map[string]time.Duration
+}
+
+type GRPCConfig struct {
+	// RequestTimeout bounds handling of a call that has no earlier deadline, zero means no limit.
+	RequestTimeout time.Duration
+}
+
+type SegmentConfig struct {
+	RestoreGracePeriod time.Duration
+	AliasTTL           time.Duration
+}
+
+type ReportConfig struct {
+	// ServerEndpoint is the address of the server the download links start with.
 	ServerEndpoint string
+	LinkSecret     []byte
+	LinkTTL        time.Duration
+	Workers        int
+	JobMaxAttempts int
+	// JobHeartbeat is how often a running job saves its progress and checks whether it was cancelled.
+	JobHeartbeat time.Duration
+}
+
+type WorkerConfig struct {
+	ExpirationInterval time.Duration
+	ActivationInterval time.Duration
+	PurgeInterval      time.Duration
+	// BatchSize is the number of memberships changed in one transaction.
+	BatchSize       int
+	ShutdownTimeout time.Duration
+}
+
+const (
+	defaultRestoreGracePeriod = 30 * 24 * time.Hour
+	defaultAliasTTL           = 30 * 24 * time.Hour
+	defaultReportLinkTTL      = time.Hour
+	defaultReportWorkers      = 2
+	defaultReportJobAttempts  = 3
+	defaultReportJobHeartbeat = 10 * time.Second
+	defaultWorkerInterval     = time.Minute
+	defaultPurgeInterval      = time.Hour
+	defaultWorkerBatchSize    = 1000
+	defaultShutdownTimeout    = 30 * time.Second
+	defaultRequestTimeout     = 30 * time.Second
+	defaultLongRequestTimeout = 10 * time.Minute
+	defaultGRPCPort           = "9090"
+	defaultPgMaxConns         = 20
+)
+
+// defaultRouteTimeouts gives more time to the routes that stream reports or read import files.
+var defaultRouteTimeouts = map[string]time.Duration{
+	"GET /history/file":             defaultLongRequestTimeout,
+	"GET /history/segments/file":    defaultLongRequestTimeout,
+	"GET /assets/csv_reports/:name": defaultLongRequestTimeout,
+	"POST /user/import":             defaultLongRequestTimeout,
+	"POST /v2/users/import":         defaultLongRequestTimeout,
 }
 
 func GetDBConfig() (DBConfig, error) {
@@ -28,6 +96,14 @@ func GetDBConfig() (DBConfig, error) {
 		return DBConfig{}, err
 	}
 
+	pgMaxConns, err := getInt("PG_MAX_CONNS", defaultPgMaxConns)
+	if err != nil {
+		return DBConfig{}, err
+	}
+	if pgMaxConns <= 0 {
+		return DBConfig{}, fmt.Errorf("PG_MAX_CONNS: must be positive")
+	}
+
 	return DBConfig{
 		PgUser:     getKey("PGUSER"),
 		PgPassword: getKey("PGPASSWORD"),
@@ -35,14 +111,189 @@ func GetDBConfig() (DBConfig, error) {
 		PgPort:     uint16(pgPort),
 		PgDatabase: getKey("PGDATABASE"),
 		PgSSLMode:  getKey("PGSSLMODE"),
+		PgMaxConns: pgMaxConns,
 	}, nil
 }
 
 func GetServerConfig() ServerConfig {
+	grpcPort := getKey("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = defaultGRPCPort
+	}
+
 	return ServerConfig{
-		HTTPPort:       ":" + getKey("HTTP_PORT"),
+		HTTPPort: ":" + getKey("HTTP_PORT"),
+		GRPCPort: ":" + grpcPort,
+	}
+}
+
+func GetGRPCConfig() (GRPCConfig, error) {
+	requestTimeout, err := getDuration("GRPC_REQUEST_TIMEOUT", defaultRequestTimeout)
+	if err != nil {
+		return GRPCConfig{}, err
+	}
+
+	return GRPCConfig{
+		RequestTimeout: requestTimeout,
+	}, nil
+}
+
+// GetHTTPConfig reads request timeouts. HTTP_ROUTE_TIMEOUTS lists route timeouts separated by commas,
+// e.g. "GET /history/file=5m,POST /user/import=10m", they are added to the default ones.
+func GetHTTPConfig() (HTTPConfig, error) {
+	requestTimeout, err := getDuration("HTTP_REQUEST_TIMEOUT", defaultRequestTimeout)
+	if err != nil {
+		return HTTPConfig{}, err
+	}
+
+	routeTimeouts := make(map[string]time.Duration, len(defaultRouteTimeouts))
+	for route, timeout := range defaultRouteTimeouts {
+		routeTimeouts[route] = timeout
+	}
+
+	if value := getKey("HTTP_ROUTE_TIMEOUTS"); value != "" {
+		for _, routeTimeout := range strings.Split(value, ",") {
+			route, timeoutValue, ok := strings.Cut(routeTimeout, "=")
+			if !ok {
+				return HTTPConfig{}, fmt.Errorf("HTTP_ROUTE_TIMEOUTS: invalid route timeout %q", routeTimeout)
+			}
+
+			timeout, err := time.ParseDuration(strings.TrimSpace(timeoutValue))
+			if err != nil {
+				return HTTPConfig{}, fmt.Errorf("HTTP_ROUTE_TIMEOUTS: %w", err)
+			}
+			routeTimeouts[strings.Join(strings.Fields(route), " ")] = timeout
+		}
+	}
+
+	return HTTPConfig{
+		RequestTimeout: requestTimeout,
+		RouteTimeouts:  routeTimeouts,
+	}, nil
+}
+
+func GetSegmentConfig() (SegmentConfig, error) {
+	restoreGracePeriod, err := getDuration("SEGMENT_RESTORE_GRACE_PERIOD", defaultRestoreGracePeriod)
+	if err != nil {
+		return SegmentConfig{}, err
+	}
+
+	aliasTTL, err := getDuration("SEGMENT_ALIAS_TTL", defaultAliasTTL)
+	if err != nil {
+		return SegmentConfig{}, err
+	}
+
+	return SegmentConfig{
+		RestoreGracePeriod: restoreGracePeriod,
+		AliasTTL:           aliasTTL,
+	}, nil
+}
+
+// GetReportConfig reads settings of report file links. REPORT_LINK_SECRET is required, every replica has to sign
+// links with the same secret to accept the links of the others.
+func GetReportConfig() (ReportConfig, error) {
+	linkTTL, err := getDuration("REPORT_LINK_TTL", defaultReportLinkTTL)
+	if err != nil {
+		return ReportConfig{}, err
+	}
+
+	workers, err := getInt("REPORT_WORKERS", defaultReportWorkers)
+	if err != nil {
+		return ReportConfig{}, err
+	}
+
+	jobMaxAttempts, err := getInt("REPORT_JOB_MAX_ATTEMPTS", defaultReportJobAttempts)
+	if err != nil {
+		return ReportConfig{}, err
+	}
+
+	jobHeartbeat, err := getDuration("REPORT_JOB_HEARTBEAT", defaultReportJobHeartbeat)
+	if err != nil {
+		return ReportConfig{}, err
+	}
+	if jobHeartbeat <= 0 {
+		return ReportConfig{}, fmt.Errorf("REPORT_JOB_HEARTBEAT: must be positive")
+	}
+
+	linkSecret := getKey("REPORT_LINK_SECRET")
+	if linkSecret == "" {
+		return ReportConfig{}, fmt.Errorf("REPORT_LINK_SECRET: must be set")
+	}
+
+	return ReportConfig{
 		ServerEndpoint: getKey("SERVER_ENDPOINT"),
+		LinkSecret:     []byte(linkSecret),
+		LinkTTL:        linkTTL,
+		Workers:        workers,
+		JobMaxAttempts: jobMaxAttempts,
+		JobHeartbeat:   jobHeartbeat,
+	}, nil
+}
+
+func GetWorkerConfig() (WorkerConfig, error) {
+	expirationInterval, err := getDuration("WORKER_EXPIRATION_INTERVAL", defaultWorkerInterval)
+	if err != nil {
+		return WorkerConfig{}, err
+	}
+
+	activationInterval, err := getDuration("WORKER_ACTIVATION_INTERVAL", defaultWorkerInterval)
+	if err != nil {
+		return WorkerConfig{}, err
 	}
+
+	purgeInterval, err := getDuration("WORKER_PURGE_INTERVAL", defaultPurgeInterval)
+	if err != nil {
+		return WorkerConfig{}, err
+	}
+
+	batchSize, err := getInt("WORKER_BATCH_SIZE", defaultWorkerBatchSize)
+	if err != nil {
+		return WorkerConfig{}, err
+	}
+	if batchSize < 1 {
+		return WorkerConfig{}, fmt.Errorf("WORKER_BATCH_SIZE: must be positive")
+	}
+
+	shutdownTimeout, err := getDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	if err != nil {
+		return WorkerConfig{}, err
+	}
+
+	return WorkerConfig{
+		ExpirationInterval: expirationInterval,
+		ActivationInterval: activationInterval,
+		PurgeInterval:      purgeInterval,
+		BatchSize:          batchSize,
+		ShutdownTimeout:    shutdownTimeout,
+	}, nil
+}
+
+func getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := getKey(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return duration, nil
+}
+
+func getInt(key string, defaultValue int) (int, error) {
+	value := getKey(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return number, nil
 }
 
 func getKey(key string) string {
@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/grpcapi/segmentationpb"
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversions of request fields add a violation to v if the value can not be converted. Fields are named the way
+// the proto JSON mapping and the HTTP API name them.
+
+func checkUserId(v *validation.Validator, field string, userId int64) bool {
+	return model.CheckUserID(v, field, int(userId))
+}
+
+func timeFromPB(v *validation.Validator, field string, ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	if !v.Check(ts.CheckValid() == nil, field, validation.CodeInvalid, "invalid timestamp") {
+		return nil
+	}
+
+	t := ts.AsTime()
+	return &t
+}
+
+func requiredTime(v *validation.Validator, field string, ts *timestamppb.Timestamp) time.Time {
+	if !v.Check(ts != nil, field, validation.CodeRequired, field+" is required") {
+		return time.Time{}
+	}
+
+	if t := timeFromPB(v, field, ts); t != nil {
+		return *t
+	}
+
+	return time.Time{}
+}
+
+func ttlFromPB(v *validation.Validator, field string, d *durationpb.Duration) *model.TTL {
+	if d == nil {
+		return nil
+	}
+	if !v.Check(d.CheckValid() == nil && d.AsDuration() >= 0, field, validation.CodeInvalid, "invalid duration") {
+		return nil
+	}
+
+	ttl := model.TTL(d.AsDuration())
+	return &ttl
+}
+
+func ttlToPB(ttl *model.TTL) *durationpb.Duration {
+	if ttl == nil {
+		return nil
+	}
+
+	return durationpb.New(time.Duration(*ttl))
+}
+
+func segmentToPB(segment model.Segment) *segmentationpb.Segment {
+	return &segmentationpb.Segment{
+		Slug:            segment.Slug,
+		AutoJoinPercent: int32(segment.AutoJoinPercent),
+		Description:     segment.Description,
+		Owner:           segment.Owner,
+		Tags:            segment.Tags,
+		DefaultTtl:      ttlToPB(segment.DefaultTTL),
+		CreateTime:      timestamppb.New(segment.CreatedAt),
+		UpdateTime:      timestamppb.New(segment.UpdatedAt),
+	}
+}
+
+func historyToPB(entry model.History) *segmentationpb.HistoryEntry {
+	pbEntry := &segmentationpb.HistoryEntry{
+		SegmentSlug:   entry.SegmentSlug,
+		Operation:     string(entry.Operation),
+		OperationTime: timestamppb.New(entry.OperationTime),
+		Source:        entry.Source,
+		ActorId:       entry.ActorID,
+		RequestId:     entry.RequestID,
+	}
+	if entry.UserID != nil {
+		userId := int64(*entry.UserID)
+		pbEntry.UserId = &userId
+	}
+
+	return pbEntry
+}
+
+func userIdsFromPB(v *validation.Validator, field string, userIds []int64) []int {
+	ids := make([]int, 0, len(userIds))
+	for i, userId := range userIds {
+		if checkUserId(v, fmt.Sprintf("%s[%d]", field, i), userId) {
+			ids = append(ids, int(userId))
+		}
+	}
+
+	return ids
+}
+
+func userIdsToPB(userIds []int) []int64 {
+	ids := make([]int64, 0, len(userIds))
+	for _, userId := range userIds {
+		ids = append(ids, int64(userId))
+	}
+
+	return ids
+}
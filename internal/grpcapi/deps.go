@@ -0,0 +1,28 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+)
+
+type userService interface {
+	GetActiveUserSegments(ctx context.Context, userId int) ([]string, error)
+	GetActiveUsersSegments(ctx context.Context, userIds []int) ([]model.UsersSegments, error)
+	UserSegmentAction(ctx context.Context, userSegment model.UserSegmentAction) error
+}
+
+type segmentService interface {
+	CreateSegment(ctx context.Context, segmentData model.AddSegment) error
+	DeleteSegment(ctx context.Context, slug string) error
+	UpdateSegment(ctx context.Context, slug string, segmentData model.UpdateSegment) (model.Segment, error)
+	GetSegment(ctx context.Context, slug string) (model.Segment, error)
+	GetSegmentsPage(ctx context.Context, params model.SegmentsPageParams) (model.SegmentsPage, error)
+}
+
+type historyService interface {
+	GetHistory(ctx context.Context, filter model.HistoryFilter) (model.HistoryPage, error)
+	GetUserSegmentsAt(ctx context.Context, userId int, at time.Time) ([]string, error)
+	GetSegmentUsersAt(ctx context.Context, slug string, at time.Time) ([]int, error)
+}
@@ -0,0 +1,83 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+	response "github.com/elgntt/segmentation-service/internal/pkg/http"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain is the domain of the ErrorInfo details, their reason is the code of the error.
+const errorDomain = "segmentation-service"
+
+var (
+	ErrInvalidActorId   = validation.NewFieldError(`invalid "x-actor-id" metadata`, actorIdKey)
+	ErrInvalidRequestId = validation.NewFieldError(`invalid "x-request-id" metadata`, requestIdKey)
+)
+
+var businessErrorCodes = map[app_err.Kind]codes.Code{
+	app_err.KindValidation: codes.InvalidArgument,
+	app_err.KindNotFound:   codes.NotFound,
+	app_err.KindConflict:   codes.AlreadyExists,
+	app_err.KindForbidden:  codes.PermissionDenied,
+}
+
+// toStatus converts err to a status the way the HTTP API picks the response status. A business error gets the code of its kind
+// and its code in the ErrorInfo detail, the fields that failed validation are listed in the BadRequest detail.
+func toStatus(err error) error {
+	var bErr app_err.BusinessError
+
+	switch {
+	case errors.As(err, &bErr):
+		return businessErrorStatus(bErr)
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Println(err)
+		return newStatus(codes.DeadlineExceeded, response.CodeTimeout, "Request timed out")
+	case errors.Is(err, context.Canceled):
+		return newStatus(codes.Canceled, response.CodeClientClosedRequest, "Client closed request")
+	default:
+		log.Println(err)
+		return newStatus(codes.Internal, response.CodeInternal, "Internal server error")
+	}
+}
+
+func businessErrorStatus(bErr app_err.BusinessError) error {
+	st := status.New(businessErrorCodes[bErr.Kind()], bErr.Error())
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: bErr.Code(), Domain: errorDomain}}
+
+	if fields := bErr.Fields(); len(fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		log.Println(err)
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+func newStatus(code codes.Code, reason, message string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	if err != nil {
+		return status.Error(code, message)
+	}
+
+	return st.Err()
+}
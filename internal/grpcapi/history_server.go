@@ -0,0 +1,107 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elgntt/segmentation-service/internal/grpcapi/segmentationpb"
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+func (s *server) GetHistory(ctx context.Context, req *segmentationpb.GetHistoryRequest) (*segmentationpb.GetHistoryResponse, error) {
+	filter, err := historyFilter(req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	page, err := s.historyService.GetHistory(ctx, filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &segmentationpb.GetHistoryResponse{
+		Entries: make([]*segmentationpb.HistoryEntry, 0, len(page.Entries)),
+	}
+	for _, entry := range page.Entries {
+		resp.Entries = append(resp.Entries, historyToPB(entry))
+	}
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.String()
+	}
+
+	return resp, nil
+}
+
+func historyFilter(req *segmentationpb.GetHistoryRequest) (model.HistoryFilter, error) {
+	v := validation.Validator{}
+	filter := model.HistoryFilter{
+		Operation: model.Operation(req.GetOperation()),
+		From:      timeFromPB(&v, "from", req.GetFrom()),
+		To:        timeFromPB(&v, "to", req.GetTo()),
+		Limit:     defaultHistoryLimit,
+	}
+
+	if len(req.GetUserIds()) > 0 {
+		filter.UserIDs = userIdsFromPB(&v, "userIds", req.GetUserIds())
+	}
+	for i, slug := range req.GetSegmentSlugs() {
+		if v.SegmentSlug(fmt.Sprintf("segmentSlugs[%d]", i), slug) {
+			filter.SegmentSlugs = append(filter.SegmentSlugs, slug)
+		}
+	}
+
+	if req.GetCursor() != "" {
+		cursor, err := model.ParseHistoryCursor(req.GetCursor())
+		v.Check(err == nil, "cursor", validation.CodeInvalid, `invalid "cursor"`)
+		filter.After = &cursor
+	}
+
+	if req.GetLimit() != 0 {
+		filter.Limit = int(req.GetLimit())
+		v.Check(filter.Limit >= 1 && filter.Limit <= maxHistoryLimit, "limit", validation.CodeOutOfRange, "limit must be between 1 and 1000")
+	}
+
+	return filter, v.Err()
+}
+
+func (s *server) GetUserSegmentsAt(ctx context.Context, req *segmentationpb.GetUserSegmentsAtRequest) (*segmentationpb.GetUserSegmentsAtResponse, error) {
+	v := validation.Validator{}
+	checkUserId(&v, "userId", req.GetUserId())
+	at := requiredTime(&v, "time", req.GetTime())
+	if err := v.Err(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	segments, err := s.historyService.GetUserSegmentsAt(ctx, int(req.GetUserId()), at)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentationpb.GetUserSegmentsAtResponse{
+		Segments: segments,
+	}, nil
+}
+
+func (s *server) GetSegmentUsersAt(ctx context.Context, req *segmentationpb.GetSegmentUsersAtRequest) (*segmentationpb.GetSegmentUsersAtResponse, error) {
+	v := validation.Validator{}
+	v.SegmentSlug("slug", req.GetSlug())
+	at := requiredTime(&v, "time", req.GetTime())
+	if err := v.Err(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	usersIDs, err := s.historyService.GetSegmentUsersAt(ctx, req.GetSlug(), at)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentationpb.GetSegmentUsersAtResponse{
+		UserIds: userIdsToPB(usersIDs),
+	}, nil
+}
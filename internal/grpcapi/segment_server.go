@@ -0,0 +1,169 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/elgntt/segmentation-service/internal/grpcapi/segmentationpb"
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	defaultSegmentsPerPage = 20
+	maxSegmentsPerPage     = 100
+)
+
+func (s *server) CreateSegment(ctx context.Context, req *segmentationpb.CreateSegmentRequest) (*segmentationpb.Segment, error) {
+	v := validation.Validator{}
+	segmentData := model.AddSegment{
+		SegmentSlug:     req.GetSlug(),
+		AutoJoinPercent: int(req.GetAutoJoinPercent()),
+		Description:     req.GetDescription(),
+		Owner:           req.GetOwner(),
+		Tags:            req.GetTags(),
+		DefaultTTL:      ttlFromPB(&v, "defaultTtl", req.GetDefaultTtl()),
+	}
+	segmentData.Validate(&v)
+	if err := v.Err(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.segmentService.CreateSegment(ctx, segmentData); err != nil {
+		return nil, toStatus(err)
+	}
+
+	segment, err := s.segmentService.GetSegment(ctx, segmentData.SegmentSlug)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return segmentToPB(segment), nil
+}
+
+func (s *server) GetSegment(ctx context.Context, req *segmentationpb.GetSegmentRequest) (*segmentationpb.Segment, error) {
+	if err := validateSegmentSlug(req.GetSlug()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	segment, err := s.segmentService.GetSegment(ctx, req.GetSlug())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return segmentToPB(segment), nil
+}
+
+func (s *server) ListSegments(ctx context.Context, req *segmentationpb.ListSegmentsRequest) (*segmentationpb.ListSegmentsResponse, error) {
+	params, err := segmentsPageParams(req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	page, err := s.segmentService.GetSegmentsPage(ctx, params)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &segmentationpb.ListSegmentsResponse{
+		Segments: make([]*segmentationpb.SegmentWithMembersCount, 0, len(page.Segments)),
+		Page:     int32(page.Page),
+		PerPage:  int32(page.PerPage),
+		Total:    int32(page.Total),
+	}
+	for _, segment := range page.Segments {
+		resp.Segments = append(resp.Segments, &segmentationpb.SegmentWithMembersCount{
+			Segment:      segmentToPB(segment.Segment),
+			MembersCount: int32(segment.MembersCount),
+		})
+	}
+
+	return resp, nil
+}
+
+func segmentsPageParams(req *segmentationpb.ListSegmentsRequest) (model.SegmentsPageParams, error) {
+	params := model.SegmentsPageParams{
+		SegmentFilter: model.SegmentFilter{
+			Tag:   req.GetTag(),
+			Owner: req.GetOwner(),
+		},
+		Page:    1,
+		PerPage: defaultSegmentsPerPage,
+		SortBy:  model.SegmentsSortBySlug,
+		Desc:    req.GetDesc(),
+	}
+
+	v := validation.Validator{}
+	if req.GetPage() != 0 {
+		params.Page = int(req.GetPage())
+		v.Check(params.Page >= 1, "page", validation.CodeOutOfRange, "page must be positive")
+	}
+	if req.GetPerPage() != 0 {
+		params.PerPage = int(req.GetPerPage())
+		v.Check(params.PerPage >= 1 && params.PerPage <= maxSegmentsPerPage, "perPage", validation.CodeOutOfRange,
+			"perPage must be between 1 and 100")
+	}
+	if req.GetSortBy() != "" {
+		params.SortBy = req.GetSortBy()
+	}
+	switch params.SortBy {
+	case model.SegmentsSortBySlug, model.SegmentsSortByCreatedAt, model.SegmentsSortByMembersCount:
+	default:
+		v.Add("sortBy", validation.CodeInvalid, `sortBy must be "slug", "createdAt" or "membersCount"`)
+	}
+
+	return params, v.Err()
+}
+
+func (s *server) UpdateSegment(ctx context.Context, req *segmentationpb.UpdateSegmentRequest) (*segmentationpb.Segment, error) {
+	v := validation.Validator{}
+	v.SegmentSlug("slug", req.GetSlug())
+
+	segmentData := model.UpdateSegment{
+		Description: req.Description,
+		Owner:       req.Owner,
+		DefaultTTL:  ttlFromPB(&v, "defaultTtl", req.GetDefaultTtl()),
+	}
+	if req.AutoJoinPercent != nil {
+		autoJoinPercent := int(req.GetAutoJoinPercent())
+		segmentData.AutoJoinPercent = &autoJoinPercent
+	}
+	if req.Tags != nil {
+		tags := req.GetTags().GetTags()
+		if tags == nil {
+			tags = []string{}
+		}
+		segmentData.Tags = &tags
+	}
+	segmentData.Validate(&v)
+	if err := v.Err(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	segment, err := s.segmentService.UpdateSegment(ctx, req.GetSlug(), segmentData)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return segmentToPB(segment), nil
+}
+
+func (s *server) DeleteSegment(ctx context.Context, req *segmentationpb.DeleteSegmentRequest) (*emptypb.Empty, error) {
+	if err := validateSegmentSlug(req.GetSlug()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.segmentService.DeleteSegment(ctx, req.GetSlug()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func validateSegmentSlug(slug string) error {
+	v := validation.Validator{}
+	v.SegmentSlug("slug", slug)
+
+	return v.Err()
+}
@@ -0,0 +1,640 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: segmentation/v1/segmentation.proto
+
+package segmentationpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SegmentService_CreateSegment_FullMethodName = "/segmentation.v1.SegmentService/CreateSegment"
+	SegmentService_GetSegment_FullMethodName    = "/segmentation.v1.SegmentService/GetSegment"
+	SegmentService_ListSegments_FullMethodName  = "/segmentation.v1.SegmentService/ListSegments"
+	SegmentService_UpdateSegment_FullMethodName = "/segmentation.v1.SegmentService/UpdateSegment"
+	SegmentService_DeleteSegment_FullMethodName = "/segmentation.v1.SegmentService/DeleteSegment"
+)
+
+// SegmentServiceClient is the client API for SegmentService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SegmentServiceClient interface {
+	CreateSegment(ctx context.Context, in *CreateSegmentRequest, opts ...grpc.CallOption) (*Segment, error)
+	GetSegment(ctx context.Context, in *GetSegmentRequest, opts ...grpc.CallOption) (*Segment, error)
+	ListSegments(ctx context.Context, in *ListSegmentsRequest, opts ...grpc.CallOption) (*ListSegmentsResponse, error)
+	UpdateSegment(ctx context.Context, in *UpdateSegmentRequest, opts ...grpc.CallOption) (*Segment, error)
+	// DeleteSegment deletes the segment like DELETE /v2/segments/{slug}, it can be restored over the HTTP API.
+	DeleteSegment(ctx context.Context, in *DeleteSegmentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type segmentServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSegmentServiceClient(cc grpc.ClientConnInterface) SegmentServiceClient {
+	return &segmentServiceClient{cc}
+}
+
+func (c *segmentServiceClient) CreateSegment(ctx context.Context, in *CreateSegmentRequest, opts ...grpc.CallOption) (*Segment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Segment)
+	err := c.cc.Invoke(ctx, SegmentService_CreateSegment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *segmentServiceClient) GetSegment(ctx context.Context, in *GetSegmentRequest, opts ...grpc.CallOption) (*Segment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Segment)
+	err := c.cc.Invoke(ctx, SegmentService_GetSegment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *segmentServiceClient) ListSegments(ctx context.Context, in *ListSegmentsRequest, opts ...grpc.CallOption) (*ListSegmentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSegmentsResponse)
+	err := c.cc.Invoke(ctx, SegmentService_ListSegments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *segmentServiceClient) UpdateSegment(ctx context.Context, in *UpdateSegmentRequest, opts ...grpc.CallOption) (*Segment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Segment)
+	err := c.cc.Invoke(ctx, SegmentService_UpdateSegment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *segmentServiceClient) DeleteSegment(ctx context.Context, in *DeleteSegmentRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, SegmentService_DeleteSegment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SegmentServiceServer is the server API for SegmentService service.
+// All implementations must embed UnimplementedSegmentServiceServer
+// for forward compatibility.
+type SegmentServiceServer interface {
+	CreateSegment(context.Context, *CreateSegmentRequest) (*Segment, error)
+	GetSegment(context.Context, *GetSegmentRequest) (*Segment, error)
+	ListSegments(context.Context, *ListSegmentsRequest) (*ListSegmentsResponse, error)
+	UpdateSegment(context.Context, *UpdateSegmentRequest) (*Segment, error)
+	// DeleteSegment deletes the segment like DELETE /v2/segments/{slug}, it can be restored over the HTTP API.
+	DeleteSegment(context.Context, *DeleteSegmentRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedSegmentServiceServer()
+}
+
+// UnimplementedSegmentServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSegmentServiceServer struct{}
+
+func (UnimplementedSegmentServiceServer) CreateSegment(context.Context, *CreateSegmentRequest) (*Segment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSegment not implemented")
+}
+func (UnimplementedSegmentServiceServer) GetSegment(context.Context, *GetSegmentRequest) (*Segment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSegment not implemented")
+}
+func (UnimplementedSegmentServiceServer) ListSegments(context.Context, *ListSegmentsRequest) (*ListSegmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSegments not implemented")
+}
+func (UnimplementedSegmentServiceServer) UpdateSegment(context.Context, *UpdateSegmentRequest) (*Segment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSegment not implemented")
+}
+func (UnimplementedSegmentServiceServer) DeleteSegment(context.Context, *DeleteSegmentRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSegment not implemented")
+}
+func (UnimplementedSegmentServiceServer) mustEmbedUnimplementedSegmentServiceServer() {}
+func (UnimplementedSegmentServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeSegmentServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SegmentServiceServer will
+// result in compilation errors.
+type UnsafeSegmentServiceServer interface {
+	mustEmbedUnimplementedSegmentServiceServer()
+}
+
+func RegisterSegmentServiceServer(s grpc.ServiceRegistrar, srv SegmentServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSegmentServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SegmentService_ServiceDesc, srv)
+}
+
+func _SegmentService_CreateSegment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSegmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SegmentServiceServer).CreateSegment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SegmentService_CreateSegment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SegmentServiceServer).CreateSegment(ctx, req.(*CreateSegmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SegmentService_GetSegment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSegmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SegmentServiceServer).GetSegment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SegmentService_GetSegment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SegmentServiceServer).GetSegment(ctx, req.(*GetSegmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SegmentService_ListSegments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSegmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SegmentServiceServer).ListSegments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SegmentService_ListSegments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SegmentServiceServer).ListSegments(ctx, req.(*ListSegmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SegmentService_UpdateSegment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSegmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SegmentServiceServer).UpdateSegment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SegmentService_UpdateSegment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SegmentServiceServer).UpdateSegment(ctx, req.(*UpdateSegmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SegmentService_DeleteSegment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSegmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SegmentServiceServer).DeleteSegment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SegmentService_DeleteSegment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SegmentServiceServer).DeleteSegment(ctx, req.(*DeleteSegmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SegmentService_ServiceDesc is the grpc.ServiceDesc for SegmentService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SegmentService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "segmentation.v1.SegmentService",
+	HandlerType: (*SegmentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateSegment",
+			Handler:    _SegmentService_CreateSegment_Handler,
+		},
+		{
+			MethodName: "GetSegment",
+			Handler:    _SegmentService_GetSegment_Handler,
+		},
+		{
+			MethodName: "ListSegments",
+			Handler:    _SegmentService_ListSegments_Handler,
+		},
+		{
+			MethodName: "UpdateSegment",
+			Handler:    _SegmentService_UpdateSegment_Handler,
+		},
+		{
+			MethodName: "DeleteSegment",
+			Handler:    _SegmentService_DeleteSegment_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "segmentation/v1/segmentation.proto",
+}
+
+const (
+	UserService_UserSegmentAction_FullMethodName          = "/segmentation.v1.UserService/UserSegmentAction"
+	UserService_GetActiveUserSegments_FullMethodName      = "/segmentation.v1.UserService/GetActiveUserSegments"
+	UserService_BatchGetActiveUserSegments_FullMethodName = "/segmentation.v1.UserService/BatchGetActiveUserSegments"
+)
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserServiceClient interface {
+	UserSegmentAction(ctx context.Context, in *UserSegmentActionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// GetActiveUserSegments registers an unknown user like the HTTP API does, which may add them to auto-join segments.
+	GetActiveUserSegments(ctx context.Context, in *GetActiveUserSegmentsRequest, opts ...grpc.CallOption) (*GetActiveUserSegmentsResponse, error)
+	// BatchGetActiveUserSegments looks up at most 1000 users, unknown users are registered like in GetActiveUserSegments.
+	BatchGetActiveUserSegments(ctx context.Context, in *BatchGetActiveUserSegmentsRequest, opts ...grpc.CallOption) (*BatchGetActiveUserSegmentsResponse, error)
+}
+
+type userServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) UserSegmentAction(ctx context.Context, in *UserSegmentActionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, UserService_UserSegmentAction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetActiveUserSegments(ctx context.Context, in *GetActiveUserSegmentsRequest, opts ...grpc.CallOption) (*GetActiveUserSegmentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetActiveUserSegmentsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetActiveUserSegments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) BatchGetActiveUserSegments(ctx context.Context, in *BatchGetActiveUserSegmentsRequest, opts ...grpc.CallOption) (*BatchGetActiveUserSegmentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetActiveUserSegmentsResponse)
+	err := c.cc.Invoke(ctx, UserService_BatchGetActiveUserSegments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+// All implementations must embed UnimplementedUserServiceServer
+// for forward compatibility.
+type UserServiceServer interface {
+	UserSegmentAction(context.Context, *UserSegmentActionRequest) (*emptypb.Empty, error)
+	// GetActiveUserSegments registers an unknown user like the HTTP API does, which may add them to auto-join segments.
+	GetActiveUserSegments(context.Context, *GetActiveUserSegmentsRequest) (*GetActiveUserSegmentsResponse, error)
+	// BatchGetActiveUserSegments looks up at most 1000 users, unknown users are registered like in GetActiveUserSegments.
+	BatchGetActiveUserSegments(context.Context, *BatchGetActiveUserSegmentsRequest) (*BatchGetActiveUserSegmentsResponse, error)
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+// UnimplementedUserServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUserServiceServer struct{}
+
+func (UnimplementedUserServiceServer) UserSegmentAction(context.Context, *UserSegmentActionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UserSegmentAction not implemented")
+}
+func (UnimplementedUserServiceServer) GetActiveUserSegments(context.Context, *GetActiveUserSegmentsRequest) (*GetActiveUserSegmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetActiveUserSegments not implemented")
+}
+func (UnimplementedUserServiceServer) BatchGetActiveUserSegments(context.Context, *BatchGetActiveUserSegmentsRequest) (*BatchGetActiveUserSegmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetActiveUserSegments not implemented")
+}
+func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
+func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserServiceServer will
+// result in compilation errors.
+type UnsafeUserServiceServer interface {
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
+	// If the following call pancis, it indicates UnimplementedUserServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&UserService_ServiceDesc, srv)
+}
+
+func _UserService_UserSegmentAction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserSegmentActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UserSegmentAction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_UserSegmentAction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UserSegmentAction(ctx, req.(*UserSegmentActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetActiveUserSegments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetActiveUserSegmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetActiveUserSegments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetActiveUserSegments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetActiveUserSegments(ctx, req.(*GetActiveUserSegmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_BatchGetActiveUserSegments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetActiveUserSegmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).BatchGetActiveUserSegments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_BatchGetActiveUserSegments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).BatchGetActiveUserSegments(ctx, req.(*BatchGetActiveUserSegmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "segmentation.v1.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UserSegmentAction",
+			Handler:    _UserService_UserSegmentAction_Handler,
+		},
+		{
+			MethodName: "GetActiveUserSegments",
+			Handler:    _UserService_GetActiveUserSegments_Handler,
+		},
+		{
+			MethodName: "BatchGetActiveUserSegments",
+			Handler:    _UserService_BatchGetActiveUserSegments_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "segmentation/v1/segmentation.proto",
+}
+
+const (
+	HistoryService_GetHistory_FullMethodName        = "/segmentation.v1.HistoryService/GetHistory"
+	HistoryService_GetUserSegmentsAt_FullMethodName = "/segmentation.v1.HistoryService/GetUserSegmentsAt"
+	HistoryService_GetSegmentUsersAt_FullMethodName = "/segmentation.v1.HistoryService/GetSegmentUsersAt"
+)
+
+// HistoryServiceClient is the client API for HistoryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HistoryServiceClient interface {
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// GetUserSegmentsAt reconstructs the segments the user was in at the given moment by replaying the history.
+	GetUserSegmentsAt(ctx context.Context, in *GetUserSegmentsAtRequest, opts ...grpc.CallOption) (*GetUserSegmentsAtResponse, error)
+	// GetSegmentUsersAt reconstructs the users of the segment at the given moment by replaying the history.
+	GetSegmentUsersAt(ctx context.Context, in *GetSegmentUsersAtRequest, opts ...grpc.CallOption) (*GetSegmentUsersAtResponse, error)
+}
+
+type historyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHistoryServiceClient(cc grpc.ClientConnInterface) HistoryServiceClient {
+	return &historyServiceClient{cc}
+}
+
+func (c *historyServiceClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, HistoryService_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *historyServiceClient) GetUserSegmentsAt(ctx context.Context, in *GetUserSegmentsAtRequest, opts ...grpc.CallOption) (*GetUserSegmentsAtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUserSegmentsAtResponse)
+	err := c.cc.Invoke(ctx, HistoryService_GetUserSegmentsAt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *historyServiceClient) GetSegmentUsersAt(ctx context.Context, in *GetSegmentUsersAtRequest, opts ...grpc.CallOption) (*GetSegmentUsersAtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSegmentUsersAtResponse)
+	err := c.cc.Invoke(ctx, HistoryService_GetSegmentUsersAt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HistoryServiceServer is the server API for HistoryService service.
+// All implementations must embed UnimplementedHistoryServiceServer
+// for forward compatibility.
+type HistoryServiceServer interface {
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	// GetUserSegmentsAt reconstructs the segments the user was in at the given moment by replaying the history.
+	GetUserSegmentsAt(context.Context, *GetUserSegmentsAtRequest) (*GetUserSegmentsAtResponse, error)
+	// GetSegmentUsersAt reconstructs the users of the segment at the given moment by replaying the history.
+	GetSegmentUsersAt(context.Context, *GetSegmentUsersAtRequest) (*GetSegmentUsersAtResponse, error)
+	mustEmbedUnimplementedHistoryServiceServer()
+}
+
+// UnimplementedHistoryServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHistoryServiceServer struct{}
+
+func (UnimplementedHistoryServiceServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedHistoryServiceServer) GetUserSegmentsAt(context.Context, *GetUserSegmentsAtRequest) (*GetUserSegmentsAtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserSegmentsAt not implemented")
+}
+func (UnimplementedHistoryServiceServer) GetSegmentUsersAt(context.Context, *GetSegmentUsersAtRequest) (*GetSegmentUsersAtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSegmentUsersAt not implemented")
+}
+func (UnimplementedHistoryServiceServer) mustEmbedUnimplementedHistoryServiceServer() {}
+func (UnimplementedHistoryServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeHistoryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HistoryServiceServer will
+// result in compilation errors.
+type UnsafeHistoryServiceServer interface {
+	mustEmbedUnimplementedHistoryServiceServer()
+}
+
+func RegisterHistoryServiceServer(s grpc.ServiceRegistrar, srv HistoryServiceServer) {
+	// If the following call pancis, it indicates UnimplementedHistoryServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HistoryService_ServiceDesc, srv)
+}
+
+func _HistoryService_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HistoryServiceServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HistoryService_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HistoryServiceServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HistoryService_GetUserSegmentsAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserSegmentsAtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HistoryServiceServer).GetUserSegmentsAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HistoryService_GetUserSegmentsAt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HistoryServiceServer).GetUserSegmentsAt(ctx, req.(*GetUserSegmentsAtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HistoryService_GetSegmentUsersAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSegmentUsersAtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HistoryServiceServer).GetSegmentUsersAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HistoryService_GetSegmentUsersAt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HistoryServiceServer).GetSegmentUsersAt(ctx, req.(*GetSegmentUsersAtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HistoryService_ServiceDesc is the grpc.ServiceDesc for HistoryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HistoryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "segmentation.v1.HistoryService",
+	HandlerType: (*HistoryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetHistory",
+			Handler:    _HistoryService_GetHistory_Handler,
+		},
+		{
+			MethodName: "GetUserSegmentsAt",
+			Handler:    _HistoryService_GetUserSegmentsAt_Handler,
+		},
+		{
+			MethodName: "GetSegmentUsersAt",
+			Handler:    _HistoryService_GetSegmentUsersAt_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "segmentation/v1/segmentation.proto",
+}
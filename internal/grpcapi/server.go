@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/grpcapi/segmentationpb"
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
+	"github.com/google/uuid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+)
+
+// Metadata keys of the actor and request ids, the same as the headers of the HTTP API.
+const (
+	actorIdKey   = "x-actor-id"
+	requestIdKey = "x-request-id"
+
+	// maxMetaLength is the size of the history columns the metadata values are stored in.
+	maxMetaLength = 255
+)
+
+type server struct {
+	segmentationpb.UnimplementedSegmentServiceServer
+	segmentationpb.UnimplementedUserServiceServer
+	segmentationpb.UnimplementedHistoryServiceServer
+
+	userService    userService
+	segmentService segmentService
+	historyService historyService
+}
+
+// New creates a gRPC server backed by the same services as the HTTP API. A call is cancelled after requestTimeout
+// unless the client set an earlier deadline, zero means no limit.
+func New(us userService, ss segmentService, hs historyService, requestTimeout time.Duration) *grpc.Server {
+	s := &server{
+		userService:    us,
+		segmentService: ss,
+		historyService: hs,
+	}
+
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(requestMeta, callTimeout(requestTimeout)))
+	segmentationpb.RegisterSegmentServiceServer(gs, s)
+	segmentationpb.RegisterUserServiceServer(gs, s)
+	segmentationpb.RegisterHistoryServiceServer(gs, s)
+	reflection.Register(gs)
+
+	return gs
+}
+
+// requestMeta puts the actor and request ids of the call metadata into the context, they are recorded in the history.
+// The request id is sent back in the response header, it is generated if the client did not send it.
+func requestMeta(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	actorId := firstValue(md, actorIdKey)
+	if len(actorId) > maxMetaLength {
+		return nil, toStatus(ErrInvalidActorId)
+	}
+
+	requestId := firstValue(md, requestIdKey)
+	if len(requestId) > maxMetaLength {
+		return nil, toStatus(ErrInvalidRequestId)
+	}
+	if requestId == "" {
+		requestId = uuid.NewString()
+	}
+
+	// the call goes on without the header if it can not be sent
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIdKey, requestId))
+
+	return handler(audit.WithMeta(ctx, audit.Meta{
+		ActorID:   actorId,
+		RequestID: requestId,
+	}), req)
+}
+
+func callTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
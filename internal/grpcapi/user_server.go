@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/grpcapi/segmentationpb"
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// maxBatchUsers limits the users looked up by one BatchGetActiveUserSegments call.
+const maxBatchUsers = 1000
+
+func (s *server) UserSegmentAction(ctx context.Context, req *segmentationpb.UserSegmentActionRequest) (*emptypb.Empty, error) {
+	action, err := userSegmentAction(req)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.userService.UserSegmentAction(ctx, action); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func userSegmentAction(req *segmentationpb.UserSegmentActionRequest) (model.UserSegmentAction, error) {
+	v := validation.Validator{}
+	action := model.UserSegmentAction{
+		UserID:                int(req.GetUserId()),
+		SegmentsSlugsToAdd:    req.GetSegmentsToAdd(),
+		SegmentsSlugsToRemove: req.GetSegmentsToRemove(),
+		StartTime:             timeFromPB(&v, "startTime", req.GetStartTime()),
+		SegmentExpirationTime: timeFromPB(&v, "expirationTime", req.GetExpirationTime()),
+		TTL:                   ttlFromPB(&v, "ttl", req.GetTtl()),
+		NoExpiration:          req.GetNoExpiration(),
+		Upsert:                req.GetUpsert(),
+	}
+
+	if len(req.GetSegmentExpirations()) > 0 {
+		action.SegmentExpirations = make(map[string]*time.Time, len(req.GetSegmentExpirations()))
+		for slug, expirationTime := range req.GetSegmentExpirations() {
+			// a map value can not be null, so the zero timestamp stands for a membership that does not expire
+			if expirationTime.GetSeconds() == 0 && expirationTime.GetNanos() == 0 {
+				action.SegmentExpirations[slug] = nil
+				continue
+			}
+			action.SegmentExpirations[slug] = timeFromPB(&v, "segmentExpirations."+slug, expirationTime)
+		}
+	}
+
+	if len(req.GetSegmentTtls()) > 0 {
+		action.SegmentTTLs = make(map[string]model.TTL, len(req.GetSegmentTtls()))
+		for slug, ttl := range req.GetSegmentTtls() {
+			if segmentTTL := ttlFromPB(&v, "segmentTtls."+slug, ttl); segmentTTL != nil {
+				action.SegmentTTLs[slug] = *segmentTTL
+			}
+		}
+	}
+
+	action.Validate(&v)
+
+	return action, v.Err()
+}
+
+func (s *server) GetActiveUserSegments(ctx context.Context, req *segmentationpb.GetActiveUserSegmentsRequest) (*segmentationpb.GetActiveUserSegmentsResponse, error) {
+	v := validation.Validator{}
+	checkUserId(&v, "userId", req.GetUserId())
+	if err := v.Err(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	segments, err := s.userService.GetActiveUserSegments(ctx, int(req.GetUserId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &segmentationpb.GetActiveUserSegmentsResponse{
+		Segments: segments,
+	}, nil
+}
+
+func (s *server) BatchGetActiveUserSegments(ctx context.Context, req *segmentationpb.BatchGetActiveUserSegmentsRequest) (*segmentationpb.BatchGetActiveUserSegmentsResponse, error) {
+	v := validation.Validator{}
+	var userIds []int
+	if v.Check(len(req.GetUserIds()) > 0, "userIds", validation.CodeRequired, "no users specified") &&
+		v.Check(len(req.GetUserIds()) <= maxBatchUsers, "userIds", validation.CodeTooMany, fmt.Sprintf("at most %d users are allowed", maxBatchUsers)) {
+		userIds = userIdsFromPB(&v, "userIds", req.GetUserIds())
+	}
+	if err := v.Err(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	usersSegments, err := s.userService.GetActiveUsersSegments(ctx, userIds)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &segmentationpb.BatchGetActiveUserSegmentsResponse{
+		Users: make([]*segmentationpb.UserSegments, 0, len(usersSegments)),
+	}
+	for _, userSegments := range usersSegments {
+		resp.Users = append(resp.Users, &segmentationpb.UserSegments{
+			UserId:   int64(userSegments.UserId),
+			Segments: userSegments.SegmentSlugs,
+		})
+	}
+
+	return resp, nil
+}
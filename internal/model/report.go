@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+const (
+	ReportJobStatusPending   = "pending"
+	ReportJobStatusRunning   = "running"
+	ReportJobStatusDone      = "done"
+	ReportJobStatusFailed    = "failed"
+	ReportJobStatusCancelled = "cancelled"
+)
+
+// Kinds of reports. History reports list history entries, segment reports aggregate membership changes of all users by segment.
+const (
+	ReportKindHistory      = "history"
+	ReportKindSegmentDaily = "segmentDaily"
+	ReportKindSegmentChurn = "segmentChurn"
+)
+
+// SegmentDailyStats is the number of users added to and removed from a segment during a day.
+type SegmentDailyStats struct {
+	Day         time.Time
+	SegmentSlug string
+	Added       int64
+	Removed     int64
+	NetChange   int64
+}
+
+// SegmentChurn is the number of users added to and removed from a segment over a time range.
+type SegmentChurn struct {
+	SegmentSlug string
+	Added       int64
+	Removed     int64
+	NetChange   int64
+	// Churn is the number of added and removed users together.
+	Churn int64
+}
+
+// ReportJobParams describes what goes into a report generated by a report job.
+type ReportJobParams struct {
+	// Kind is one of the report kinds, a history report if empty.
+	Kind         string     `json:"kind,omitempty"`
+	UserIDs      []int      `json:"userIds,omitempty"`
+	SegmentSlugs []string   `json:"slugs,omitempty"`
+	Operation    Operation  `json:"operation,omitempty"`
+	From         *time.Time `json:"from,omitempty"`
+	To           *time.Time `json:"to,omitempty"`
+	Format       string     `json:"format"`
+	Delimiter    string     `json:"delimiter,omitempty"`
+	Header       bool       `json:"header,omitempty"`
+	// Limit is the number of segments in a segmentChurn report, all segments if zero.
+	Limit int `json:"limit,omitempty"`
+}
+
+type ReportJob struct {
+	ID          int64           `json:"id"`
+	Params      ReportJobParams `json:"params"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	RowsTotal   *int64          `json:"rowsTotal"`
+	RowsWritten int64           `json:"rowsWritten"`
+	FileName    *string         `json:"-"`
+	Error       *string         `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+type ReportJobState struct {
+	ReportJob
+	// Progress is the share of written rows in percent.
+	Progress float64 `json:"progress"`
+	URL      string  `json:"url,omitempty"`
+}
+
+// ReportFile is a report file stored in the database.
+type ReportFile struct {
+	Name string
+	Size int64
+}
@@ -1,17 +1,141 @@
 package model
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/pkg/ttl"
+)
 
 type AddSegment struct {
-	SegmentSlug     string `json:"slug"`
-	AutoJoinPercent int    `json:"autoJoinPercent"`
+	SegmentSlug     string   `json:"slug"`
+	AutoJoinPercent int      `json:"autoJoinPercent"`
+	Description     string   `json:"description"`
+	Owner           string   `json:"owner"`
+	Tags            []string `json:"tags"`
+	// DefaultTTL is the expiration of memberships added without an explicit expiration time.
+	DefaultTTL *TTL `json:"defaultTtl,omitempty" swaggertype:"string" example:"P7D"`
+}
+
+// UpdateSegment holds the segment fields to change, nil fields are left as is.
+type UpdateSegment struct {
+	AutoJoinPercent *int      `json:"autoJoinPercent,omitempty"`
+	Description     *string   `json:"description,omitempty"`
+	Owner           *string   `json:"owner,omitempty"`
+	Tags            *[]string `json:"tags,omitempty"`
+	// DefaultTTL replaces the default expiration of memberships, zero removes it.
+	DefaultTTL *TTL `json:"defaultTtl,omitempty" swaggertype:"string" example:"72h"`
+}
+
+type Segment struct {
+	ID              int       `json:"-"`
+	Slug            string    `json:"slug"`
+	AutoJoinPercent int       `json:"autoJoinPercent"`
+	Description     string    `json:"description"`
+	Owner           string    `json:"owner"`
+	Tags            []string  `json:"tags"`
+	DefaultTTL      *TTL      `json:"defaultTtl,omitempty" swaggertype:"string" example:"168h0m0s"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// TTL is a time to live of a membership. In JSON it is a Go duration such as "72h" or an ISO 8601 duration such as "P7D".
+type TTL time.Duration
+
+func (t TTL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(t).String())
+}
+
+func (t *TTL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	d, err := ttl.Parse(s)
+	if err != nil {
+		return err
+	}
+	*t = TTL(d)
+
+	return nil
+}
+
+type SegmentFilter struct {
+	Tag   string
+	Owner string
+}
+
+const (
+	SegmentsSortBySlug         = "slug"
+	SegmentsSortByCreatedAt    = "createdAt"
+	SegmentsSortByMembersCount = "membersCount"
+)
+
+type SegmentsPageParams struct {
+	SegmentFilter
+	Page    int
+	PerPage int
+	SortBy  string
+	Desc    bool
+}
+
+type SegmentWithMembersCount struct {
+	Segment
+	MembersCount int `json:"membersCount"`
+}
+
+type SegmentsPage struct {
+	Segments []SegmentWithMembersCount `json:"segments"`
+	Page     int                       `json:"page"`
+	PerPage  int                       `json:"perPage"`
+	Total    int                       `json:"total"`
+}
+
+type SegmentMember struct {
+	UserID         int        `json:"userId"`
+	ExpirationTime *time.Time `json:"expirationTime,omitempty"`
+}
+
+type SegmentMembersPage struct {
+	Members    []SegmentMember `json:"members"`
+	NextCursor *int            `json:"nextCursor"`
 }
 
 type UserSegmentAction struct {
-	UserID                int        `json:"userId"`
-	SegmentsSlugsToAdd    []string   `json:"segmentsToAdd"`
-	SegmentsSlugsToRemove []string   `json:"segmentsToRemove"`
+	UserID                int      `json:"userId"`
+	SegmentsSlugsToAdd    []string `json:"segmentsToAdd"`
+	SegmentsSlugsToRemove []string `json:"segmentsToRemove"`
+	// StartTime schedules the added memberships to start in the future, ttls are counted from it.
+	StartTime *time.Time `json:"startTime,omitempty"`
+	// SegmentExpirationTime applies to the added segments that have no expiration time in SegmentExpirations.
 	SegmentExpirationTime *time.Time `json:"expirationTime,omitempty"`
+	// TTL applies to the added segments like SegmentExpirationTime, counting from the time of the request.
+	TTL *TTL `json:"ttl,omitempty" swaggertype:"string" example:"72h"`
+	// SegmentExpirations sets the expiration time of single added segments by slug, null for a membership that does not expire.
+	SegmentExpirations map[string]*time.Time `json:"segmentExpirations,omitempty"`
+	// SegmentTTLs sets the ttl of single added segments by slug.
+	SegmentTTLs map[string]TTL `json:"segmentTtls,omitempty" swaggertype:"object,string"`
+	// NoExpiration makes the added segments that have no expiration time or ttl of their own never expire
+	// instead of getting the default ttl of the segment.
+	NoExpiration bool `json:"noExpiration,omitempty"`
+	// Upsert sets the requested expiration time on memberships the user already has and the requested start time on those
+	// that have not started yet, otherwise they are left as they are.
+	Upsert bool `json:"upsert,omitempty"`
+}
+
+// SegmentExpiration is a segment to add a user to with the expiration time of the membership, nil if it does not expire.
+type SegmentExpiration struct {
+	SegmentSlug    string
+	ExpirationTime *time.Time
+	// UseDefaultTTL makes the membership expire after the default ttl of the segment, ExpirationTime is ignored.
+	UseDefaultTTL bool
+	// StartTime schedules the membership, nil if it starts right away.
+	StartTime *time.Time
 }
 
 type UsersSegments struct {
@@ -19,20 +143,142 @@ type UsersSegments struct {
 	SegmentSlugs []string
 }
 
+// Operation is the kind of a history entry.
+type Operation string
+
+const (
+	OperationAdding   Operation = "adding"
+	OperationRemoval  Operation = "removal"
+	OperationRestored Operation = "restored"
+	// OperationExpirationChanged is recorded when the expiration time of an existing membership changes.
+	OperationExpirationChanged Operation = "expiration_changed"
+	// OperationRenamed, OperationSegmentCreated and OperationSegmentDeleted are about the segment itself and have no user.
+	OperationRenamed        Operation = "renamed"
+	OperationSegmentCreated Operation = "segment_created"
+	OperationSegmentDeleted Operation = "segment_deleted"
+)
+
+// IsValid reports whether o is one of the known operations, the history_operation type of the database has the same values.
+func (o Operation) IsValid() bool {
+	switch o {
+	case OperationAdding, OperationRemoval, OperationRestored, OperationExpirationChanged,
+		OperationRenamed, OperationSegmentCreated, OperationSegmentDeleted:
+		return true
+	}
+
+	return false
+}
+
+// Sources of history entries, the part of the service that made the change.
+const (
+	HistorySourceAPI            = "api"
+	HistorySourceTTLExpiry      = "ttl_expiry"
+	HistorySourceSegmentDeleted = "segment_deleted"
+	HistorySourceAutoJoin       = "auto_join"
+	HistorySourceImport         = "import"
+	HistorySourceSchedule       = "schedule"
+)
+
 type History struct {
-	UserID        int
-	SegmentSlug   string
-	Operation     string
+	ID            int64     `json:"-"`
+	UserID        *int      `json:"userId,omitempty"`
+	SegmentSlug   string    `json:"segmentSlug"`
+	Operation     Operation `json:"operation"`
+	OperationTime time.Time `json:"operationTime"`
+	// Source, ActorID and RequestID are empty for entries recorded before they were introduced.
+	Source    string `json:"source,omitempty"`
+	ActorID   string `json:"actorId,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// HistoryFilter selects history entries. Empty fields do not filter, zero Limit returns all matching entries.
+type HistoryFilter struct {
+	UserIDs      []int
+	SegmentSlugs []string
+	Operation    Operation
+	From         *time.Time
+	To           *time.Time
+	After        *HistoryCursor
+	Limit        int
+}
+
+// HistoryCursor points at the last entry of a history page, entries are ordered by operation time and id.
+type HistoryCursor struct {
 	OperationTime time.Time
+	ID            int64
 }
+
+// String makes an opaque cursor out of the operation time and id, the HTTP and gRPC APIs hand it out as is.
+func (c HistoryCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.OperationTime.UnixNano(), c.ID)))
+}
+
+// ParseHistoryCursor parses a cursor made by HistoryCursor.String.
+func ParseHistoryCursor(cursor string) (HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return HistoryCursor{}, err
+	}
+
+	operationTimeStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return HistoryCursor{}, fmt.Errorf("malformed cursor %q", raw)
+	}
+
+	operationTime, err := strconv.ParseInt(operationTimeStr, 10, 64)
+	if err != nil {
+		return HistoryCursor{}, err
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return HistoryCursor{}, err
+	}
+
+	return HistoryCursor{
+		OperationTime: time.Unix(0, operationTime).UTC(),
+		ID:            id,
+	}, nil
+}
+
+type HistoryPage struct {
+	Entries    []History
+	NextCursor *HistoryCursor
+}
+
+// MembershipMismatch is a user and segment pair whose membership replayed from the history differs from users_segments.
+type MembershipMismatch struct {
+	UserID      int    `json:"userId"`
+	SegmentSlug string `json:"segmentSlug"`
+	// InHistory reports whether the user is in the segment according to the history.
+	InHistory bool `json:"inHistory"`
+	// InLive reports whether the user is in the segment according to users_segments.
+	InLive bool `json:"inLive"`
+}
+
 type HistoryDataMultipleSegments struct {
 	UserId      int
 	SegmentSlug []string
-	Operation   string
+	Operation   Operation
+	Source      string
+	ActorID     string
+	RequestID   string
 }
 
 type HistoryDataMultipleUsers struct {
 	UsersIDs    []int
 	SegmentSlug string
-	Operation   string
+	Operation   Operation
+	Source      string
+	ActorID     string
+	RequestID   string
+}
+
+// SegmentEvent is a history entry about the segment itself rather than one of its users.
+type SegmentEvent struct {
+	SegmentSlug string
+	Operation   Operation
+	Source      string
+	ActorID     string
+	RequestID   string
 }
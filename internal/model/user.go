@@ -0,0 +1,10 @@
+package model
+
+type AddUser struct {
+	UserID int `json:"userId"`
+}
+
+type ImportUsersResult struct {
+	Imported       int `json:"imported"`
+	AlreadyExisted int `json:"alreadyExisted"`
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/pkg/validation"
+)
+
+// Limits of the lists in requests.
+const (
+	MaxActionSegments = 100
+	MaxSegmentTags    = 50
+)
+
+// MaxUserID is the largest user id, user ids are stored in INT columns.
+const MaxUserID = math.MaxInt32
+
+// IsValidUserID reports whether the user id is positive and fits in the INT columns.
+func IsValidUserID(userId int) bool {
+	return userId >= 1 && userId <= MaxUserID
+}
+
+// CheckUserID adds a violation to v if the user id is not valid.
+func CheckUserID(v *validation.Validator, field string, userId int) bool {
+	if !v.Check(userId >= 1, field, validation.CodeInvalid, field+" must be positive") {
+		return false
+	}
+
+	return v.Check(userId <= MaxUserID, field, validation.CodeOutOfRange, fmt.Sprintf("%s must be at most %d", field, MaxUserID))
+}
+
+// Validate adds the violations of the segment to v.
+func (s AddSegment) Validate(v *validation.Validator) {
+	v.NewSegmentSlug("slug", s.SegmentSlug)
+	checkAutoJoinPercent(v, s.AutoJoinPercent)
+	v.Tags("tags", s.Tags, MaxSegmentTags)
+}
+
+// Validate adds the violations of the changes to v.
+func (s UpdateSegment) Validate(v *validation.Validator) {
+	if s.AutoJoinPercent != nil {
+		checkAutoJoinPercent(v, *s.AutoJoinPercent)
+	}
+	if s.Tags != nil {
+		v.Tags("tags", *s.Tags, MaxSegmentTags)
+	}
+}
+
+func checkAutoJoinPercent(v *validation.Validator, percent int) {
+	v.Check(percent >= 0 && percent <= 100, "autoJoinPercent", validation.CodeOutOfRange, "autoJoinPercent must be between 0 and 100")
+}
+
+// Validate adds the violations of the action to v.
+func (a UserSegmentAction) Validate(v *validation.Validator) {
+	CheckUserID(v, "userId", a.UserID)
+
+	if len(a.SegmentsSlugsToAdd) == 0 && len(a.SegmentsSlugsToRemove) == 0 {
+		v.Add("segmentsToAdd", validation.CodeRequired, "no segments specified")
+	}
+	v.SegmentSlugs("segmentsToAdd", a.SegmentsSlugsToAdd, MaxActionSegments)
+	v.SegmentSlugs("segmentsToRemove", a.SegmentsSlugsToRemove, MaxActionSegments)
+	for i, slug := range a.SegmentsSlugsToRemove {
+		if slug != "" && slices.Contains(a.SegmentsSlugsToAdd, slug) {
+			v.Add(fmt.Sprintf("segmentsToRemove[%d]", i), validation.CodeConflict, fmt.Sprintf("segment %q is both added and removed", slug))
+		}
+	}
+
+	checkExpirationTime(v, "expirationTime", a.SegmentExpirationTime)
+	for _, slug := range sortedKeys(a.SegmentExpirations) {
+		field := "segmentExpirations." + slug
+		v.Check(slices.Contains(a.SegmentsSlugsToAdd, slug), field, validation.CodeInvalid, `segment is not in "segmentsToAdd"`)
+		checkExpirationTime(v, field, a.SegmentExpirations[slug])
+	}
+
+	if a.TTL != nil {
+		v.Check(a.SegmentExpirationTime == nil, "ttl", validation.CodeConflict, "expirationTime and ttl are both set")
+		v.Check(*a.TTL > 0, "ttl", validation.CodeOutOfRange, "ttl must be positive")
+	}
+	if a.NoExpiration {
+		v.Check(a.SegmentExpirationTime == nil, "noExpiration", validation.CodeConflict, "expirationTime and noExpiration are both set")
+		v.Check(a.TTL == nil, "noExpiration", validation.CodeConflict, "ttl and noExpiration are both set")
+	}
+	for _, slug := range sortedKeys(a.SegmentTTLs) {
+		field := "segmentTtls." + slug
+		_, hasExpiration := a.SegmentExpirations[slug]
+		v.Check(slices.Contains(a.SegmentsSlugsToAdd, slug), field, validation.CodeInvalid, `segment is not in "segmentsToAdd"`)
+		v.Check(!hasExpiration, field, validation.CodeConflict, "expiration time and ttl of the segment are both set")
+		v.Check(a.SegmentTTLs[slug] > 0, field, validation.CodeOutOfRange, "ttl must be positive")
+	}
+
+	if a.StartTime != nil {
+		startsBeforeExpiration := a.SegmentExpirationTime == nil || a.SegmentExpirationTime.After(*a.StartTime)
+		for _, expirationTime := range a.SegmentExpirations {
+			if expirationTime != nil && !expirationTime.After(*a.StartTime) {
+				startsBeforeExpiration = false
+			}
+		}
+		v.Check(startsBeforeExpiration, "startTime", validation.CodeOutOfRange, "startTime must be before the expiration time")
+	}
+}
+
+func checkExpirationTime(v *validation.Validator, field string, expirationTime *time.Time) {
+	v.Check(expirationTime == nil || !expirationTime.Before(time.Now()), field, validation.CodeOutOfRange, "expiration time must be in the future")
+}
+
+// sortedKeys returns the keys of m in order, so that the violations are reported in the same order every time.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
@@ -1,15 +1,96 @@
 package app_err
 
+import "errors"
+
+// Kind tells what went wrong with a request, the API maps it to the response status.
+type Kind int
+
+const (
+	KindValidation Kind = iota
+	KindNotFound
+	KindConflict
+	KindForbidden
+)
+
+// FieldError is a field of a request that failed validation.
+type FieldError struct {
+	Field string `json:"field"`
+	// Code is stable like the code of the error, for example "required" or "too_long".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BusinessError is an error caused by the request rather than by the service. Code is stable and meant for clients
+// to tell errors apart, the message may change.
 type BusinessError struct {
+	kind    Kind
+	code    string
 	message string
+	fields  []FieldError
 }
 
 func (b BusinessError) Error() string {
 	return b.message
 }
 
-func NewBusinessError(message string) error {
+func (b BusinessError) Kind() Kind {
+	return b.kind
+}
+
+func (b BusinessError) Code() string {
+	return b.code
+}
+
+// Fields returns the fields that failed validation, if the error is about request fields.
+func (b BusinessError) Fields() []FieldError {
+	return b.fields
+}
+
+// Is reports whether target is a business error with the same code, so errors.Is matches errors created with another message.
+func (b BusinessError) Is(target error) bool {
+	t, ok := target.(BusinessError)
+	return ok && t.code == b.code
+}
+
+func NewValidationError(code, message string, fields ...FieldError) error {
+	return BusinessError{
+		kind:    KindValidation,
+		code:    code,
+		message: message,
+		fields:  fields,
+	}
+}
+
+func NewNotFoundError(code, message string) error {
+	return BusinessError{
+		kind:    KindNotFound,
+		code:    code,
+		message: message,
+	}
+}
+
+func NewConflictError(code, message string) error {
+	return BusinessError{
+		kind:    KindConflict,
+		code:    code,
+		message: message,
+	}
+}
+
+func NewForbiddenError(code, message string) error {
 	return BusinessError{
+		kind:    KindForbidden,
+		code:    code,
 		message: message,
 	}
 }
+
+// Code returns the code of a business error in the chain of err, empty if there is none.
+func Code(err error) string {
+	var bErr BusinessError
+	if errors.As(err, &bErr) {
+		return bErr.code
+	}
+
+	return ""
+}
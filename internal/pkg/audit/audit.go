@@ -0,0 +1,23 @@
+package audit
+
+import "context"
+
+// Meta tells who made a change and in which request, it is recorded together with the history entries of the change.
+type Meta struct {
+	// ActorID identifies the caller, empty if the caller did not introduce itself.
+	ActorID string
+	// RequestID correlates history entries with the request or the worker run that made them.
+	RequestID string
+}
+
+type metaKey struct{}
+
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// FromContext returns the meta added by WithMeta, the zero Meta if there is none.
+func FromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
@@ -19,6 +19,7 @@ func OpenDB(ctx context.Context, cfg config.DBConfig) (*pgxpool.Pool, error) {
 	config.ConnConfig.Database = cfg.PgDatabase
 	config.ConnConfig.User = cfg.PgUser
 	config.ConnConfig.Password = cfg.PgPassword
+	config.MaxConns = int32(cfg.PgMaxConns)
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
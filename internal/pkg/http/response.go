@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
@@ -15,30 +16,61 @@ type ErrorResponse struct {
 }
 
 type ErrorMessage struct {
+	// Code is stable and tells errors apart, Message is meant for people and may change.
+	Code    string `json:"code" example:"segment_not_found"`
 	Message string `json:"message"`
+	// Fields lists the request fields that failed validation.
+	Fields []app_err.FieldError `json:"fields,omitempty"`
 }
 
+// StatusClientClosedRequest is the non-standard status of a request the client stopped waiting for.
+const StatusClientClosedRequest = 499
+
+// Codes of the errors that are not business errors.
+const (
+	CodeInternal            = "internal"
+	CodeTimeout             = "timeout"
+	CodeClientClosedRequest = "client_closed_request"
+)
+
+var businessErrorStatuses = map[app_err.Kind]int{
+	app_err.KindValidation: http.StatusBadRequest,
+	app_err.KindNotFound:   http.StatusNotFound,
+	app_err.KindConflict:   http.StatusConflict,
+	app_err.KindForbidden:  http.StatusForbidden,
+}
+
+// WriteErrorResponse writes a business error with the status of its kind. An error caused by the request context is written as 504
+// if the request timed out and as 499 if the client went away, other errors are written as 500.
 func WriteErrorResponse(c *gin.Context, err error) {
 	var bErr app_err.BusinessError
+	ctxErr := c.Request.Context().Err()
 
-	if errors.As(err, &bErr) {
-		errorResponse := ErrorResponse{
+	switch {
+	case errors.As(err, &bErr):
+		c.JSON(businessErrorStatuses[bErr.Kind()], ErrorResponse{
 			ErrorMessage: ErrorMessage{
+				Code:    bErr.Code(),
 				Message: bErr.Error(),
+				Fields:  bErr.Fields(),
 			},
-		}
-
-		c.JSON(http.StatusBadRequest, errorResponse)
-
-	} else {
-		errorResponse := ErrorResponse{
-			ErrorMessage: ErrorMessage{
-				Message: "Internal server error",
-			},
-		}
-
+		})
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctxErr, context.DeadlineExceeded):
 		log.Println(err)
+		c.JSON(http.StatusGatewayTimeout, newErrorResponse(CodeTimeout, "Request timed out"))
+	case errors.Is(err, context.Canceled) || errors.Is(ctxErr, context.Canceled):
+		c.JSON(StatusClientClosedRequest, newErrorResponse(CodeClientClosedRequest, "Client closed request"))
+	default:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, newErrorResponse(CodeInternal, "Internal server error"))
+	}
+}
 
-		c.JSON(http.StatusInternalServerError, errorResponse)
+func newErrorResponse(code, message string) ErrorResponse {
+	return ErrorResponse{
+		ErrorMessage: ErrorMessage{
+			Code:    code,
+			Message: message,
+		},
 	}
 }
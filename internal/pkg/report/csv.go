@@ -0,0 +1,49 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func newCSVEncoder(w io.Writer, columns []Column, opts Options) (Encoder, error) {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = defaultCSVDelimiter
+	if opts.Delimiter != 0 {
+		csvWriter.Comma = opts.Delimiter
+	}
+
+	if opts.Header {
+		header := make([]string, 0, len(columns))
+		for _, column := range columns {
+			header = append(header, column.Name)
+		}
+		if err := csvWriter.Write(header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &csvEncoder{
+		w:       csvWriter,
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}, nil
+}
+
+func (e *csvEncoder) Encode(values []any) error {
+	for i, column := range e.columns {
+		e.record[i] = formatText(column, values[i])
+	}
+
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// encodeJSONObject appends a JSON object with a field per column, in the column order. Nil values are omitted.
+func encodeJSONObject(buf []byte, columns []Column, values []any) ([]byte, error) {
+	buf = append(buf, '{')
+	first := true
+	for i, column := range columns {
+		if values[i] == nil {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+
+		name, err := json.Marshal(column.Name)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, name...)
+		buf = append(buf, ':')
+
+		value := values[i]
+		if column.Type == ColumnDate {
+			value = value.(time.Time).Format(dateLayout)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, data...)
+	}
+
+	return append(buf, '}'), nil
+}
+
+// jsonEncoder writes a JSON array without holding the rows in memory.
+type jsonEncoder struct {
+	w       *bufio.Writer
+	columns []Column
+	buf     []byte
+	encoded bool
+}
+
+func newJSONEncoder(w io.Writer, columns []Column, _ Options) (Encoder, error) {
+	return &jsonEncoder{w: bufio.NewWriter(w), columns: columns}, nil
+}
+
+func (e *jsonEncoder) Encode(values []any) error {
+	separator := byte(',')
+	if !e.encoded {
+		separator = '['
+		e.encoded = true
+	}
+
+	var err error
+	e.buf, err = encodeJSONObject(append(e.buf[:0], separator), e.columns, values)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(e.buf)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "]"
+	if !e.encoded {
+		end = "[]"
+	}
+	if _, err := e.w.WriteString(end); err != nil {
+		return err
+	}
+
+	return e.w.Flush()
+}
+
+type ndjsonEncoder struct {
+	w       *bufio.Writer
+	columns []Column
+	buf     []byte
+}
+
+func newNDJSONEncoder(w io.Writer, columns []Column, _ Options) (Encoder, error) {
+	return &ndjsonEncoder{w: bufio.NewWriter(w), columns: columns}, nil
+}
+
+func (e *ndjsonEncoder) Encode(values []any) error {
+	var err error
+	e.buf, err = encodeJSONObject(e.buf[:0], e.columns, values)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(append(e.buf, '\n'))
+	return err
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return e.w.Flush()
+}
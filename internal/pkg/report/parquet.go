@@ -0,0 +1,300 @@
+package report
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Values from the parquet format specification.
+const (
+	parquetTypeInt32     = 1
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetConvertedTypeNone            = -1
+	parquetConvertedTypeUTF8            = 0
+	parquetConvertedTypeDate            = 6
+	parquetConvertedTypeTimestampMillis = 9
+
+	parquetRepetitionOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecUncompressed = 0
+	parquetPageTypeData      = 0
+)
+
+const (
+	parquetMagic        = "PAR1"
+	parquetRowGroupRows = 64 * 1024
+	parquetCreatedBy    = "segmentation-service"
+)
+
+// parquetColumn buffers values of one column for the current row group. All columns are optional.
+type parquetColumn struct {
+	Column
+	physicalType  int32
+	convertedType int32
+
+	definitionLevels []byte
+	values           []byte
+}
+
+func newParquetColumn(column Column) (*parquetColumn, error) {
+	c := &parquetColumn{Column: column}
+	switch column.Type {
+	case ColumnInt:
+		c.physicalType, c.convertedType = parquetTypeInt64, parquetConvertedTypeNone
+	case ColumnString:
+		c.physicalType, c.convertedType = parquetTypeByteArray, parquetConvertedTypeUTF8
+	case ColumnTime:
+		c.physicalType, c.convertedType = parquetTypeInt64, parquetConvertedTypeTimestampMillis
+	case ColumnDate:
+		c.physicalType, c.convertedType = parquetTypeInt32, parquetConvertedTypeDate
+	default:
+		return nil, fmt.Errorf("unknown type of column %q", column.Name)
+	}
+
+	return c, nil
+}
+
+func (c *parquetColumn) append(value any) {
+	if value == nil {
+		c.definitionLevels = append(c.definitionLevels, 0)
+		return
+	}
+	c.definitionLevels = append(c.definitionLevels, 1)
+
+	switch c.Type {
+	case ColumnInt:
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(value.(int64)))
+	case ColumnString:
+		v := value.(string)
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(v)))
+		c.values = append(c.values, v...)
+	case ColumnTime:
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(value.(time.Time).UnixMilli()))
+	case ColumnDate:
+		// days since the unix epoch
+		y, m, d := value.(time.Time).Date()
+		days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(int32(days)))
+	}
+}
+
+// page returns the data page body: definition levels followed by PLAIN encoded values.
+func (c *parquetColumn) page() []byte {
+	levels := encodeRLELevels(c.definitionLevels)
+	page := make([]byte, 0, 4+len(levels)+len(c.values))
+	page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+	page = append(page, levels...)
+
+	return append(page, c.values...)
+}
+
+func (c *parquetColumn) reset() {
+	c.definitionLevels = c.definitionLevels[:0]
+	c.values = c.values[:0]
+}
+
+type parquetColumnChunk struct {
+	column         *parquetColumn
+	numValues      int64
+	size           int64
+	dataPageOffset int64
+}
+
+type parquetRowGroup struct {
+	chunks  []parquetColumnChunk
+	size    int64
+	numRows int64
+}
+
+// parquetEncoder writes an uncompressed parquet file with one data page per column in each row group.
+// Row groups are written as soon as they are full, so memory use does not grow with the report size.
+type parquetEncoder struct {
+	w      io.Writer
+	offset int64
+
+	columns []*parquetColumn
+
+	rows      int64
+	totalRows int64
+	rowGroups []parquetRowGroup
+}
+
+func newParquetEncoder(w io.Writer, columns []Column, _ Options) (Encoder, error) {
+	e := &parquetEncoder{w: w}
+	for _, column := range columns {
+		c, err := newParquetColumn(column)
+		if err != nil {
+			return nil, err
+		}
+		e.columns = append(e.columns, c)
+	}
+
+	if err := e.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *parquetEncoder) Encode(values []any) error {
+	for i, column := range e.columns {
+		column.append(values[i])
+	}
+
+	e.rows++
+	if e.rows == parquetRowGroupRows {
+		return e.flushRowGroup()
+	}
+
+	return nil
+}
+
+func (e *parquetEncoder) Close() error {
+	if e.rows > 0 {
+		if err := e.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := e.fileMetaData()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, parquetMagic...)
+
+	return e.write(footer)
+}
+
+func (e *parquetEncoder) flushRowGroup() error {
+	rowGroup := parquetRowGroup{
+		numRows: e.rows,
+	}
+
+	for _, column := range e.columns {
+		page := column.page()
+		header := pageHeader(len(page), e.rows)
+
+		chunk := parquetColumnChunk{
+			column:         column,
+			numValues:      e.rows,
+			size:           int64(len(header) + len(page)),
+			dataPageOffset: e.offset,
+		}
+		if err := e.write(header); err != nil {
+			return err
+		}
+		if err := e.write(page); err != nil {
+			return err
+		}
+
+		rowGroup.chunks = append(rowGroup.chunks, chunk)
+		rowGroup.size += chunk.size
+		column.reset()
+	}
+
+	e.rowGroups = append(e.rowGroups, rowGroup)
+	e.totalRows += e.rows
+	e.rows = 0
+
+	return nil
+}
+
+func (e *parquetEncoder) write(p []byte) error {
+	n, err := e.w.Write(p)
+	e.offset += int64(n)
+	return err
+}
+
+func pageHeader(pageSize int, numValues int64) []byte {
+	t := &thriftWriter{}
+	t.beginStruct()
+	t.i32Field(1, parquetPageTypeData)
+	t.i32Field(2, int32(pageSize))
+	t.i32Field(3, int32(pageSize))
+	t.structField(5)
+	t.i32Field(1, int32(numValues))
+	t.i32Field(2, parquetEncodingPlain)
+	t.i32Field(3, parquetEncodingRLE)
+	t.i32Field(4, parquetEncodingRLE)
+	t.endStruct()
+	t.endStruct()
+
+	return t.buf
+}
+
+func (e *parquetEncoder) fileMetaData() []byte {
+	t := &thriftWriter{}
+	t.beginStruct()
+	t.i32Field(1, 1)
+
+	t.listField(2, thriftStruct, len(e.columns)+1)
+	t.beginStruct()
+	t.stringField(4, "schema")
+	t.i32Field(5, int32(len(e.columns)))
+	t.endStruct()
+	for _, column := range e.columns {
+		t.beginStruct()
+		t.i32Field(1, column.physicalType)
+		t.i32Field(3, parquetRepetitionOptional)
+		t.stringField(4, column.Name)
+		if column.convertedType != parquetConvertedTypeNone {
+			t.i32Field(6, column.convertedType)
+		}
+		t.endStruct()
+	}
+
+	t.i64Field(3, e.totalRows)
+
+	t.listField(4, thriftStruct, len(e.rowGroups))
+	for _, rowGroup := range e.rowGroups {
+		t.beginStruct()
+		t.listField(1, thriftStruct, len(rowGroup.chunks))
+		for _, chunk := range rowGroup.chunks {
+			t.beginStruct()
+			t.i64Field(2, chunk.dataPageOffset)
+			t.structField(3)
+			t.i32Field(1, chunk.column.physicalType)
+			t.listField(2, thriftI32, 2)
+			t.writeI32(parquetEncodingPlain)
+			t.writeI32(parquetEncodingRLE)
+			t.listField(3, thriftBinary, 1)
+			t.writeString(chunk.column.Name)
+			t.i32Field(4, parquetCodecUncompressed)
+			t.i64Field(5, chunk.numValues)
+			t.i64Field(6, chunk.size)
+			t.i64Field(7, chunk.size)
+			t.i64Field(9, chunk.dataPageOffset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64Field(2, rowGroup.size)
+		t.i64Field(3, rowGroup.numRows)
+		t.endStruct()
+	}
+
+	t.stringField(6, parquetCreatedBy)
+	t.endStruct()
+
+	return t.buf
+}
+
+// encodeRLELevels encodes levels of bit width 1 with the RLE/bit-packing hybrid, using RLE runs only.
+func encodeRLELevels(levels []byte) []byte {
+	var buf []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf = binary.AppendUvarint(buf, uint64(j-i)<<1)
+		buf = append(buf, levels[i])
+		i = j
+	}
+
+	return buf
+}
@@ -0,0 +1,749 @@
+package report
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var testColumns = []Column{
+	{Name: "userId", Type: ColumnInt},
+	{Name: "segment", Type: ColumnString},
+	{Name: "operationTime", Type: ColumnTime},
+	{Name: "day", Type: ColumnDate},
+}
+
+var testRows = [][]any{
+	{int64(1000), "AVITO_VOICE_MESSAGES", time.Date(2023, 8, 31, 12, 30, 15, 250e6, time.UTC), time.Date(2023, 8, 31, 0, 0, 0, 0, time.UTC)},
+	{nil, "", nil, nil},
+	{int64(-7), nil, time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC), time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)},
+	{int64(1 << 40), "сегмент", time.Date(2024, 2, 29, 0, 0, 0, 1e6, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+}
+
+func encodeParquet(t *testing.T, columns []Column, rows [][]any) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	e, err := newParquetEncoder(&buf, columns, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := e.Encode(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParquetEncoder(t *testing.T) {
+	manyRows := make([][]any, parquetRowGroupRows+10)
+	for i := range manyRows {
+		manyRows[i] = []any{int64(i)}
+		if i%3 == 0 {
+			manyRows[i] = []any{nil}
+		}
+	}
+	tests := []struct {
+		name          string
+		columns       []Column
+		rows          [][]any
+		wantRowGroups int
+	}{
+		{
+			name:          "every column type with nulls",
+			columns:       testColumns,
+			rows:          testRows,
+			wantRowGroups: 1,
+		},
+		{
+			name:          "no rows",
+			columns:       testColumns,
+			rows:          nil,
+			wantRowGroups: 0,
+		},
+		{
+			name:          "rows over several row groups",
+			columns:       []Column{{Name: "userId", Type: ColumnInt}},
+			rows:          manyRows,
+			wantRowGroups: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := readParquetFile(encodeParquet(t, tt.columns, tt.rows))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			columns, err := file.reportColumns()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("schema = %v, want %v", columns, tt.columns)
+			}
+			if file.rowGroups != tt.wantRowGroups {
+				t.Errorf("row groups = %d, want %d", file.rowGroups, tt.wantRowGroups)
+			}
+			if len(file.rows) != len(tt.rows) {
+				t.Fatalf("rows = %d, want %d", len(file.rows), len(tt.rows))
+			}
+			for i := range tt.rows {
+				if !reflect.DeepEqual(file.rows[i], tt.rows[i]) {
+					t.Errorf("row %d = %v, want %v", i, file.rows[i], tt.rows[i])
+				}
+			}
+		})
+	}
+}
+
+// TestParquetEncoderGolden keeps the byte layout of the file from changing unnoticed.
+// After an intended change run go test -run Golden -update and check the new file with a parquet reader, e.g. pyarrow.
+func TestParquetEncoderGolden(t *testing.T) {
+	const golden = "testdata/report.parquet"
+
+	got := encodeParquet(t, testColumns, testRows)
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("parquet file differs from %s:\ngot  %x\nwant %x", golden, got, want)
+	}
+}
+
+// TestReadParquetFile checks the decoder the tests above rely on against a file of another implementation:
+// testdata/flat.parquet.snappy is the example written by github.com/xitongsys/parquet-go, found in the examples of
+// github.com/xitongsys/parquet-go-source.
+func TestReadParquetFile(t *testing.T) {
+	data, err := os.ReadFile("testdata/flat.parquet.snappy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := readParquetFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSchema := []parquetSchemaColumn{
+		{name: "name", physicalType: parquetTypeByteArray, convertedType: parquetConvertedTypeUTF8},
+		{name: "age", physicalType: parquetTypeInt32, convertedType: parquetConvertedTypeNone},
+		{name: "id", physicalType: parquetTypeInt64, convertedType: parquetConvertedTypeNone},
+		{name: "weight", physicalType: parquetTypeFloat, convertedType: parquetConvertedTypeNone},
+		{name: "sex", physicalType: parquetTypeBoolean, convertedType: parquetConvertedTypeNone},
+		{name: "day", physicalType: parquetTypeInt32, convertedType: parquetConvertedTypeDate},
+	}
+	if !reflect.DeepEqual(file.schema, wantSchema) {
+		t.Errorf("schema = %v, want %v", file.schema, wantSchema)
+	}
+	if file.rowGroups != 1 {
+		t.Errorf("row groups = %d, want 1", file.rowGroups)
+	}
+	if len(file.rows) != 10 {
+		t.Fatalf("rows = %d, want 10", len(file.rows))
+	}
+	for i, row := range file.rows {
+		want := []any{
+			"StudentName",
+			int32(20 + i%5),
+			int64(i),
+			float32(50.0 + float32(i)*0.1),
+			i%2 == 0,
+			time.Date(2019, 5, 24, 0, 0, 0, 0, time.UTC),
+		}
+		if !reflect.DeepEqual(row, want) {
+			t.Errorf("row %d = %v, want %v", i, row, want)
+		}
+	}
+}
+
+// Values from the parquet format specification that the encoder does not write, but other writers do.
+const (
+	parquetTypeBoolean = 0
+	parquetTypeFloat   = 4
+
+	parquetRepetitionRequired = 0
+
+	parquetEncodingPlainDictionary = 2
+	parquetEncodingRLEDictionary   = 8
+
+	parquetCodecSnappy        = 1
+	parquetPageTypeDictionary = 2
+)
+
+// parquetFile is the content of a file decoded by readParquetFile.
+type parquetFile struct {
+	schema    []parquetSchemaColumn
+	rowGroups int
+	rows      [][]any
+}
+
+type parquetSchemaColumn struct {
+	name          string
+	physicalType  int32
+	convertedType int32
+	optional      bool
+}
+
+// reportColumns returns the report columns the schema was written for. The encoder makes every column optional.
+func (f parquetFile) reportColumns() ([]Column, error) {
+	columns := make([]Column, 0, len(f.schema))
+	for _, c := range f.schema {
+		if !c.optional {
+			return nil, fmt.Errorf("column %q is not optional", c.name)
+		}
+
+		column := Column{Name: c.name}
+		switch {
+		case c.physicalType == parquetTypeInt64 && c.convertedType == parquetConvertedTypeNone:
+			column.Type = ColumnInt
+		case c.physicalType == parquetTypeByteArray && c.convertedType == parquetConvertedTypeUTF8:
+			column.Type = ColumnString
+		case c.physicalType == parquetTypeInt64 && c.convertedType == parquetConvertedTypeTimestampMillis:
+			column.Type = ColumnTime
+		case c.physicalType == parquetTypeInt32 && c.convertedType == parquetConvertedTypeDate:
+			column.Type = ColumnDate
+		default:
+			return nil, fmt.Errorf("column %q has unexpected type %d/%d", c.name, c.physicalType, c.convertedType)
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// readParquetFile decodes a file following the parquet format specification rather than the encoder, so that the tests do
+// not share the encoder's mistakes. It reads flat schemas of required and optional columns stored in v1 data pages,
+// uncompressed or snappy compressed, with PLAIN or dictionary encoded values. Values are int32, int64, float32, bool,
+// string for UTF8 and []byte for other byte arrays, and time.Time for DATE and TIMESTAMP_MILLIS.
+func readParquetFile(data []byte) (parquetFile, error) {
+	if len(data) < 12 || string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		return parquetFile{}, errors.New("no parquet magic")
+	}
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLength
+	if footerStart < 4 {
+		return parquetFile{}, errors.New("footer length out of range")
+	}
+
+	r := &compactReader{buf: data[footerStart : len(data)-8]}
+	metaData, err := r.readStruct()
+	if err != nil {
+		return parquetFile{}, fmt.Errorf("file metadata: %w", err)
+	}
+	if r.pos != len(r.buf) {
+		return parquetFile{}, errors.New("file metadata is shorter than the footer")
+	}
+
+	file := parquetFile{}
+	schema := metaData[2].([]any)
+	root := schema[0].(map[int16]any)
+	if int(root[5].(int32)) != len(schema)-1 {
+		return parquetFile{}, errors.New("root does not list every column")
+	}
+	for _, element := range schema[1:] {
+		column, err := schemaColumn(element.(map[int16]any))
+		if err != nil {
+			return parquetFile{}, err
+		}
+		file.schema = append(file.schema, column)
+	}
+
+	var rowGroups []any
+	if metaData[4] != nil {
+		rowGroups = metaData[4].([]any)
+	}
+	file.rowGroups = len(rowGroups)
+	for _, rg := range rowGroups {
+		rowGroup := rg.(map[int16]any)
+		numRows := int(rowGroup[3].(int64))
+		chunks := rowGroup[1].([]any)
+		if len(chunks) != len(file.schema) {
+			return parquetFile{}, errors.New("row group does not have a chunk for every column")
+		}
+
+		rows := make([][]any, numRows)
+		for i := range rows {
+			rows[i] = make([]any, len(file.schema))
+		}
+		for i, chunk := range chunks {
+			column := file.schema[i]
+			values, err := readColumnChunk(data, chunk.(map[int16]any)[3].(map[int16]any), column)
+			if err != nil {
+				return parquetFile{}, fmt.Errorf("column %q: %w", column.name, err)
+			}
+			if len(values) != numRows {
+				return parquetFile{}, fmt.Errorf("column %q has %d values in a row group of %d rows", column.name, len(values), numRows)
+			}
+			for j, value := range values {
+				rows[j][i] = value
+			}
+		}
+		file.rows = append(file.rows, rows...)
+	}
+
+	if int(metaData[3].(int64)) != len(file.rows) {
+		return parquetFile{}, errors.New("num_rows does not match the row groups")
+	}
+
+	return file, nil
+}
+
+func schemaColumn(element map[int16]any) (parquetSchemaColumn, error) {
+	column := parquetSchemaColumn{
+		name:          string(element[4].([]byte)),
+		convertedType: parquetConvertedTypeNone,
+	}
+	if _, ok := element[5]; ok {
+		return parquetSchemaColumn{}, fmt.Errorf("column %q is not flat", column.name)
+	}
+
+	switch element[3] {
+	case int32(parquetRepetitionRequired):
+	case int32(parquetRepetitionOptional):
+		column.optional = true
+	default:
+		return parquetSchemaColumn{}, fmt.Errorf("column %q is repeated", column.name)
+	}
+
+	column.physicalType = element[1].(int32)
+	if convertedType, ok := element[6].(int32); ok {
+		column.convertedType = convertedType
+	}
+
+	return column, nil
+}
+
+func readColumnChunk(data []byte, metaData map[int16]any, column parquetSchemaColumn) ([]any, error) {
+	if metaData[1] != column.physicalType {
+		return nil, errors.New("chunk type differs from the schema")
+	}
+	if path := metaData[3].([]any); len(path) != 1 || string(path[0].([]byte)) != column.name {
+		return nil, errors.New("chunk path differs from the schema")
+	}
+	codec := metaData[4].(int32)
+	if codec != parquetCodecUncompressed && codec != parquetCodecSnappy {
+		return nil, fmt.Errorf("unexpected codec %d", codec)
+	}
+
+	// the chunk starts with the dictionary page if there is one
+	start := int(metaData[9].(int64))
+	if dictionaryStart, ok := metaData[11].(int64); ok && int(dictionaryStart) < start {
+		start = int(dictionaryStart)
+	}
+	size := int(metaData[7].(int64))
+	if start < 4 || start+size > len(data) {
+		return nil, errors.New("chunk is out of the file")
+	}
+	if codec == parquetCodecUncompressed && metaData[6] != metaData[7] {
+		return nil, errors.New("compressed and uncompressed sizes of an uncompressed chunk differ")
+	}
+
+	numValues := int(metaData[5].(int64))
+	result := make([]any, 0, numValues)
+	var dictionary []any
+	r := &compactReader{buf: data[start : start+size]}
+	for r.pos < len(r.buf) {
+		header, err := r.readStruct()
+		if err != nil {
+			return nil, fmt.Errorf("page header: %w", err)
+		}
+		compressedSize := int(header[3].(int32))
+		if r.pos+compressedSize > len(r.buf) {
+			return nil, errors.New("page is out of the chunk")
+		}
+		page := r.buf[r.pos : r.pos+compressedSize]
+		r.pos += compressedSize
+
+		if codec == parquetCodecSnappy {
+			if page, err = snappy.Decode(nil, page); err != nil {
+				return nil, fmt.Errorf("page: %w", err)
+			}
+		}
+		if len(page) != int(header[2].(int32)) {
+			return nil, errors.New("page size differs from its header")
+		}
+
+		switch header[1] {
+		case int32(parquetPageTypeDictionary):
+			if dictionary != nil || len(result) != 0 {
+				return nil, errors.New("dictionary page is not the first page")
+			}
+			dictionaryPage := header[7].(map[int16]any)
+			if dictionaryPage[2] != int32(parquetEncodingPlain) && dictionaryPage[2] != int32(parquetEncodingPlainDictionary) {
+				return nil, errors.New("unexpected dictionary encoding")
+			}
+			values, rest, err := decodePlain(page, column.physicalType, int(dictionaryPage[1].(int32)))
+			if err != nil {
+				return nil, fmt.Errorf("dictionary: %w", err)
+			}
+			if len(rest) != 0 {
+				return nil, errors.New("dictionary page has more values than its header")
+			}
+			dictionary = values
+		case int32(parquetPageTypeData):
+			values, err := readDataPage(page, header[5].(map[int16]any), column, dictionary)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, values...)
+		default:
+			return nil, fmt.Errorf("unexpected page type %d", header[1])
+		}
+	}
+	if len(result) != numValues {
+		return nil, errors.New("num_values of the chunk and its pages differ")
+	}
+
+	for i, value := range result {
+		if value != nil {
+			result[i] = logicalValue(column, value)
+		}
+	}
+
+	return result, nil
+}
+
+// readDataPage decodes a v1 data page. Values of optional columns are preceded by RLE encoded definition levels,
+// dictionary encoded values are RLE encoded indexes into the dictionary.
+func readDataPage(page []byte, header map[int16]any, column parquetSchemaColumn, dictionary []any) ([]any, error) {
+	numValues := int(header[1].(int32))
+	definedValues := numValues
+	levels := make([]int, numValues)
+	for i := range levels {
+		levels[i] = 1
+	}
+	if column.optional {
+		if header[3] != int32(parquetEncodingRLE) || len(page) < 4 {
+			return nil, errors.New("unexpected definition levels")
+		}
+		levelsLength := int(binary.LittleEndian.Uint32(page))
+		if len(page) < 4+levelsLength {
+			return nil, errors.New("definition levels are truncated")
+		}
+		var err error
+		if levels, err = decodeRLEHybrid(page[4:4+levelsLength], 1, numValues); err != nil {
+			return nil, fmt.Errorf("definition levels: %w", err)
+		}
+		page = page[4+levelsLength:]
+
+		definedValues = 0
+		for _, level := range levels {
+			definedValues += level
+		}
+	}
+
+	var values []any
+	switch header[2] {
+	case int32(parquetEncodingPlain):
+		var rest []byte
+		var err error
+		if values, rest, err = decodePlain(page, column.physicalType, definedValues); err != nil {
+			return nil, err
+		}
+		if len(rest) != 0 {
+			return nil, errors.New("page has more values than definition levels")
+		}
+	case int32(parquetEncodingPlainDictionary), int32(parquetEncodingRLEDictionary):
+		if dictionary == nil || len(page) < 1 {
+			return nil, errors.New("dictionary encoded page without a dictionary")
+		}
+		indexes, err := decodeRLEHybrid(page[1:], int(page[0]), definedValues)
+		if err != nil {
+			return nil, fmt.Errorf("dictionary indexes: %w", err)
+		}
+		for _, index := range indexes {
+			if index >= len(dictionary) {
+				return nil, errors.New("dictionary index out of range")
+			}
+			values = append(values, dictionary[index])
+		}
+	default:
+		return nil, fmt.Errorf("unexpected page encoding %d", header[2])
+	}
+
+	result := make([]any, 0, numValues)
+	for _, level := range levels {
+		if level == 0 {
+			result = append(result, nil)
+			continue
+		}
+		result = append(result, values[0])
+		values = values[1:]
+	}
+
+	return result, nil
+}
+
+// decodePlain decodes count PLAIN encoded values of the physical type and returns the rest of buf.
+func decodePlain(buf []byte, physicalType int32, count int) ([]any, []byte, error) {
+	values := make([]any, 0, count)
+	if physicalType == parquetTypeBoolean {
+		// booleans are bit-packed, the first value in the lowest bit
+		size := (count + 7) / 8
+		if len(buf) < size {
+			return nil, nil, errors.New("values are truncated")
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, buf[i/8]>>(i%8)&1 == 1)
+		}
+		return values, buf[size:], nil
+	}
+
+	for i := 0; i < count; i++ {
+		var size int
+		switch physicalType {
+		case parquetTypeInt32, parquetTypeFloat:
+			size = 4
+		case parquetTypeInt64:
+			size = 8
+		case parquetTypeByteArray:
+			if len(buf) < 4 {
+				return nil, nil, errors.New("values are truncated")
+			}
+			size = 4 + int(binary.LittleEndian.Uint32(buf))
+		default:
+			return nil, nil, fmt.Errorf("unexpected physical type %d", physicalType)
+		}
+		if len(buf) < size {
+			return nil, nil, errors.New("values are truncated")
+		}
+
+		switch physicalType {
+		case parquetTypeInt32:
+			values = append(values, int32(binary.LittleEndian.Uint32(buf)))
+		case parquetTypeFloat:
+			values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(buf)))
+		case parquetTypeInt64:
+			values = append(values, int64(binary.LittleEndian.Uint64(buf)))
+		case parquetTypeByteArray:
+			values = append(values, buf[4:size])
+		}
+		buf = buf[size:]
+	}
+
+	return values, buf, nil
+}
+
+// logicalValue converts a physical value to the type of its converted type.
+func logicalValue(column parquetSchemaColumn, value any) any {
+	switch column.convertedType {
+	case parquetConvertedTypeUTF8:
+		return string(value.([]byte))
+	case parquetConvertedTypeTimestampMillis:
+		return time.UnixMilli(value.(int64)).UTC()
+	case parquetConvertedTypeDate:
+		return time.Unix(int64(value.(int32))*24*60*60, 0).UTC()
+	}
+
+	return value
+}
+
+// decodeRLEHybrid decodes count values of bitWidth bits written with the RLE/bit-packing hybrid encoding.
+func decodeRLEHybrid(buf []byte, bitWidth, count int) ([]int, error) {
+	if bitWidth > 32 {
+		return nil, errors.New("bit width out of range")
+	}
+
+	values := make([]int, 0, count)
+	for len(values) < count {
+		header, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, errors.New("invalid run header")
+		}
+		buf = buf[n:]
+
+		if header&1 == 1 {
+			// bit-packed groups of 8 values, the first value in the lowest bits
+			groups := int(header >> 1)
+			size := groups * bitWidth
+			if len(buf) < size {
+				return nil, errors.New("bit-packed run is truncated")
+			}
+			for i := 0; i < groups*8; i++ {
+				value := 0
+				for bit := 0; bit < bitWidth; bit++ {
+					position := i*bitWidth + bit
+					value |= int(buf[position/8]>>(position%8)&1) << bit
+				}
+				values = append(values, value)
+			}
+			buf = buf[size:]
+			continue
+		}
+
+		// the repeated value takes bitWidth rounded up to whole bytes
+		size := (bitWidth + 7) / 8
+		if len(buf) < size {
+			return nil, errors.New("run value is truncated")
+		}
+		value := 0
+		for i := 0; i < size; i++ {
+			value |= int(buf[i]) << (8 * i)
+		}
+		if value >= 1<<bitWidth {
+			return nil, errors.New("invalid run value")
+		}
+		for i := uint64(0); i < header>>1; i++ {
+			values = append(values, value)
+		}
+		buf = buf[size:]
+	}
+	if len(values) > count+7 || len(buf) != 0 {
+		return nil, errors.New("values do not match the expected count")
+	}
+
+	return values[:count], nil
+}
+
+// Thrift compact protocol types of booleans, which the encoder does not write.
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+)
+
+// compactReader decodes the thrift compact protocol into maps keyed by field id. Lists are []any, binaries are []byte.
+type compactReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *compactReader) readStruct() (map[int16]any, error) {
+	fields := make(map[int16]any)
+	var lastFieldId int16
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return fields, nil
+		}
+
+		fieldType := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			lastFieldId += delta
+		} else {
+			id, err := r.readZigzag()
+			if err != nil {
+				return nil, err
+			}
+			lastFieldId = int16(id)
+		}
+
+		if _, ok := fields[lastFieldId]; ok {
+			return nil, fmt.Errorf("field %d is repeated", lastFieldId)
+		}
+		if fieldType == thriftBoolTrue || fieldType == thriftBoolFalse {
+			// a boolean field keeps its value in the type of the field header
+			fields[lastFieldId] = fieldType == thriftBoolTrue
+			continue
+		}
+		fields[lastFieldId], err = r.readValue(fieldType)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", lastFieldId, err)
+		}
+	}
+}
+
+func (r *compactReader) readValue(valueType byte) (any, error) {
+	switch valueType {
+	case thriftBoolTrue, thriftBoolFalse:
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return b == thriftBoolTrue, nil
+	case thriftI32:
+		v, err := r.readZigzag()
+		if err != nil {
+			return nil, err
+		}
+		if int64(int32(v)) != v {
+			return nil, errors.New("i32 out of range")
+		}
+		return int32(v), nil
+	case thriftI64:
+		return r.readZigzag()
+	case thriftBinary:
+		length, err := r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(r.buf)-r.pos) < length {
+			return nil, errors.New("binary is truncated")
+		}
+		v := r.buf[r.pos : r.pos+int(length)]
+		r.pos += int(length)
+		return v, nil
+	case thriftList:
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+		list := make([]any, 0, size)
+		for i := uint64(0); i < size; i++ {
+			v, err := r.readValue(header & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case thriftStruct:
+		return r.readStruct()
+	}
+
+	return nil, fmt.Errorf("unexpected thrift type %d", valueType)
+}
+
+func (r *compactReader) readByte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errors.New("unexpected end of data")
+	}
+	r.pos++
+	return r.buf[r.pos-1], nil
+}
+
+func (r *compactReader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errors.New("invalid varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *compactReader) readZigzag() (int64, error) {
+	v, err := r.readVarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatJSON    = "json"
+	FormatNDJSON  = "ndjson"
+	FormatXLSX    = "xlsx"
+	FormatParquet = "parquet"
+)
+
+const defaultCSVDelimiter = ';'
+
+// ErrTooManyRows is returned by Encoder.Encode when the report has more rows than the format can hold.
+var ErrTooManyRows = errors.New("too many rows for the report format")
+
+type ColumnType int
+
+// Types of column values passed to Encoder.Encode. A nil value is written as an empty cell or null in every column type.
+const (
+	// ColumnInt values are int64.
+	ColumnInt ColumnType = iota
+	// ColumnString values are string.
+	ColumnString
+	// ColumnTime values are time.Time.
+	ColumnTime
+	// ColumnDate values are time.Time, only the date part is written.
+	ColumnDate
+)
+
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Encoder writes report rows one by one. Every row has a value for each column of the report.
+type Encoder interface {
+	Encode(values []any) error
+	// Close writes the buffered part of the report. The underlying writer is not closed.
+	Close() error
+}
+
+type Options struct {
+	Format string
+	// Delimiter separates CSV fields, ';' if not set.
+	Delimiter rune
+	// Header adds a row with column names to CSV.
+	Header bool
+}
+
+type format struct {
+	contentType string
+	newEncoder  func(w io.Writer, columns []Column, opts Options) (Encoder, error)
+}
+
+var formats = map[string]format{
+	FormatCSV:     {contentType: "text/csv", newEncoder: newCSVEncoder},
+	FormatJSON:    {contentType: "application/json", newEncoder: newJSONEncoder},
+	FormatNDJSON:  {contentType: "application/x-ndjson", newEncoder: newNDJSONEncoder},
+	FormatXLSX:    {contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newEncoder: newXLSXEncoder},
+	FormatParquet: {contentType: "application/vnd.apache.parquet", newEncoder: newParquetEncoder},
+}
+
+const (
+	timeLayout = "2006-01-02 15:04:05"
+	dateLayout = "2006-01-02"
+)
+
+func NewEncoder(w io.Writer, columns []Column, opts Options) (Encoder, error) {
+	f, ok := formats[opts.Format]
+	if !ok {
+		return nil, fmt.Errorf("unknown report format %q", opts.Format)
+	}
+
+	return f.newEncoder(w, columns, opts)
+}
+
+// ParseDelimiter parses a CSV delimiter given as a single character or "tab". An empty string gives the default delimiter.
+func ParseDelimiter(delimiter string) (rune, error) {
+	switch {
+	case delimiter == "":
+		return 0, nil
+	case delimiter == "tab":
+		return '\t', nil
+	case utf8.RuneCountInString(delimiter) == 1:
+		r, _ := utf8.DecodeRuneInString(delimiter)
+		if r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return 0, fmt.Errorf("invalid delimiter %q", delimiter)
+		}
+		return r, nil
+	}
+
+	return 0, fmt.Errorf("invalid delimiter %q", delimiter)
+}
+
+func IsFormat(name string) bool {
+	_, ok := formats[name]
+	return ok
+}
+
+func ContentType(format string) string {
+	return formats[format].contentType
+}
+
+// Extension returns the file name extension of the format, with the leading dot.
+func Extension(format string) string {
+	return "." + format
+}
+
+// formatText formats a value for text based formats.
+func formatText(column Column, value any) string {
+	if value == nil {
+		return ""
+	}
+
+	switch column.Type {
+	case ColumnInt:
+		return strconv.FormatInt(value.(int64), 10)
+	case ColumnTime:
+		return value.(time.Time).Format(timeLayout)
+	case ColumnDate:
+		return value.(time.Time).Format(dateLayout)
+	}
+
+	return value.(string)
+}
@@ -0,0 +1,84 @@
+package report
+
+import "encoding/binary"
+
+// Thrift compact protocol types, only the ones used by parquet metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the thrift compact protocol, which parquet uses for page headers and the footer.
+type thriftWriter struct {
+	buf         []byte
+	lastFieldId int16
+	structStack []int16
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.structStack = append(t.structStack, t.lastFieldId)
+	t.lastFieldId = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.lastFieldId = t.structStack[len(t.structStack)-1]
+	t.structStack = t.structStack[:len(t.structStack)-1]
+}
+
+func (t *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	delta := id - t.lastFieldId
+	if delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|fieldType)
+	} else {
+		t.buf = append(t.buf, fieldType)
+		t.writeI32(int32(id))
+	}
+	t.lastFieldId = id
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.writeI32(v)
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.writeI64(v)
+}
+
+func (t *thriftWriter) stringField(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.writeString(v)
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginStruct()
+}
+
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elemType)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elemType)
+	t.buf = binary.AppendUvarint(t.buf, uint64(size))
+}
+
+func (t *thriftWriter) writeI32(v int32) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(uint32((v<<1)^(v>>31))))
+}
+
+func (t *thriftWriter) writeI64(v int64) {
+	t.buf = binary.AppendUvarint(t.buf, uint64((v<<1)^(v>>63)))
+}
+
+func (t *thriftWriter) writeString(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
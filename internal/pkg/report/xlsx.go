@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxSheet = "Sheet1"
+	// xlsxMaxRows is the number of rows in an Excel sheet, the header row included.
+	xlsxMaxRows = excelize.TotalRows
+)
+
+// xlsxEncoder writes rows with the excelize stream writer. The workbook itself is written to w on Close.
+type xlsxEncoder struct {
+	w       io.Writer
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	columns []Column
+	row     int
+}
+
+func newXLSXEncoder(w io.Writer, columns []Column, _ Options) (Encoder, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	e := &xlsxEncoder{w: w, file: file, stream: stream, columns: columns}
+
+	header := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+	if err := e.writeRow(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *xlsxEncoder) Encode(values []any) error {
+	cells := make([]interface{}, 0, len(e.columns))
+	for i, column := range e.columns {
+		switch {
+		case values[i] == nil:
+			cells = append(cells, nil)
+		case column.Type == ColumnInt:
+			cells = append(cells, values[i])
+		default:
+			cells = append(cells, formatText(column, values[i]))
+		}
+	}
+
+	return e.writeRow(cells)
+}
+
+func (e *xlsxEncoder) writeRow(values []interface{}) error {
+	if e.row == xlsxMaxRows {
+		return fmt.Errorf("%w: an xlsx sheet holds at most %d rows including the header", ErrTooManyRows, xlsxMaxRows)
+	}
+
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxEncoder) Close() error {
+	defer e.file.Close()
+
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+
+	return e.file.Write(e.w)
+}
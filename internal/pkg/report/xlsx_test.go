@@ -0,0 +1,45 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXLSXEncoderRowLimit(t *testing.T) {
+	var buf bytes.Buffer
+	encoder, err := newXLSXEncoder(&buf, testColumns, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := encoder.(*xlsxEncoder)
+	// the stream writer accepts skipped rows, so the sheet does not have to be filled to get to the limit
+	e.row = xlsxMaxRows - 2
+
+	for i := 0; i < 2; i++ {
+		if err := e.Encode(testRows[0]); err != nil {
+			t.Fatalf("Encode() of row %d error = %v", e.row, err)
+		}
+	}
+	if err := e.Encode(testRows[0]); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("Encode() over the limit error = %v, want %v", err, ErrTooManyRows)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lastRow, err := file.GetCellValue(xlsxSheet, "B1048576")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastRow != testRows[0][1] {
+		t.Errorf("last row segment = %q, want %q", lastRow, testRows[0][1])
+	}
+}
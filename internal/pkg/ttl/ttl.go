@@ -0,0 +1,59 @@
+package ttl
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalid = errors.New("invalid ttl")
+
+// isoDuration matches ISO 8601 durations made of weeks, days, hours, minutes and seconds.
+// Years and months are not accepted since their length depends on the date they are counted from.
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+var isoUnits = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+
+// Parse parses a Go duration such as "72h" or an ISO 8601 duration such as "P7D" or "PT1H30M".
+// The duration must not be negative.
+func Parse(s string) (time.Duration, error) {
+	if strings.HasPrefix(s, "P") {
+		return parseISO(s)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, ErrInvalid
+	}
+
+	return d, nil
+}
+
+func parseISO(s string) (time.Duration, error) {
+	match := isoDuration.FindStringSubmatch(s)
+	if match == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, ErrInvalid
+	}
+
+	var d time.Duration
+	for i, unit := range isoUnits {
+		value := match[i+1]
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, ErrInvalid
+		}
+		part := n * float64(unit)
+		if part > float64(1<<63-1)-float64(d) {
+			return 0, ErrInvalid
+		}
+		d += time.Duration(part)
+	}
+
+	return d, nil
+}
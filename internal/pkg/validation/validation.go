@@ -0,0 +1,132 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+)
+
+// ErrorCode is the code of the error returned for a request that has violations, the violations have codes of their own.
+const ErrorCode = "validation_failed"
+
+// Codes of single violations.
+const (
+	CodeRequired   = "required"
+	CodeInvalid    = "invalid"
+	CodeTooLong    = "too_long"
+	CodeTooMany    = "too_many"
+	CodeOutOfRange = "out_of_range"
+	CodeDuplicate  = "duplicate"
+	CodeConflict   = "conflict"
+)
+
+// MaxSlugLength is the length of the slug column.
+const MaxSlugLength = 255
+
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Validator collects the violations of a request, so that all of them are reported at once.
+type Validator struct {
+	violations []app_err.FieldError
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.violations = append(v.violations, app_err.FieldError{
+		Field:   field,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Check adds the violation unless ok and returns ok.
+func (v *Validator) Check(ok bool, field, code, message string) bool {
+	if !ok {
+		v.Add(field, code, message)
+	}
+
+	return ok
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.violations) == 0
+}
+
+// Err returns a validation error listing all violations, nil if there are none.
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+
+	messages := make([]string, 0, len(v.violations))
+	for _, violation := range v.violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", violation.Field, violation.Message))
+	}
+
+	return app_err.NewValidationError(ErrorCode, strings.Join(messages, "; "), v.violations...)
+}
+
+// NewFieldError creates a validation error with an invalid violation of each of the given request fields.
+func NewFieldError(message string, fields ...string) error {
+	fieldErrors := make([]app_err.FieldError, 0, len(fields))
+	for _, field := range fields {
+		fieldErrors = append(fieldErrors, app_err.FieldError{Field: field, Code: CodeInvalid, Message: message})
+	}
+
+	return app_err.NewValidationError(ErrorCode, message, fieldErrors...)
+}
+
+// SegmentSlug checks a slug that refers to a segment. Segments created before the slug format was checked may have any slug,
+// so only the length is checked.
+func (v *Validator) SegmentSlug(field, slug string) bool {
+	if slug == "" {
+		v.Add(field, CodeRequired, "segment slug is required")
+		return false
+	}
+
+	return v.Check(utf8.RuneCountInString(slug) <= MaxSlugLength, field, CodeTooLong,
+		fmt.Sprintf("segment slug is longer than %d characters", MaxSlugLength))
+}
+
+// NewSegmentSlug checks a slug a segment is created or renamed to.
+func (v *Validator) NewSegmentSlug(field, slug string) bool {
+	if !v.SegmentSlug(field, slug) {
+		return false
+	}
+
+	return v.Check(slugPattern.MatchString(slug), field, CodeInvalid,
+		`segment slug may contain only latin letters, digits, "_", "-" and "."`)
+}
+
+// SegmentSlugs checks a list of at most max slugs that refer to segments, each slug may be listed once.
+func (v *Validator) SegmentSlugs(field string, slugs []string, max int) {
+	if !v.Check(len(slugs) <= max, field, CodeTooMany, fmt.Sprintf("at most %d segments are allowed", max)) {
+		return
+	}
+
+	for i, slug := range slugs {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		if !v.SegmentSlug(itemField, slug) {
+			continue
+		}
+		v.Check(!slices.Contains(slugs[:i], slug), itemField, CodeDuplicate, fmt.Sprintf("segment %q is listed more than once", slug))
+	}
+}
+
+// Tags checks a list of at most max tags, tags may not be empty or repeat.
+func (v *Validator) Tags(field string, tags []string, max int) {
+	if !v.Check(len(tags) <= max, field, CodeTooMany, fmt.Sprintf("at most %d tags are allowed", max)) {
+		return
+	}
+
+	for i, tag := range tags {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		if !v.Check(tag != "", itemField, CodeRequired, "tag is required") {
+			continue
+		}
+		v.Check(!slices.Contains(tags[:i], tag), itemField, CodeDuplicate, fmt.Sprintf("tag %q is listed more than once", tag))
+	}
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LockSession is a dedicated connection, opened outside the pool, that holds all Postgres session advisory locks of the replica.
+// Held locks never take connections from the pool that requests and the workers' own queries need, and the locks are released
+// by the database as soon as the connection of a stopped replica is gone. LockSession is safe for concurrent use.
+type LockSession struct {
+	connConfig *pgx.ConnConfig
+
+	mu   sync.Mutex
+	conn *pgx.Conn
+	held map[int64]bool
+}
+
+// NewLockSession creates a session connecting with the settings of the pool. The connection is opened when the first lock is taken.
+func NewLockSession(pool *pgxpool.Pool) *LockSession {
+	return &LockSession{
+		connConfig: pool.Config().ConnConfig.Copy(),
+		held:       make(map[int64]bool),
+	}
+}
+
+func (s *LockSession) tryLock(ctx context.Context, key int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil && s.held[key] {
+		if err := s.conn.Ping(ctx); err == nil {
+			return true, nil
+		}
+		// the locks went away with the session
+		s.closeConn(ctx)
+	}
+
+	if s.conn == nil {
+		conn, err := pgx.ConnectConfig(ctx, s.connConfig)
+		if err != nil {
+			return false, err
+		}
+		s.conn = conn
+	}
+
+	var locked bool
+	err := s.conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked)
+	if err != nil {
+		if s.conn.IsClosed() {
+			s.closeConn(ctx)
+		}
+		return false, err
+	}
+	if locked {
+		s.held[key] = true
+	}
+
+	return locked, nil
+}
+
+func (s *LockSession) unlock(ctx context.Context, key int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil || !s.held[key] {
+		return nil
+	}
+
+	_, err := s.conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, key)
+	if err != nil {
+		// a session that may still hold the lock is not kept
+		s.closeConn(ctx)
+		return err
+	}
+	delete(s.held, key)
+
+	return nil
+}
+
+// Close closes the connection, releasing every lock held by the session.
+func (s *LockSession) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close(ctx)
+	s.conn = nil
+	s.held = make(map[int64]bool)
+
+	return err
+}
+
+func (s *LockSession) closeConn(ctx context.Context) {
+	s.conn.Close(ctx)
+	s.conn = nil
+	s.held = make(map[int64]bool)
+}
+
+// AdvisoryLock is a Postgres session advisory lock held on the connection of a LockSession.
+type AdvisoryLock struct {
+	session *LockSession
+	key     int64
+}
+
+func NewAdvisoryLock(session *LockSession, key int64) *AdvisoryLock {
+	return &AdvisoryLock{
+		session: session,
+		key:     key,
+	}
+}
+
+// TryLock takes the lock if no other session holds it and reports whether the lock is held.
+// A lock taken before is checked to still be held.
+func (l *AdvisoryLock) TryLock(ctx context.Context) (bool, error) {
+	return l.session.tryLock(ctx, l.key)
+}
+
+// Unlock releases the lock if it is held.
+func (l *AdvisoryLock) Unlock(ctx context.Context) error {
+	return l.session.unlock(ctx, l.key)
+}
@@ -2,10 +2,10 @@ package repository
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,49 +19,113 @@ func NewHistoryRepo(pool *pgxpool.Pool) *HistoryRepo {
 	}
 }
 
-func (r *HistoryRepo) DeleteExpiredUserSegments(ctx context.Context) ([]model.UsersSegments, error) {
-	rows, err := r.pool.Query(ctx,
-		` WITH deleted_segments AS (
-				DELETE FROM users_segments
+// DeleteExpiredUserSegments deletes up to limit expired memberships, skipping the ones locked by another transaction.
+// It returns the deleted memberships of not deleted segments grouped by user and the number of all deleted memberships.
+// Scheduled memberships that were never activated are deleted but not returned.
+func (r *HistoryRepo) DeleteExpiredUserSegments(ctx context.Context, limit int) ([]model.UsersSegments, int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH expired_segments AS (
+				SELECT id
+				FROM users_segments
 				WHERE expiration_time IS NOT NULL
 				AND expiration_time <= CURRENT_TIMESTAMP
-				RETURNING user_id, segment_id
+				ORDER BY expiration_time
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			), deleted_segments AS (
+				DELETE FROM users_segments
+				WHERE id IN (SELECT id FROM expired_segments)
+				RETURNING user_id, segment_id, start_time
 			)
 			SELECT d.user_id,
-				   array_agg(s.slug) AS segment_slugs
+				   array_agg(s.slug) FILTER (WHERE s.deleted_at IS NULL AND d.start_time IS NULL) AS segment_slugs,
+				   COUNT(*)
 			FROM deleted_segments d
 			JOIN segments s ON d.segment_id = s.id
-			GROUP BY d.user_id`)
+			GROUP BY d.user_id`, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return collectUsersSegments(rows)
+}
 
+// ActivateScheduledUserSegments activates up to limit scheduled memberships of not deleted segments whose start time has come,
+// skipping the ones locked by another transaction. It returns the activated memberships grouped by user and their number.
+func (r *HistoryRepo) ActivateScheduledUserSegments(ctx context.Context, limit int) ([]model.UsersSegments, int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH started_segments AS (
+				SELECT us.id
+				FROM users_segments us
+				JOIN segments s ON us.segment_id = s.id
+				WHERE us.start_time IS NOT NULL
+				AND us.start_time <= CURRENT_TIMESTAMP
+				AND s.deleted_at IS NULL
+				ORDER BY us.start_time
+				LIMIT $1
+				FOR UPDATE OF us SKIP LOCKED
+			), activated_segments AS (
+				UPDATE users_segments
+				SET start_time = NULL
+				WHERE id IN (SELECT id FROM started_segments)
+				RETURNING user_id, segment_id
+			)
+			SELECT a.user_id,
+				   array_agg(s.slug) AS segment_slugs,
+				   COUNT(*)
+			FROM activated_segments a
+			JOIN segments s ON a.segment_id = s.id
+			GROUP BY a.user_id`, limit)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
+	return collectUsersSegments(rows)
+}
+
+// collectUsersSegments reads rows of user id, segment slugs and the number of processed memberships of the user.
+// Users without slugs are left out, the returned number counts all processed memberships.
+func collectUsersSegments(rows pgx.Rows) ([]model.UsersSegments, int, error) {
 	defer rows.Close()
 
 	usersSegments := []model.UsersSegments{}
+	total := 0
 	for rows.Next() {
-		userSegmentsTemp := model.UsersSegments{}
-		if err := rows.Scan(&userSegmentsTemp.UserId, &userSegmentsTemp.SegmentSlugs); err != nil {
-			return nil, fmt.Errorf("error zdes:%w", err)
+		userSegments := model.UsersSegments{}
+		var count int
+		if err := rows.Scan(&userSegments.UserId, &userSegments.SegmentSlugs, &count); err != nil {
+			return nil, 0, err
+		}
+		total += count
+		if len(userSegments.SegmentSlugs) != 0 {
+			usersSegments = append(usersSegments, userSegments)
 		}
-		usersSegments = append(usersSegments, userSegmentsTemp)
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return usersSegments, nil
+	return usersSegments, total, nil
 }
 
-func (r *HistoryRepo) RecordUserMultipleSegmentsToHistory(ctx context.Context, historyData model.HistoryDataMultipleSegments) (err error) {
-	query := `
-		INSERT INTO user_segment_history (user_id, segment_slug, operation)
-		VALUES ($1, $2, $3)`
+// segmentIdBySlug links a history entry to the segment, preferring the active one over archived segments with the same slug.
+const segmentIdBySlug = `(
+	SELECT id
+	FROM segments
+	WHERE slug = $2
+	ORDER BY deleted_at DESC NULLS FIRST
+	LIMIT 1
+)`
+
+// insertHistoryQuery records a history entry, empty actor and request ids are stored as NULL.
+const insertHistoryQuery = `
+	INSERT INTO user_segment_history (user_id, segment_slug, segment_id, operation, source, actor_id, request_id)
+	VALUES ($1, $2, ` + segmentIdBySlug + `, $3, $4, NULLIF($5, ''), NULLIF($6, ''))`
 
+func (r *HistoryRepo) RecordUserMultipleSegmentsToHistory(ctx context.Context, historyData model.HistoryDataMultipleSegments) (err error) {
 	for _, segmentSlug := range historyData.SegmentSlug {
-		_, err := r.pool.Exec(ctx, query, historyData.UserId, segmentSlug, historyData.Operation)
+		_, err := conn(ctx, r.pool).Exec(ctx, insertHistoryQuery,
+			historyData.UserId, segmentSlug, historyData.Operation, historyData.Source, historyData.ActorID, historyData.RequestID)
 		if err != nil {
 			return err
 		}
@@ -71,12 +135,9 @@ func (r *HistoryRepo) RecordUserMultipleSegmentsToHistory(ctx context.Context, h
 }
 
 func (r *HistoryRepo) RecordMultipleUsersToHistory(ctx context.Context, historyData model.HistoryDataMultipleUsers) error {
-	query := `
-		INSERT INTO user_segment_history (user_id, segment_slug, operation)
-		VALUES ($1, $2, $3)`
-
 	for _, userId := range historyData.UsersIDs {
-		_, err := r.pool.Exec(ctx, query, userId, historyData.SegmentSlug, historyData.Operation)
+		_, err := conn(ctx, r.pool).Exec(ctx, insertHistoryQuery,
+			userId, historyData.SegmentSlug, historyData.Operation, historyData.Source, historyData.ActorID, historyData.RequestID)
 		if err != nil {
 			return err
 		}
@@ -85,32 +146,289 @@ func (r *HistoryRepo) RecordMultipleUsersToHistory(ctx context.Context, historyD
 	return nil
 }
 
-func (r *HistoryRepo) GetHistory(ctx context.Context, month, year, userId int) ([]model.History, error) {
-	rows, err := r.pool.Query(ctx,
-		` SELECT user_id, segment_slug, operation, operation_time
-			FROM user_segment_history
-			WHERE DATE_TRUNC('month', operation_time) = $1::date
-			AND user_id = $2
-			ORDER BY operation_time`, time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), userId)
+func (r *HistoryRepo) RecordSegmentEvent(ctx context.Context, event model.SegmentEvent) error {
+	_, err := conn(ctx, r.pool).Exec(ctx, insertHistoryQuery,
+		nil, event.SegmentSlug, event.Operation, event.Source, event.ActorID, event.RequestID)
+
+	return err
+}
 
+// utcTime converts t to UTC before it is bound as a timestamp. operation_time is a TIMESTAMP holding UTC time, and the time
+// bound as a timestamp keeps its clock time but loses its offset, so 12:00+03:00 would be taken for 12:00 UTC.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	utc := t.UTC()
+	return &utc
+}
+
+// historyFilterCondition matches entries against the fields of model.HistoryFilter passed as $1-$5.
+const historyFilterCondition = `(COALESCE(CARDINALITY($1::int[]), 0) = 0 OR user_id = ANY($1))
+	AND (COALESCE(CARDINALITY($2::text[]), 0) = 0
+		OR segment_slug = ANY($2)
+		OR segment_id IN (SELECT id FROM segments WHERE slug = ANY($2))
+		OR segment_id IN (SELECT segment_id FROM segment_slug_aliases WHERE slug = ANY($2)))
+	AND ($3::text = '' OR operation::text = $3)
+	AND ($4::timestamp IS NULL OR operation_time >= $4)
+	AND ($5::timestamp IS NULL OR operation_time < $5)`
+
+// GetHistory returns history entries matching the filter ordered by operation time.
+// Segment slugs also match entries recorded under previous slugs of the same segment.
+func (r *HistoryRepo) GetHistory(ctx context.Context, filter model.HistoryFilter) ([]model.History, error) {
+	var history []model.History
+	err := r.StreamHistory(ctx, filter, func(historyRow model.History) error {
+		history = append(history, historyRow)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return history, nil
+}
+
+// StreamHistory is GetHistory that passes entries to fn one by one as they are read, without loading them all into memory.
+func (r *HistoryRepo) StreamHistory(ctx context.Context, filter model.HistoryFilter, fn func(historyRow model.History) error) error {
+	var afterTime *time.Time
+	var afterId int64
+	if filter.After != nil {
+		afterTime = utcTime(&filter.After.OperationTime)
+		afterId = filter.After.ID
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT id, user_id, segment_slug, operation, operation_time,
+				   COALESCE(source, ''), COALESCE(actor_id, ''), COALESCE(request_id, '')
+			FROM user_segment_history
+			WHERE `+historyFilterCondition+`
+			AND ($6::timestamp IS NULL OR (operation_time, id) > ($6, $7))
+			ORDER BY operation_time, id
+			LIMIT NULLIF($8, 0)`,
+		filter.UserIDs, filter.SegmentSlugs, filter.Operation, utcTime(filter.From), utcTime(filter.To), afterTime, afterId, filter.Limit)
+
+	if err != nil {
+		return err
+	}
+
 	defer rows.Close()
 
-	var history []model.History
 	for rows.Next() {
 		var historyRow model.History
-		if err := rows.Scan(&historyRow.UserID, &historyRow.SegmentSlug, &historyRow.Operation, &historyRow.OperationTime); err != nil {
+		if err := rows.Scan(&historyRow.ID, &historyRow.UserID, &historyRow.SegmentSlug, &historyRow.Operation, &historyRow.OperationTime,
+			&historyRow.Source, &historyRow.ActorID, &historyRow.RequestID); err != nil {
+			return err
+		}
+		if err := fn(historyRow); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountHistory returns the number of entries matching the filter, After and Limit are ignored.
+func (r *HistoryRepo) CountHistory(ctx context.Context, filter model.HistoryFilter) (int64, error) {
+	var count int64
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT COUNT(*)
+			FROM user_segment_history
+			WHERE `+historyFilterCondition,
+		filter.UserIDs, filter.SegmentSlugs, filter.Operation, utcTime(filter.From), utcTime(filter.To)).Scan(&count)
+
+	return count, err
+}
+
+// segmentStatsQuery aggregates membership changes of users matching the fields of model.HistoryFilter passed as $1-$5.
+// Entries recorded under previous slugs of a segment are counted under its current slug.
+const segmentStatsQuery = ` WITH filtered AS (
+		SELECT segment_id, segment_slug, operation, operation_time
+		FROM user_segment_history
+		WHERE user_id IS NOT NULL
+		AND operation IN ('adding', 'restored', 'removal')
+		AND ` + historyFilterCondition + `
+	), changes AS (
+		SELECT COALESCE(s.slug, f.segment_slug) AS slug,
+			   f.operation_time,
+			   CASE WHEN f.operation IN ('adding', 'restored') THEN 1 ELSE 0 END AS added,
+			   CASE WHEN f.operation = 'removal' THEN 1 ELSE 0 END AS removed
+		FROM filtered f
+		LEFT JOIN segments s ON s.id = f.segment_id
+	)`
+
+// StreamSegmentDailyStats passes to fn the number of users added to and removed from each segment per day, ordered by day and slug.
+func (r *HistoryRepo) StreamSegmentDailyStats(ctx context.Context, filter model.HistoryFilter, fn func(stats model.SegmentDailyStats) error) error {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		segmentStatsQuery+`
+			SELECT operation_time::date AS day, slug, SUM(added), SUM(removed)
+			FROM changes
+			GROUP BY day, slug
+			ORDER BY day, slug`,
+		filter.UserIDs, filter.SegmentSlugs, "", utcTime(filter.From), utcTime(filter.To))
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var stats model.SegmentDailyStats
+		if err := rows.Scan(&stats.Day, &stats.SegmentSlug, &stats.Added, &stats.Removed); err != nil {
+			return err
+		}
+		stats.NetChange = stats.Added - stats.Removed
+		if err := fn(stats); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetTopChurnSegments returns up to limit segments with the most users added and removed in total, limit 0 returns all segments.
+func (r *HistoryRepo) GetTopChurnSegments(ctx context.Context, filter model.HistoryFilter, limit int) ([]model.SegmentChurn, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		segmentStatsQuery+`
+			SELECT slug, SUM(added) AS added, SUM(removed) AS removed
+			FROM changes
+			GROUP BY slug
+			ORDER BY added + removed DESC, slug
+			LIMIT NULLIF($6, 0)`,
+		filter.UserIDs, filter.SegmentSlugs, "", utcTime(filter.From), utcTime(filter.To), limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var segments []model.SegmentChurn
+	for rows.Next() {
+		var segment model.SegmentChurn
+		if err := rows.Scan(&segment.SegmentSlug, &segment.Added, &segment.Removed); err != nil {
 			return nil, err
 		}
-		history = append(history, historyRow)
+		segment.NetChange = segment.Added - segment.Removed
+		segment.Churn = segment.Added + segment.Removed
+		segments = append(segments, segment)
 	}
 
-	if err := rows.Err(); err != nil {
+	return segments, rows.Err()
+}
+
+// lastMembershipEvents keeps the last adding, restored or removal event of each user and segment made until $1, which has
+// to be in UTC like operation_time (see utcTime).
+// Entries are matched to segments by id, so the segment keeps its entries made under previous slugs.
+const lastMembershipEvents = ` SELECT DISTINCT ON (user_id, COALESCE(segment_id::text, segment_slug))
+		user_id, segment_id, segment_slug, operation
+	FROM user_segment_history
+	WHERE user_id IS NOT NULL
+	AND operation IN ('adding', 'restored', 'removal')
+	AND ($1::timestamp IS NULL OR operation_time <= $1)
+	ORDER BY user_id, COALESCE(segment_id::text, segment_slug), operation_time DESC, id DESC`
+
+// GetUserSegmentsAt replays the history up to the moment at and returns slugs of segments the user was in, under their current slugs.
+func (r *HistoryRepo) GetUserSegmentsAt(ctx context.Context, userId int, at time.Time) ([]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT COALESCE(s.slug, e.segment_slug) AS slug
+			FROM (`+lastMembershipEvents+`) e
+			LEFT JOIN segments s ON s.id = e.segment_id
+			WHERE e.user_id = $2
+			AND e.operation <> 'removal'
+			ORDER BY slug`,
+		at.UTC(), userId)
+
+	if err != nil {
 		return nil, err
 	}
 
-	return history, nil
+	defer rows.Close()
+
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, rows.Err()
+}
+
+// GetSegmentUsersAt replays the history up to the moment at and returns ids of users that were in the segment.
+// The slug matches the segment by its current slug, including deleted segments, and by its previous slugs.
+func (r *HistoryRepo) GetSegmentUsersAt(ctx context.Context, slug string, at time.Time) ([]int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT DISTINCT e.user_id
+			FROM (`+lastMembershipEvents+`) e
+			WHERE e.operation <> 'removal'
+			AND (e.segment_id IN (SELECT id FROM segments WHERE slug = $2)
+				OR e.segment_id IN (SELECT segment_id FROM segment_slug_aliases WHERE slug = $2)
+				OR (e.segment_id IS NULL AND e.segment_slug = $2))
+			ORDER BY e.user_id`,
+		at.UTC(), slug)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var usersIDs []int
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		usersIDs = append(usersIDs, userId)
+	}
+
+	return usersIDs, rows.Err()
+}
+
+// GetMembershipMismatches compares memberships replayed from the whole history with users_segments of not deleted segments
+// and returns up to limit differing pairs ordered by user id and slug, limit 0 returns all of them.
+func (r *HistoryRepo) GetMembershipMismatches(ctx context.Context, limit int) ([]model.MembershipMismatch, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH replayed AS (
+				SELECT e.user_id, e.segment_id
+				FROM (`+lastMembershipEvents+`) e
+				JOIN segments s ON s.id = e.segment_id
+				WHERE e.operation <> 'removal'
+				AND s.deleted_at IS NULL
+			), live AS (
+				SELECT us.user_id, us.segment_id
+				FROM users_segments us
+				JOIN segments s ON s.id = us.segment_id
+				WHERE s.deleted_at IS NULL
+				AND us.start_time IS NULL
+			)
+			SELECT COALESCE(r.user_id, l.user_id) AS user_id, s.slug, r.user_id IS NOT NULL, l.user_id IS NOT NULL
+			FROM replayed r
+			FULL JOIN live l ON l.user_id = r.user_id AND l.segment_id = r.segment_id
+			JOIN segments s ON s.id = COALESCE(r.segment_id, l.segment_id)
+			WHERE r.user_id IS NULL OR l.user_id IS NULL
+			ORDER BY user_id, s.slug
+			LIMIT NULLIF($2, 0)`,
+		nil, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var mismatches []model.MembershipMismatch
+	for rows.Next() {
+		var mismatch model.MembershipMismatch
+		if err := rows.Scan(&mismatch.UserID, &mismatch.SegmentSlug, &mismatch.InHistory, &mismatch.InLive); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+
+	return mismatches, rows.Err()
 }
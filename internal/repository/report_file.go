@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReportFileRepo stores report files in parts, so that every replica can serve them and a file is never held in memory as a whole.
+type ReportFileRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewReportFileRepo(pool *pgxpool.Pool) *ReportFileRepo {
+	return &ReportFileRepo{
+		pool: pool,
+	}
+}
+
+// CreateReportFile adds a file that is being written. The file cannot be read until it is completed.
+func (r *ReportFileRepo) CreateReportFile(ctx context.Context, name string) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		` INSERT INTO report_files (name)
+		  VALUES ($1)`, name)
+
+	return err
+}
+
+// AddReportFilePart saves the next part of the file. It reports false if the file was deleted while it was written.
+func (r *ReportFileRepo) AddReportFilePart(ctx context.Context, name string, part int, data []byte) (bool, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` WITH file AS (
+			UPDATE report_files
+			SET updated_at = CURRENT_TIMESTAMP
+			WHERE name = $1
+			RETURNING name
+		  )
+		  INSERT INTO report_file_parts (file_name, part, data)
+		  SELECT name, $2, $3
+		  FROM file`, name, part, data)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CompleteReportFile records the size of the written file and makes it readable. It reports false if the file was deleted.
+func (r *ReportFileRepo) CompleteReportFile(ctx context.Context, name string, size int64) (bool, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE report_files
+		  SET size = $2,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE name = $1`, name, size)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// GetReportFile returns nil if there is no such completed file.
+func (r *ReportFileRepo) GetReportFile(ctx context.Context, name string) (*model.ReportFile, error) {
+	var file model.ReportFile
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT name, size
+		  FROM report_files
+		  WHERE name = $1
+		  AND size IS NOT NULL`, name).Scan(&file.Name, &file.Size)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// StreamReportFile passes the parts of the file to fn in order.
+func (r *ReportFileRepo) StreamReportFile(ctx context.Context, name string, fn func(data []byte) error) error {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT data
+		  FROM report_file_parts
+		  WHERE file_name = $1
+		  ORDER BY part`, name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *ReportFileRepo) DeleteReportFile(ctx context.Context, name string) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		` DELETE FROM report_files
+		  WHERE name = $1`, name)
+
+	return err
+}
+
+// DeleteReportFilesUpdatedBefore deletes the files, completed or abandoned while being written, that have not changed
+// since updatedBefore and returns their number.
+func (r *ReportFileRepo) DeleteReportFilesUpdatedBefore(ctx context.Context, updatedBefore time.Time) (int, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` DELETE FROM report_files
+		  WHERE updated_at < $1`, updatedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReportJobRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewReportJobRepo(pool *pgxpool.Pool) *ReportJobRepo {
+	return &ReportJobRepo{
+		pool: pool,
+	}
+}
+
+const reportJobColumns = `id, params, status, attempts, rows_total, rows_written, file_name, error, created_at, updated_at, finished_at`
+
+func scanReportJob(row pgx.Row) (*model.ReportJob, error) {
+	var job model.ReportJob
+	err := row.Scan(
+		&job.ID,
+		&job.Params,
+		&job.Status,
+		&job.Attempts,
+		&job.RowsTotal,
+		&job.RowsWritten,
+		&job.FileName,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (r *ReportJobRepo) CreateReportJob(ctx context.Context, params model.ReportJobParams) (*model.ReportJob, error) {
+	return scanReportJob(conn(ctx, r.pool).QueryRow(ctx,
+		` INSERT INTO report_jobs (params)
+		  VALUES ($1)
+		  RETURNING `+reportJobColumns, params))
+}
+
+// GetReportJob returns nil if there is no such job.
+func (r *ReportJobRepo) GetReportJob(ctx context.Context, id int64) (*model.ReportJob, error) {
+	return scanReportJob(conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT `+reportJobColumns+`
+		  FROM report_jobs
+		  WHERE id = $1`, id))
+}
+
+// ClaimReportJob marks the oldest job that is due as running and returns it, or nil if there is nothing to do.
+// Running jobs that have not reported progress for staleAfter are considered abandoned by a crashed worker and are claimed again
+// while they have attempts left, abandoned jobs that have used up maxAttempts are marked as failed.
+func (r *ReportJobRepo) ClaimReportJob(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*model.ReportJob, error) {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE report_jobs
+		  SET status = 'failed',
+			  error = 'report job was abandoned by its worker',
+			  updated_at = CURRENT_TIMESTAMP,
+			  finished_at = CURRENT_TIMESTAMP
+		  WHERE status = 'running'
+		  AND updated_at < CURRENT_TIMESTAMP - $1::interval
+		  AND attempts >= $2`, staleAfter, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReportJob(conn(ctx, r.pool).QueryRow(ctx,
+		` UPDATE report_jobs
+		  SET status = 'running',
+			  attempts = attempts + 1,
+			  rows_written = 0,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE id = (
+			SELECT id
+			FROM report_jobs
+			WHERE (status = 'pending' AND run_after <= CURRENT_TIMESTAMP)
+			OR (status = 'running' AND updated_at < CURRENT_TIMESTAMP - $1::interval AND attempts < $2)
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		  )
+		  RETURNING `+reportJobColumns, staleAfter, maxAttempts))
+}
+
+func (r *ReportJobRepo) SetReportJobRowsTotal(ctx context.Context, id int64, rowsTotal int64) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE report_jobs
+		  SET rows_total = $2,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1`, id, rowsTotal)
+
+	return err
+}
+
+// UpdateReportJobProgress saves the number of written rows and returns the current job status,
+// so the worker notices that the job was cancelled.
+func (r *ReportJobRepo) UpdateReportJobProgress(ctx context.Context, id int64, rowsWritten int64) (string, error) {
+	var status string
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` UPDATE report_jobs
+		  SET rows_written = $2,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		  RETURNING status`, id, rowsWritten).Scan(&status)
+
+	return status, err
+}
+
+// CompleteReportJob marks the running job as done. It reports false if the job is not running anymore.
+func (r *ReportJobRepo) CompleteReportJob(ctx context.Context, id int64, fileName string, rowsWritten int64) (bool, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE report_jobs
+		  SET status = 'done',
+			  file_name = $2,
+			  rows_written = $3,
+			  error = NULL,
+			  updated_at = CURRENT_TIMESTAMP,
+			  finished_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		  AND status = 'running'`, id, fileName, rowsWritten)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// FailReportJob records the error of the running job. The job is scheduled again at retryAt,
+// or marked as failed if retryAt is nil.
+func (r *ReportJobRepo) FailReportJob(ctx context.Context, id int64, errMessage string, retryAt *time.Time) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE report_jobs
+		  SET status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			  error = $2,
+			  run_after = COALESCE($3, run_after),
+			  updated_at = CURRENT_TIMESTAMP,
+			  finished_at = CASE WHEN $3::timestamptz IS NULL THEN CURRENT_TIMESTAMP END
+		  WHERE id = $1
+		  AND status = 'running'`, id, errMessage, retryAt)
+
+	return err
+}
+
+// CancelReportJob cancels a pending or running job and reports whether it was cancelled.
+func (r *ReportJobRepo) CancelReportJob(ctx context.Context, id int64) (bool, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE report_jobs
+		  SET status = 'cancelled',
+			  updated_at = CURRENT_TIMESTAMP,
+			  finished_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		  AND status IN ('pending', 'running')`, id)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
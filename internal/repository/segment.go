@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
 	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
 	"github.com/jackc/pgx/v5/pgconn"
 
@@ -21,11 +24,18 @@ func NewSegmentRepo(pool *pgxpool.Pool) *SegmentRepo {
 	}
 }
 
-func (r *SegmentRepo) CreateSegment(ctx context.Context, slug string) (int, error) {
-	row := r.pool.QueryRow(ctx,
-		` INSERT INTO segments (slug)
-		  VALUES ($1)
-		  RETURNING id`, slug)
+func (r *SegmentRepo) CreateSegment(ctx context.Context, segmentData model.AddSegment) (int, error) {
+	row := conn(ctx, r.pool).QueryRow(ctx,
+		` INSERT INTO segments (slug, auto_join_percent, description, owner, tags, default_ttl)
+		  VALUES ($1, $2, $3, $4, COALESCE($5, '{}'::TEXT[]), NULLIF($6::interval, '0'::interval))
+		  RETURNING id`,
+		segmentData.SegmentSlug,
+		segmentData.AutoJoinPercent,
+		segmentData.Description,
+		segmentData.Owner,
+		segmentData.Tags,
+		(*time.Duration)(segmentData.DefaultTTL),
+	)
 
 	var segmentId int
 
@@ -34,7 +44,7 @@ func (r *SegmentRepo) CreateSegment(ctx context.Context, slug string) (int, erro
 		var pgxError *pgconn.PgError
 		if errors.As(err, &pgxError) {
 			if pgxError.Code == "23505" {
-				return 0, app_err.NewBusinessError("segment slug already exists")
+				return 0, ErrSegmentAlreadyExists
 			}
 		}
 		return 0, err
@@ -43,11 +53,256 @@ func (r *SegmentRepo) CreateSegment(ctx context.Context, slug string) (int, erro
 	return segmentId, nil
 }
 
+// ErrSegmentAlreadyExists is returned when a segment that is not deleted already has the slug.
+var ErrSegmentAlreadyExists = app_err.NewConflictError("segment_already_exists", "segment slug already exists")
+
+// ErrSegmentAliasExists is returned when the slug is still an alias of another segment.
+var ErrSegmentAliasExists = app_err.NewConflictError("segment_alias_exists", "segment slug is an alias of another segment")
+
+const segmentColumns = `id, slug, auto_join_percent, description, owner, tags, default_ttl, created_at, updated_at`
+
+func (r *SegmentRepo) GetSegment(ctx context.Context, slug string) (*model.Segment, error) {
+	row := conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT `+segmentColumns+`
+		  FROM segments
+		  WHERE slug = $1
+		  AND deleted_at IS NULL`, slug)
+
+	segment, err := scanSegment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &segment, nil
+}
+
+func (r *SegmentRepo) UpdateSegment(ctx context.Context, slug string, segmentData model.UpdateSegment) (*model.Segment, error) {
+	row := conn(ctx, r.pool).QueryRow(ctx,
+		` UPDATE segments
+		  SET auto_join_percent = COALESCE($2, auto_join_percent),
+			  description = COALESCE($3, description),
+			  owner = COALESCE($4, owner),
+			  tags = COALESCE($5, tags),
+			  default_ttl = CASE WHEN $6::interval IS NULL THEN default_ttl ELSE NULLIF($6::interval, '0'::interval) END,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE slug = $1
+		  AND deleted_at IS NULL
+		  RETURNING `+segmentColumns,
+		slug,
+		segmentData.AutoJoinPercent,
+		segmentData.Description,
+		segmentData.Owner,
+		segmentData.Tags,
+		(*time.Duration)(segmentData.DefaultTTL),
+	)
+
+	segment, err := scanSegment(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &segment, nil
+}
+
+func (r *SegmentRepo) GetSegments(ctx context.Context, filter model.SegmentFilter) ([]model.Segment, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT `+segmentColumns+`
+		  FROM segments
+		  WHERE deleted_at IS NULL
+		  AND ($1 = '' OR $1 = ANY(tags))
+		  AND ($2 = '' OR owner = $2)
+		  ORDER BY slug`, filter.Tag, filter.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []model.Segment{}
+	for rows.Next() {
+		segment, err := scanSegment(rows)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+var segmentsSortColumns = map[string]string{
+	model.SegmentsSortBySlug:         "s.slug",
+	model.SegmentsSortByCreatedAt:    "s.created_at",
+	model.SegmentsSortByMembersCount: "members_count",
+}
+
+func (r *SegmentRepo) CountSegments(ctx context.Context, filter model.SegmentFilter) (int, error) {
+	var total int
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT COUNT(*)
+		  FROM segments
+		  WHERE deleted_at IS NULL
+		  AND ($1 = '' OR $1 = ANY(tags))
+		  AND ($2 = '' OR owner = $2)`, filter.Tag, filter.Owner).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// GetSegmentsPage returns one page of segments with the number of their active members.
+func (r *SegmentRepo) GetSegmentsPage(ctx context.Context, params model.SegmentsPageParams) ([]model.SegmentWithMembersCount, error) {
+	sortColumn, ok := segmentsSortColumns[params.SortBy]
+	if !ok {
+		sortColumn = segmentsSortColumns[model.SegmentsSortBySlug]
+	}
+	direction := "ASC"
+	if params.Desc {
+		direction = "DESC"
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT s.id, s.slug, s.auto_join_percent, s.description, s.owner, s.tags, s.default_ttl, s.created_at, s.updated_at,
+				 COUNT(us.id) FILTER (
+					WHERE (us.expiration_time IS NULL OR us.expiration_time > CURRENT_TIMESTAMP)
+					AND (us.start_time IS NULL OR us.start_time <= CURRENT_TIMESTAMP)
+				 ) AS members_count
+		  FROM segments s
+		  LEFT JOIN users_segments us ON us.segment_id = s.id
+		  WHERE s.deleted_at IS NULL
+		  AND ($1 = '' OR $1 = ANY(s.tags))
+		  AND ($2 = '' OR s.owner = $2)
+		  GROUP BY s.id
+		  ORDER BY `+sortColumn+` `+direction+`, s.id `+direction+`
+		  LIMIT $3 OFFSET $4`,
+		params.Tag,
+		params.Owner,
+		params.PerPage,
+		(params.Page-1)*params.PerPage,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []model.SegmentWithMembersCount{}
+	for rows.Next() {
+		segment := model.SegmentWithMembersCount{}
+		var defaultTTL *time.Duration
+		err := rows.Scan(
+			&segment.ID,
+			&segment.Slug,
+			&segment.AutoJoinPercent,
+			&segment.Description,
+			&segment.Owner,
+			&segment.Tags,
+			&defaultTTL,
+			&segment.CreatedAt,
+			&segment.UpdatedAt,
+			&segment.MembersCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		segment.DefaultTTL = (*model.TTL)(defaultTTL)
+		segments = append(segments, segment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+// GetSegmentMembers returns up to limit active members of the segment with user id greater than afterUserId.
+func (r *SegmentRepo) GetSegmentMembers(ctx context.Context, segmentId, afterUserId, limit int) ([]model.SegmentMember, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT user_id, expiration_time
+		  FROM users_segments
+		  WHERE segment_id = $1
+		  AND user_id > $2
+		  AND (expiration_time IS NULL OR expiration_time > CURRENT_TIMESTAMP)
+		  AND (start_time IS NULL OR start_time <= CURRENT_TIMESTAMP)
+		  ORDER BY user_id
+		  LIMIT $3`, segmentId, afterUserId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []model.SegmentMember{}
+	for rows.Next() {
+		member := model.SegmentMember{}
+		if err := rows.Scan(&member.UserID, &member.ExpirationTime); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// AddPercentUsersToSegment adds the users whose bucket lies between the two rollout percents.
+func (r *SegmentRepo) AddPercentUsersToSegment(ctx context.Context, segmentId, fromPercent, toPercent int) ([]int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` INSERT INTO users_segments (user_id, segment_id, expiration_time, auto_joined)
+			SELECT id, $1, CURRENT_TIMESTAMP + (SELECT default_ttl FROM segments WHERE id = $1), TRUE
+			FROM users
+			WHERE segment_bucket($1, id) >= $2 * 100
+			AND segment_bucket($1, id) < $3 * 100
+			ON CONFLICT (user_id, segment_id) DO NOTHING
+			RETURNING user_id`, segmentId, fromPercent, toPercent)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectUsersIDs(rows)
+}
+
+// RemovePercentUsersFromSegment removes the auto-joined users whose bucket lies between the two rollout percents,
+// memberships added through the API are kept. It returns only the users whose membership was active: scheduled
+// memberships are removed too but they have never been recorded as added.
+func (r *SegmentRepo) RemovePercentUsersFromSegment(ctx context.Context, segmentId, fromPercent, toPercent int) ([]int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH deleted AS (
+				DELETE FROM users_segments
+				WHERE segment_id = $1
+				AND auto_joined
+				AND segment_bucket($1, user_id) >= $2 * 100
+				AND segment_bucket($1, user_id) < $3 * 100
+				RETURNING user_id, start_time
+			)
+			SELECT user_id
+			FROM deleted
+			WHERE start_time IS NULL
+			ORDER BY user_id`, segmentId, fromPercent, toPercent)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectUsersIDs(rows)
+}
+
+// DeleteSegment archives the segment. Its memberships are kept so that the segment can be restored.
 func (r *SegmentRepo) DeleteSegment(ctx context.Context, slug string) (*int, error) {
 	var removedSegmentId *int
-	err := r.pool.QueryRow(ctx,
-		` DELETE FROM segments 
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` UPDATE segments
+		  SET deleted_at = CURRENT_TIMESTAMP
 		  WHERE slug = $1
+		  AND deleted_at IS NULL
 		  RETURNING id`, slug).Scan(&removedSegmentId)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -59,39 +314,171 @@ func (r *SegmentRepo) DeleteSegment(ctx context.Context, slug string) (*int, err
 	return removedSegmentId, err
 }
 
-func (r *SegmentRepo) RemoveUsersFromDeletedSegment(ctx context.Context, sigmentId int) ([]int, error) {
-	rows, err := r.pool.Query(ctx,
-		` DELETE FROM users_segments
-		  WHERE segment_id = $1
-		  RETURNING user_id`, sigmentId)
+// RenameSegment changes the slug of the segment and reports whether an active segment was renamed. It returns ErrSegmentAlreadyExists
+// when another segment has the slug and ErrSegmentAliasExists when the slug is still an alias of another segment.
+func (r *SegmentRepo) RenameSegment(ctx context.Context, segmentId int, newSlug string) (bool, error) {
+	var aliasTaken bool
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT EXISTS (
+			SELECT 1
+			FROM segment_slug_aliases
+			WHERE slug = $1
+			AND segment_id <> $2
+			AND expires_at > CURRENT_TIMESTAMP
+		  )`, newSlug, segmentId).Scan(&aliasTaken)
+	if err != nil {
+		return false, err
+	}
+
+	if aliasTaken {
+		return false, ErrSegmentAliasExists
+	}
 
+	tag, err := conn(ctx, r.pool).Exec(ctx,
+		` UPDATE segments
+		  SET slug = $2,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE id = $1
+		  AND deleted_at IS NULL`, segmentId, newSlug)
+	if err != nil {
+		var pgxError *pgconn.PgError
+		if errors.As(err, &pgxError) {
+			if pgxError.Code == "23505" {
+				return false, ErrSegmentAlreadyExists
+			}
+		}
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *SegmentRepo) CreateSegmentAlias(ctx context.Context, slug string, segmentId int, expiresAt time.Time) error {
+	_, err := conn(ctx, r.pool).Exec(ctx,
+		` INSERT INTO segment_slug_aliases (slug, segment_id, expires_at)
+		  VALUES ($1, $2, $3)
+		  ON CONFLICT (slug) DO UPDATE
+		  SET segment_id = EXCLUDED.segment_id,
+			  created_at = CURRENT_TIMESTAMP,
+			  expires_at = EXCLUDED.expires_at`, slug, segmentId, expiresAt)
+
+	return err
+}
+
+// ResolveSegmentAliases maps the given slugs that are unexpired aliases to the current slug of their segment.
+// A slug of an existing segment always takes precedence over an alias.
+func (r *SegmentRepo) ResolveSegmentAliases(ctx context.Context, slugs []string) (map[string]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT a.slug, s.slug
+		  FROM segment_slug_aliases a
+		  JOIN segments s ON s.id = a.segment_id
+		  WHERE a.slug = ANY($1)
+		  AND a.expires_at > CURRENT_TIMESTAMP
+		  AND s.deleted_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM segments WHERE slug = a.slug AND deleted_at IS NULL
+		  )`, slugs)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var usersIDs []int
+	aliases := make(map[string]string)
 	for rows.Next() {
-		var userId int
-		if err := rows.Scan(&userId); err != nil {
+		var alias, slug string
+		if err := rows.Scan(&alias, &slug); err != nil {
 			return nil, err
 		}
-		usersIDs = append(usersIDs, userId)
+		aliases[alias] = slug
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return usersIDs, nil
+	return aliases, nil
+}
+
+// RestoreSegment brings back the most recently archived segment with the slug if it was archived after deletedAfter.
+func (r *SegmentRepo) RestoreSegment(ctx context.Context, slug string, deletedAfter time.Time) (*int, error) {
+	var restoredSegmentId *int
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` UPDATE segments
+		  SET deleted_at = NULL,
+			  updated_at = CURRENT_TIMESTAMP
+		  WHERE id = (
+			SELECT id
+			FROM segments
+			WHERE slug = $1
+			AND deleted_at > $2
+			ORDER BY deleted_at DESC
+			LIMIT 1
+		  )
+		  RETURNING id`, slug, deletedAfter).Scan(&restoredSegmentId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		var pgxError *pgconn.PgError
+		if errors.As(err, &pgxError) {
+			if pgxError.Code == "23505" {
+				return nil, ErrSegmentAlreadyExists
+			}
+		}
+		return nil, err
+	}
+
+	return restoredSegmentId, nil
+}
+
+// PurgeDeletedSegments permanently deletes segments archived before deletedBefore together with their memberships and slug aliases.
+func (r *SegmentRepo) PurgeDeletedSegments(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purgedCount int
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` WITH purged_segments AS (
+				DELETE FROM segments
+				WHERE deleted_at IS NOT NULL
+				AND deleted_at <= $1
+				RETURNING id
+			), purged_memberships AS (
+				DELETE FROM users_segments
+				WHERE segment_id IN (SELECT id FROM purged_segments)
+			), purged_aliases AS (
+				DELETE FROM segment_slug_aliases
+				WHERE segment_id IN (SELECT id FROM purged_segments)
+			)
+			SELECT COUNT(*) FROM purged_segments`, deletedBefore).Scan(&purgedCount)
+	if err != nil {
+		return 0, err
+	}
+
+	return purgedCount, nil
 }
 
+// GetSegmentUsers returns the users with an active membership in the segment. Scheduled memberships are left out until they are activated.
+func (r *SegmentRepo) GetSegmentUsers(ctx context.Context, segmentId int) ([]int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT user_id
+		  FROM users_segments
+		  WHERE segment_id = $1
+		  AND (expiration_time IS NULL OR expiration_time > CURRENT_TIMESTAMP)
+		  AND start_time IS NULL
+		  ORDER BY user_id`, segmentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return collectUsersIDs(rows)
+}
+
+// AddMultipleUsersToSegment adds the users picked by the auto-join rollout of a new segment.
 func (r *SegmentRepo) AddMultipleUsersToSegment(ctx context.Context, segmentId int, usersIDs []int) error {
 	query := `
-		INSERT INTO users_segments (user_id, segment_id)
-		VALUES ($1, $2)`
+		INSERT INTO users_segments (user_id, segment_id, expiration_time, auto_joined)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + (SELECT default_ttl FROM segments WHERE id = $2), TRUE)`
 
 	for _, userId := range usersIDs {
-		_, err := r.pool.Exec(ctx, query, userId, segmentId)
+		_, err := conn(ctx, r.pool).Exec(ctx, query, userId, segmentId)
 		if err != nil {
 			return err
 		}
@@ -99,16 +486,20 @@ func (r *SegmentRepo) AddMultipleUsersToSegment(ctx context.Context, segmentId i
 	return nil
 }
 
+// GetSegmentsBySlug returns the slugs of the segments that exist. Within a transaction the segments are locked
+// until it ends, so they can not be deleted or renamed while their memberships change.
 func (r *SegmentRepo) GetSegmentsBySlug(ctx context.Context, slugs []string) ([]string, error) {
 	slugArray := &pgtype.TextArray{}
 	if err := slugArray.Set(slugs); err != nil {
 		return nil, err
 	}
 
-	rows, err := r.pool.Query(ctx,
+	rows, err := conn(ctx, r.pool).Query(ctx,
 		` SELECT slug 
 		  FROM segments 
-		  WHERE slug = ANY($1)`, slugArray)
+		  WHERE slug = ANY($1)
+		  AND deleted_at IS NULL
+		  FOR SHARE`, slugArray)
 	if err != nil {
 		return nil, err
 	}
@@ -130,3 +521,40 @@ func (r *SegmentRepo) GetSegmentsBySlug(ctx context.Context, slugs []string) ([]
 
 	return segments, nil
 }
+
+func scanSegment(row pgx.Row) (model.Segment, error) {
+	segment := model.Segment{}
+	var defaultTTL *time.Duration
+	err := row.Scan(
+		&segment.ID,
+		&segment.Slug,
+		&segment.AutoJoinPercent,
+		&segment.Description,
+		&segment.Owner,
+		&segment.Tags,
+		&defaultTTL,
+		&segment.CreatedAt,
+		&segment.UpdatedAt,
+	)
+	segment.DefaultTTL = (*model.TTL)(defaultTTL)
+
+	return segment, err
+}
+
+func collectUsersIDs(rows pgx.Rows) ([]int, error) {
+	defer rows.Close()
+
+	var usersIDs []int
+	for rows.Next() {
+		var userId int
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		usersIDs = append(usersIDs, userId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usersIDs, nil
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
-	"time"
+	"errors"
 
+	"github.com/elgntt/segmentation-service/internal/model"
 	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,42 +20,187 @@ func NewUserRepo(pool *pgxpool.Pool) *UserRepo {
 	}
 }
 
-func (r *UserRepo) AddUserToMultipleSegments(ctx context.Context, expirationTime *time.Time, segmentsSlugs []string, userId int) ([]string, error) {
-	query := `
-		INSERT INTO users_segments (user_id, segment_id, expiration_time)
-		VALUES ($1, (
-			SELECT id FROM segments WHERE slug = $2
-		), $3)
-		ON CONFLICT (user_id, segment_id) DO NOTHING`
-
-	addedSlugs := make([]string, 0, len(segmentsSlugs))
-	for _, segmentSlug := range segmentsSlugs {
-		result, err := r.pool.Exec(ctx, query, userId, segmentSlug, expirationTime)
-		if err != nil {
+// CreateUser registers the user and reports whether it was seen for the first time.
+func (r *UserRepo) CreateUser(ctx context.Context, userId int) (bool, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` INSERT INTO users (id)
+		  VALUES ($1)
+		  ON CONFLICT (id) DO NOTHING`, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *UserRepo) DeleteUser(ctx context.Context, userId int) (bool, error) {
+	result, err := conn(ctx, r.pool).Exec(ctx,
+		` DELETE FROM users
+		  WHERE id = $1`, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// UserExists reports whether the user exists. Within a transaction the user is locked until it ends,
+// so the user can not be deleted while their memberships change.
+func (r *UserRepo) UserExists(ctx context.Context, userId int) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.pool).QueryRow(ctx,
+		` SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 FOR SHARE)`, userId).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *UserRepo) RemoveUserFromAllSegments(ctx context.Context, userId int) ([]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH deleted_segments AS (
+				DELETE FROM users_segments
+				WHERE user_id = $1
+				RETURNING segment_id, start_time
+			)
+			SELECT s.slug
+			FROM deleted_segments d
+			JOIN segments s ON d.segment_id = s.id
+			WHERE s.deleted_at IS NULL
+			AND d.start_time IS NULL`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deletedSegmentsSlugs []string
+	for rows.Next() {
+		var deletedSegment string
+		if err := rows.Scan(&deletedSegment); err != nil {
 			return nil, err
 		}
-		if result.RowsAffected() == 1 {
-			addedSlugs = append(addedSlugs, segmentSlug)
+		deletedSegmentsSlugs = append(deletedSegmentsSlugs, deletedSegment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deletedSegmentsSlugs, nil
+}
+
+// AddUserToPercentSegments adds the user to every auto-join segment whose rollout covers the user's bucket.
+func (r *UserRepo) AddUserToPercentSegments(ctx context.Context, userId int) ([]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` INSERT INTO users_segments (user_id, segment_id, expiration_time, auto_joined)
+			SELECT $1, id, CURRENT_TIMESTAMP + default_ttl, TRUE
+			FROM segments
+			WHERE deleted_at IS NULL
+			AND auto_join_percent > 0
+			AND segment_bucket(id, $1) < auto_join_percent * 100
+			ON CONFLICT (user_id, segment_id) DO NOTHING
+			RETURNING (SELECT slug FROM segments WHERE id = segment_id)`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addedSlugs []string
+	for rows.Next() {
+		var segmentSlug string
+		if err := rows.Scan(&segmentSlug); err != nil {
+			return nil, err
 		}
+		addedSlugs = append(addedSlugs, segmentSlug)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
 	return addedSlugs, nil
 }
 
+// AddUserToMultipleSegments adds the user to the segments. With upsert the memberships the user already has get the new expiration time
+// and stop counting as auto-joined, so lowering the rollout percent no longer removes them. Upsert also moves the start time of
+// memberships that have not started yet, one without a start time starts right away. Active memberships stay active.
+// It returns slugs of the segments the user was added to, including the scheduled memberships started by the upsert,
+// and slugs of the active memberships whose expiration time changed.
+func (r *UserRepo) AddUserToMultipleSegments(ctx context.Context, userId int, segments []model.SegmentExpiration, upsert bool) ([]string, []string, error) {
+	query := `
+		WITH segment AS (
+			SELECT id, default_ttl
+			FROM segments
+			WHERE slug = $2
+			AND deleted_at IS NULL
+		), existing AS (
+			SELECT us.start_time
+			FROM users_segments us
+			JOIN segment s ON s.id = us.segment_id
+			WHERE us.user_id = $1
+			FOR UPDATE OF us
+		)
+		INSERT INTO users_segments (user_id, segment_id, expiration_time, start_time)
+		SELECT $1, id, CASE WHEN $5::boolean THEN COALESCE($6::timestamptz, CURRENT_TIMESTAMP) + default_ttl ELSE $3::timestamptz END, $6::timestamptz
+		FROM segment
+		ON CONFLICT (user_id, segment_id) DO UPDATE
+		SET expiration_time = EXCLUDED.expiration_time,
+			start_time = CASE WHEN users_segments.start_time IS NULL THEN NULL ELSE EXCLUDED.start_time END,
+			auto_joined = FALSE
+		WHERE $4::boolean
+		AND (
+			users_segments.expiration_time IS DISTINCT FROM EXCLUDED.expiration_time
+			OR (users_segments.start_time IS NOT NULL AND users_segments.start_time IS DISTINCT FROM EXCLUDED.start_time)
+		)
+		RETURNING xmax = 0 AS inserted, (SELECT start_time FROM existing) IS NOT NULL AS was_scheduled, start_time IS NOT NULL AS scheduled`
+
+	addedSlugs := make([]string, 0, len(segments))
+	var changedSlugs []string
+	for _, segment := range segments {
+		var inserted, wasScheduled, scheduled bool
+		err := conn(ctx, r.pool).QueryRow(ctx, query, userId, segment.SegmentSlug, segment.ExpirationTime, upsert, segment.UseDefaultTTL, segment.StartTime).
+			Scan(&inserted, &wasScheduled, &scheduled)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return nil, nil, err
+		}
+
+		switch {
+		case inserted:
+			addedSlugs = append(addedSlugs, segment.SegmentSlug)
+		case !wasScheduled:
+			changedSlugs = append(changedSlugs, segment.SegmentSlug)
+		case !scheduled:
+			// the scheduled membership was started by the upsert
+			addedSlugs = append(addedSlugs, segment.SegmentSlug)
+		}
+	}
+
+	return addedSlugs, changedSlugs, nil
+}
+
 func (r *UserRepo) RemoveUserFromMultipleSegments(ctx context.Context, segmentsSlugsToRemove []string, userId int) ([]string, error) {
 	slugArray := &pgtype.TextArray{}
 	if err := slugArray.Set(segmentsSlugsToRemove); err != nil {
 		return nil, err
 	}
 
-	rows, err := r.pool.Query(ctx,
-		`DELETE FROM users_segments
-			WHERE user_id = $1
-	  		AND segment_id IN (SELECT id FROM segments WHERE slug = ANY($2))
-			RETURNING (SELECT slug FROM segments WHERE id = segment_id)`, userId, slugArray)
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH deleted_segments AS (
+				DELETE FROM users_segments
+				WHERE user_id = $1
+				AND segment_id IN (SELECT id FROM segments WHERE slug = ANY($2) AND deleted_at IS NULL)
+				RETURNING segment_id, start_time
+			)
+			SELECT s.slug
+			FROM deleted_segments d
+			JOIN segments s ON d.segment_id = s.id
+			WHERE d.start_time IS NULL`, userId, slugArray)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	deletedSegmentsSlugs := make([]string, 0, len(segmentsSlugsToRemove))
 
@@ -64,26 +211,21 @@ func (r *UserRepo) RemoveUserFromMultipleSegments(ctx context.Context, segmentsS
 		}
 		deletedSegmentsSlugs = append(deletedSegmentsSlugs, deletedSegment)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return deletedSegmentsSlugs, nil
 }
 
-func (r *UserRepo) GetPercentUsers(ctx context.Context, usersPercent int) ([]int, error) {
-	rows, err := r.pool.Query(ctx,
-		`WITH users AS (
-			SELECT 
-			  DISTINCT user_id 
-			FROM 
-			  users_segments
-		  ) 
-		  SELECT 
-			  users.user_id 
-		  FROM 
-			  users 
-		  ORDER BY 
-			  RANDOM() 
-		  LIMIT 
-			  (SELECT COUNT(*) FROM USERS) * $1/100`, usersPercent)
+// GetPercentUsers returns the users whose bucket for the segment falls into the given percent.
+// The selection is stable: the same segment and percent always yield the same users.
+func (r *UserRepo) GetPercentUsers(ctx context.Context, segmentId, usersPercent int) ([]int, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT id
+			FROM users
+			WHERE segment_bucket($1, id) < $2 * 100
+			ORDER BY id`, segmentId, usersPercent)
 	if err != nil {
 		return nil, err
 	}
@@ -106,12 +248,14 @@ func (r *UserRepo) GetPercentUsers(ctx context.Context, usersPercent int) ([]int
 }
 
 func (r *UserRepo) GetActiveUserSegments(ctx context.Context, userId int) ([]string, error) {
-	rows, err := r.pool.Query(ctx,
+	rows, err := conn(ctx, r.pool).Query(ctx,
 		` SELECT segments.slug
 			FROM users_segments us
 			JOIN segments  ON us.segment_id = segments.id
 			WHERE us.user_id = $1
-			AND (us.expiration_time IS NULL OR us.expiration_time > CURRENT_TIMESTAMP)`, userId)
+			AND segments.deleted_at IS NULL
+			AND (us.expiration_time IS NULL OR us.expiration_time > CURRENT_TIMESTAMP)
+			AND (us.start_time IS NULL OR us.start_time <= CURRENT_TIMESTAMP)`, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -133,3 +277,73 @@ func (r *UserRepo) GetActiveUserSegments(ctx context.Context, userId int) ([]str
 
 	return userSegmentSlugs, nil
 }
+
+// GetActiveUsersSegments returns the active segments of the users by user id, users without active segments are left out.
+func (r *UserRepo) GetActiveUsersSegments(ctx context.Context, userIds []int) (map[int][]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` SELECT us.user_id, segments.slug
+			FROM users_segments us
+			JOIN segments ON us.segment_id = segments.id
+			WHERE us.user_id = ANY($1::int[])
+			AND segments.deleted_at IS NULL
+			AND (us.expiration_time IS NULL OR us.expiration_time > CURRENT_TIMESTAMP)
+			AND (us.start_time IS NULL OR us.start_time <= CURRENT_TIMESTAMP)`, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectSegmentsByUser(rows, len(userIds))
+}
+
+// CreateUsersWithAutoJoin creates the users that do not exist yet and adds them to every auto-join segment whose rollout
+// covers their bucket, all in one statement. It returns slugs of the segments the created users were added to by user id.
+func (r *UserRepo) CreateUsersWithAutoJoin(ctx context.Context, userIds []int) (map[int][]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		` WITH created_users AS (
+				INSERT INTO users (id)
+				SELECT DISTINCT unnest($1::int[])
+				ON CONFLICT (id) DO NOTHING
+				RETURNING id
+			), joined AS (
+				INSERT INTO users_segments (user_id, segment_id, expiration_time, auto_joined)
+				SELECT u.id, s.id, CURRENT_TIMESTAMP + s.default_ttl, TRUE
+				FROM created_users u
+				JOIN segments s ON s.deleted_at IS NULL
+				AND s.auto_join_percent > 0
+				AND segment_bucket(s.id, u.id) < s.auto_join_percent * 100
+				ON CONFLICT (user_id, segment_id) DO NOTHING
+				RETURNING user_id, segment_id
+			)
+			SELECT j.user_id, s.slug
+			FROM joined j
+			JOIN segments s ON s.id = j.segment_id
+			ORDER BY j.user_id, s.id`, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectSegmentsByUser(rows, 0)
+}
+
+// collectSegmentsByUser reads rows of user id and segment slug.
+func collectSegmentsByUser(rows pgx.Rows, usersCount int) (map[int][]string, error) {
+	defer rows.Close()
+
+	usersSegments := make(map[int][]string, usersCount)
+	for rows.Next() {
+		var (
+			userId      int
+			segmentSlug string
+		)
+		if err := rows.Scan(&userId, &segmentSlug); err != nil {
+			return nil, err
+		}
+
+		usersSegments[userId] = append(usersSegments[userId], segmentSlug)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usersSegments, nil
+}
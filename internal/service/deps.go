@@ -6,39 +6,105 @@ import (
 	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
 )
 
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type SegmentRepo interface {
-	CreateSegment(ctx context.Context, slug string) (int, error)
+	CreateSegment(ctx context.Context, segmentData model.AddSegment) (int, error)
 	DeleteSegment(ctx context.Context, slug string) (*int, error)
+	GetSegment(ctx context.Context, slug string) (*model.Segment, error)
+	UpdateSegment(ctx context.Context, slug string, segmentData model.UpdateSegment) (*model.Segment, error)
+	GetSegments(ctx context.Context, filter model.SegmentFilter) ([]model.Segment, error)
+	CountSegments(ctx context.Context, filter model.SegmentFilter) (int, error)
+	GetSegmentsPage(ctx context.Context, params model.SegmentsPageParams) ([]model.SegmentWithMembersCount, error)
+	GetSegmentMembers(ctx context.Context, segmentId, afterUserId, limit int) ([]model.SegmentMember, error)
+	AddPercentUsersToSegment(ctx context.Context, segmentId, fromPercent, toPercent int) ([]int, error)
+	RemovePercentUsersFromSegment(ctx context.Context, segmentId, fromPercent, toPercent int) ([]int, error)
 	AddMultipleUsersToSegment(ctx context.Context, segmentId int, usersIDs []int) error
 
 	GetSegmentsBySlug(ctx context.Context, slugs []string) ([]string, error)
 
-	RemoveUsersFromDeletedSegment(ctx context.Context, sigmentId int) ([]int, error)
+	GetSegmentUsers(ctx context.Context, segmentId int) ([]int, error)
+	RestoreSegment(ctx context.Context, slug string, deletedAfter time.Time) (*int, error)
+	PurgeDeletedSegments(ctx context.Context, deletedBefore time.Time) (int, error)
+	RenameSegment(ctx context.Context, segmentId int, newSlug string) (bool, error)
+	CreateSegmentAlias(ctx context.Context, slug string, segmentId int, expiresAt time.Time) error
+	ResolveSegmentAliases(ctx context.Context, slugs []string) (map[string]string, error)
 }
 
 type HistoryRepo interface {
 	RecordUserMultipleSegmentsToHistory(ctx context.Context, historyData model.HistoryDataMultipleSegments) error
 	RecordMultipleUsersToHistory(ctx context.Context, historyData model.HistoryDataMultipleUsers) error
-	DeleteExpiredUserSegments(ctx context.Context) ([]model.UsersSegments, error)
-	GetHistory(ctx context.Context, month, year, userId int) ([]model.History, error)
+	RecordSegmentEvent(ctx context.Context, event model.SegmentEvent) error
+	DeleteExpiredUserSegments(ctx context.Context, limit int) ([]model.UsersSegments, int, error)
+	ActivateScheduledUserSegments(ctx context.Context, limit int) ([]model.UsersSegments, int, error)
+	GetHistory(ctx context.Context, filter model.HistoryFilter) ([]model.History, error)
+	StreamHistory(ctx context.Context, filter model.HistoryFilter, fn func(historyRow model.History) error) error
+	CountHistory(ctx context.Context, filter model.HistoryFilter) (int64, error)
+	StreamSegmentDailyStats(ctx context.Context, filter model.HistoryFilter, fn func(stats model.SegmentDailyStats) error) error
+	GetTopChurnSegments(ctx context.Context, filter model.HistoryFilter, limit int) ([]model.SegmentChurn, error)
+	GetUserSegmentsAt(ctx context.Context, userId int, at time.Time) ([]string, error)
+	GetSegmentUsersAt(ctx context.Context, slug string, at time.Time) ([]int, error)
+	GetMembershipMismatches(ctx context.Context, limit int) ([]model.MembershipMismatch, error)
+}
+
+type ReportJobRepo interface {
+	CreateReportJob(ctx context.Context, params model.ReportJobParams) (*model.ReportJob, error)
+	GetReportJob(ctx context.Context, id int64) (*model.ReportJob, error)
+	ClaimReportJob(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*model.ReportJob, error)
+	SetReportJobRowsTotal(ctx context.Context, id int64, rowsTotal int64) error
+	UpdateReportJobProgress(ctx context.Context, id int64, rowsWritten int64) (string, error)
+	CompleteReportJob(ctx context.Context, id int64, fileName string, rowsWritten int64) (bool, error)
+	FailReportJob(ctx context.Context, id int64, errMessage string, retryAt *time.Time) error
+	CancelReportJob(ctx context.Context, id int64) (bool, error)
+}
+
+type ReportFileRepo interface {
+	CreateReportFile(ctx context.Context, name string) error
+	AddReportFilePart(ctx context.Context, name string, part int, data []byte) (bool, error)
+	CompleteReportFile(ctx context.Context, name string, size int64) (bool, error)
+	GetReportFile(ctx context.Context, name string) (*model.ReportFile, error)
+	StreamReportFile(ctx context.Context, name string, fn func(data []byte) error) error
+	DeleteReportFile(ctx context.Context, name string) error
+	DeleteReportFilesUpdatedBefore(ctx context.Context, updatedBefore time.Time) (int, error)
 }
 
 type UserRepo interface {
+	CreateUser(ctx context.Context, userId int) (bool, error)
+	DeleteUser(ctx context.Context, userId int) (bool, error)
+	UserExists(ctx context.Context, userId int) (bool, error)
+	RemoveUserFromAllSegments(ctx context.Context, userId int) ([]string, error)
+	AddUserToPercentSegments(ctx context.Context, userId int) ([]string, error)
 	GetActiveUserSegments(ctx context.Context, userId int) ([]string, error)
+	GetActiveUsersSegments(ctx context.Context, userIds []int) (map[int][]string, error)
+	CreateUsersWithAutoJoin(ctx context.Context, userIds []int) (map[int][]string, error)
 	RemoveUserFromMultipleSegments(ctx context.Context, segmentsSlugsToRemove []string, userId int) ([]string, error)
-	AddUserToMultipleSegments(ctx context.Context, expirationTime *time.Time, segmentsSlugs []string, userId int) ([]string, error)
-	GetPercentUsers(ctx context.Context, usersPercent int) ([]int, error)
+	AddUserToMultipleSegments(ctx context.Context, userId int, segments []model.SegmentExpiration, upsert bool) ([]string, []string, error)
+	GetPercentUsers(ctx context.Context, segmentId, usersPercent int) ([]int, error)
 }
 
 const (
-	addOperationStr    = "adding"
-	removeOperationStr = "removal"
-
-	csvFilesDir = "assets/csv_reports/"
+	// reportFilesPath is the path of the report download links.
+	reportFilesPath = "assets/csv_reports/"
 )
 
-const (
-	ErrNoDataAvailable = "no data available"
+var (
+	ErrNoDataAvailable       = app_err.NewNotFoundError("no_data_available", "no data available")
+	ErrSegmentDoesNotExist   = app_err.NewNotFoundError("segment_not_found", "segment does not exists")
+	ErrNothingToRestore      = app_err.NewNotFoundError("nothing_to_restore", "no deleted segment to restore")
+	ErrUserAlreadyExists     = app_err.NewConflictError("user_already_exists", "user already exists")
+	ErrUserDoesNotExist      = app_err.NewNotFoundError("user_not_found", "user does not exist")
+	ErrUnknownOperation      = app_err.NewValidationError("unknown_operation", "unknown history operation")
+	ErrInvalidReportLink     = app_err.NewForbiddenError("invalid_report_link", "invalid report link")
+	ErrReportLinkExpired     = app_err.NewForbiddenError("report_link_expired", "report link has expired")
+	ErrUnknownReportFormat   = app_err.NewValidationError("unknown_report_format", "unknown report format")
+	ErrInvalidDelimiter      = app_err.NewValidationError("invalid_delimiter", "invalid csv delimiter")
+	ErrReportJobDoesNotExist = app_err.NewNotFoundError("report_job_not_found", "report job does not exist")
+	ErrReportJobFinished     = app_err.NewConflictError("report_job_finished", "report job is already finished")
+	ErrUnknownReportKind     = app_err.NewValidationError("unknown_report_kind", "unknown report kind")
+	ErrReportTooManyRows     = app_err.NewValidationError("report_too_many_rows", "report has more rows than the format can hold")
 )
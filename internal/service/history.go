@@ -2,91 +2,245 @@ package service
 
 import (
 	"context"
-	"encoding/csv"
-	"github.com/elgntt/segmentation-service/internal/config"
-	"os"
-	"strconv"
+	"errors"
+	"io"
+	"time"
 
+	"github.com/elgntt/segmentation-service/internal/config"
 	"github.com/elgntt/segmentation-service/internal/model"
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
-	"github.com/google/uuid"
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
 )
 
 type HistoryService struct {
-	historyRepo HistoryRepo
-	segmentRepo SegmentRepo
+	historyRepo    HistoryRepo
+	segmentRepo    SegmentRepo
+	reportJobRepo  ReportJobRepo
+	reportFileRepo ReportFileRepo
+	transactor     Transactor
+	cfg            config.ReportConfig
 }
 
-func NewHistoryService(historyRepo HistoryRepo, segmentRepo SegmentRepo) *HistoryService {
+func NewHistoryService(historyRepo HistoryRepo, segmentRepo SegmentRepo, reportJobRepo ReportJobRepo, reportFileRepo ReportFileRepo, transactor Transactor, cfg config.ReportConfig) *HistoryService {
 	return &HistoryService{
-		historyRepo: historyRepo,
-		segmentRepo: segmentRepo,
+		historyRepo:    historyRepo,
+		segmentRepo:    segmentRepo,
+		reportJobRepo:  reportJobRepo,
+		reportFileRepo: reportFileRepo,
+		transactor:     transactor,
+		cfg:            cfg,
 	}
 }
 
-func (s *HistoryService) DeleteExpiredUserSegments(ctx context.Context) error {
-	usersSegments, err := s.historyRepo.DeleteExpiredUserSegments(ctx)
-	if err != nil {
-		return err
-	}
+// DeleteExpiredUserSegments removes expired memberships and records their removal. Memberships are processed in batches of batchSize,
+// each batch in its own transaction. It returns the number of removed memberships.
+func (s *HistoryService) DeleteExpiredUserSegments(ctx context.Context, batchSize int) (int, error) {
+	return s.processInBatches(ctx, batchSize, s.historyRepo.DeleteExpiredUserSegments, model.OperationRemoval, model.HistorySourceTTLExpiry)
+}
+
+// ActivateScheduledUserSegments activates the scheduled memberships whose start time has come and records their adding.
+// Memberships are processed in batches of batchSize, each batch in its own transaction. It returns the number of activated memberships.
+func (s *HistoryService) ActivateScheduledUserSegments(ctx context.Context, batchSize int) (int, error) {
+	return s.processInBatches(ctx, batchSize, s.historyRepo.ActivateScheduledUserSegments, model.OperationAdding, model.HistorySourceSchedule)
+}
 
-	for _, userSegments := range usersSegments {
-		err = s.RecordUserMultipleSegmentsToHistory(ctx, userSegments.SegmentSlugs, removeOperationStr, userSegments.UserId)
+// processInBatches calls process until it handles less than batchSize memberships and records the returned ones to the history.
+func (s *HistoryService) processInBatches(
+	ctx context.Context,
+	batchSize int,
+	process func(ctx context.Context, limit int) ([]model.UsersSegments, int, error),
+	operation model.Operation,
+	source string,
+) (int, error) {
+	total := 0
+	for {
+		processed := 0
+		err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			usersSegments, count, err := process(ctx, batchSize)
+			if err != nil {
+				return err
+			}
+			processed = count
+
+			for _, userSegments := range usersSegments {
+				err = s.RecordUserMultipleSegmentsToHistory(ctx, userSegments.SegmentSlugs, operation, source, userSegments.UserId)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
 		if err != nil {
-			return err
+			return total, err
 		}
-	}
 
-	return nil
+		total += processed
+		if processed < batchSize {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
 }
 
-func (s *HistoryService) GenerateCSVFile(ctx context.Context, month, year, userId int) (string, error) {
-	history, err := s.historyRepo.GetHistory(ctx, month, year, userId)
+// GetHistory returns one page of history entries matching the filter. Pass NextCursor of the page as filter.After to get the next one.
+func (s *HistoryService) GetHistory(ctx context.Context, filter model.HistoryFilter) (model.HistoryPage, error) {
+	if filter.Operation != "" && !filter.Operation.IsValid() {
+		return model.HistoryPage{}, ErrUnknownOperation
+	}
+
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	entries, err := s.historyRepo.GetHistory(ctx, filter)
 	if err != nil {
-		return "", err
+		return model.HistoryPage{}, err
 	}
 
-	if history == nil {
-		return "", app_err.NewBusinessError(ErrNoDataAvailable)
+	page := model.HistoryPage{
+		Entries: entries,
+	}
+	if page.Entries == nil {
+		page.Entries = []model.History{}
+	}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = &model.HistoryCursor{
+			OperationTime: last.OperationTime,
+			ID:            last.ID,
+		}
 	}
 
-	filePath := csvFilesDir + uuid.NewString() + ".csv"
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", err
+	return page, nil
+}
+
+// WriteReport streams the user's history for the month to w in the requested format, reading entries from the database one by one.
+// Nothing is written to w if there is no history.
+func (s *HistoryService) WriteReport(ctx context.Context, month, year, userId int, opts report.Options, w io.Writer) error {
+	if !report.IsFormat(opts.Format) {
+		return ErrUnknownReportFormat
 	}
 
-	defer file.Close()
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
 
-	csvWriter := csv.NewWriter(file)
+	_, err := s.writeReport(ctx, model.HistoryFilter{
+		UserIDs: []int{userId},
+		From:    &from,
+		To:      &to,
+	}, opts, w, nil)
 
-	csvWriter.Comma = ';'
+	return err
+}
 
-	for _, historyRow := range history {
-		if err := csvWriter.Write([]string{
-			strconv.Itoa(historyRow.UserID),
-			historyRow.SegmentSlug,
-			historyRow.Operation,
-			historyRow.OperationTime.Format("2006-01-02 15:04:05"),
-		}); err != nil {
-			return "", err
+var historyReportColumns = []report.Column{
+	{Name: "userId", Type: report.ColumnInt},
+	{Name: "segmentSlug", Type: report.ColumnString},
+	{Name: "operation", Type: report.ColumnString},
+	{Name: "operationTime", Type: report.ColumnTime},
+	{Name: "source", Type: report.ColumnString},
+	{Name: "actorId", Type: report.ColumnString},
+	{Name: "requestId", Type: report.ColumnString},
+}
+
+// writeReport encodes history entries matching the filter into w and returns the number of written entries.
+// onRow, if set, is called after every entry with the number of entries written so far.
+func (s *HistoryService) writeReport(ctx context.Context, filter model.HistoryFilter, opts report.Options, w io.Writer, onRow func(rowsWritten int64) error) (int64, error) {
+	return writeReportRows(w, historyReportColumns, opts, func(emit func(values []any) error) error {
+		return s.historyRepo.StreamHistory(ctx, filter, func(historyRow model.History) error {
+			var userId any
+			if historyRow.UserID != nil {
+				userId = int64(*historyRow.UserID)
+			}
+
+			return emit([]any{
+				userId,
+				historyRow.SegmentSlug,
+				string(historyRow.Operation),
+				historyRow.OperationTime,
+				optionalString(historyRow.Source),
+				optionalString(historyRow.ActorID),
+				optionalString(historyRow.RequestID),
+			})
+		})
+	}, onRow)
+}
+
+// optionalString makes an empty string a missing report value.
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// writeReportRows encodes the rows passed by stream to emit into w and returns the number of written rows.
+// The encoder is created on the first row, so nothing is written to w if there are no rows.
+func writeReportRows(w io.Writer, columns []report.Column, opts report.Options, stream func(emit func(values []any) error) error, onRow func(rowsWritten int64) error) (int64, error) {
+	var encoder report.Encoder
+	var rowsWritten int64
+	err := stream(func(values []any) error {
+		if encoder == nil {
+			var err error
+			encoder, err = report.NewEncoder(w, columns, opts)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := encoder.Encode(values); err != nil {
+			if errors.Is(err, report.ErrTooManyRows) {
+				return ErrReportTooManyRows
+			}
+			return err
 		}
+		rowsWritten++
+
+		if onRow != nil {
+			return onRow(rowsWritten)
+		}
+		return nil
+	})
+	if err != nil {
+		return rowsWritten, err
 	}
-	csvWriter.Flush()
-	if err := csvWriter.Error(); err != nil {
-		return "", err
+
+	if encoder == nil {
+		return 0, ErrNoDataAvailable
 	}
 
-	serverEndpoint := config.GetServerConfig().ServerEndpoint
+	return rowsWritten, encoder.Close()
+}
+
+// GenerateReportFile writes the report into a file and returns a signed link to it that expires after the link TTL.
+func (s *HistoryService) GenerateReportFile(ctx context.Context, month, year, userId int, opts report.Options) (string, error) {
+	if !report.IsFormat(opts.Format) {
+		return "", ErrUnknownReportFormat
+	}
+
+	fileName, err := s.createReportFile(ctx, opts.Format, func(w io.Writer) error {
+		return s.WriteReport(ctx, month, year, userId, opts, w)
+	})
+	if err != nil {
+		return "", err
+	}
 
-	return serverEndpoint + filePath, nil
+	return s.reportLink(fileName, time.Now().Add(s.cfg.LinkTTL)), nil
 }
 
-func (s *HistoryService) RecordUserMultipleSegmentsToHistory(ctx context.Context, segmentsSlugs []string, operation string, userId int) error {
+func (s *HistoryService) RecordUserMultipleSegmentsToHistory(ctx context.Context, segmentsSlugs []string, operation model.Operation, source string, userId int) error {
+	meta := audit.FromContext(ctx)
 	historyData := model.HistoryDataMultipleSegments{
 		UserId:      userId,
 		SegmentSlug: segmentsSlugs,
 		Operation:   operation,
+		Source:      source,
+		ActorID:     meta.ActorID,
+		RequestID:   meta.RequestID,
 	}
 
 	return s.historyRepo.RecordUserMultipleSegmentsToHistory(ctx, historyData)
@@ -1,16 +1,21 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
 	"github.com/golang/mock/gomock"
 )
 
 func TestHistoryService_DeleteExpiredUserSegments(t *testing.T) {
 	repoError := "error from repo"
+	batchSize := 2
 	userId := 100
 	segmentSlugs := []string{"AVITO_TECH", "AVITO_DISCOUNT_11"}
 	userSegments := []model.UsersSegments{
@@ -22,40 +27,53 @@ func TestHistoryService_DeleteExpiredUserSegments(t *testing.T) {
 	historyData := model.HistoryDataMultipleSegments{
 		UserId:      userId,
 		SegmentSlug: []string{"AVITO_TECH", "AVITO_DISCOUNT_11"},
-		Operation:   removeOperationStr,
+		Operation:   model.OperationRemoval,
+		Source:      model.HistorySourceTTLExpiry,
 	}
 	tests := []struct {
 		name              string
 		historyRepoBehave func(repository *MockHistoryRepo)
 		segmentRepoBehave func(repository *MockSegmentRepo)
+		want              int
 		wantErr           bool
 	}{
 		{
 			name: "success",
 			historyRepoBehave: func(repository *MockHistoryRepo) {
-				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any()).Return(userSegments, nil)
+				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any(), batchSize).Return(userSegments, 2, nil)
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), historyData).Return(nil)
+				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any(), batchSize).Return([]model.UsersSegments{}, 0, nil)
 			},
+			want:    2,
 			wantErr: false,
 		},
 		{
 			name: "error from DeleteExpiredUserSegments()",
 			historyRepoBehave: func(repository *MockHistoryRepo) {
-				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any()).Return(nil, errors.New(repoError))
+				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any(), batchSize).Return(nil, 0, errors.New(repoError))
 			},
 			wantErr: true,
 		},
 		{
 			name: "there are no deleted user segments",
 			historyRepoBehave: func(repository *MockHistoryRepo) {
-				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any()).Return(nil, nil)
+				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any(), batchSize).Return(nil, 0, nil)
 			},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name: "only memberships of deleted segments",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any(), batchSize).Return([]model.UsersSegments{}, 1, nil)
+			},
+			want:    1,
 			wantErr: false,
 		},
 		{
 			name: "error from RecordUserMultipleUserSegments()",
 			historyRepoBehave: func(repository *MockHistoryRepo) {
-				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any()).Return(userSegments, nil)
+				repository.EXPECT().DeleteExpiredUserSegments(gomock.Any(), batchSize).Return(userSegments, 2, nil)
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), historyData).Return(errors.New(repoError))
 			},
 			wantErr: true,
@@ -76,10 +94,295 @@ func TestHistoryService_DeleteExpiredUserSegments(t *testing.T) {
 			}
 			s := &HistoryService{
 				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
 				segmentRepo: mockSegmentRepo,
 			}
-			if err := s.DeleteExpiredUserSegments(context.Background()); (err != nil) != tt.wantErr {
+			got, err := s.DeleteExpiredUserSegments(context.Background(), batchSize)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("HistoryService.DeleteExpiredUserSegments() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("HistoryService.DeleteExpiredUserSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryService_ActivateScheduledUserSegments(t *testing.T) {
+	repoError := "error from repo"
+	userId := 100
+	userSegments := []model.UsersSegments{
+		{
+			UserId:       userId,
+			SegmentSlugs: []string{"AVITO_TECH", "AVITO_DISCOUNT_11"},
+		},
+	}
+	historyData := model.HistoryDataMultipleSegments{
+		UserId:      userId,
+		SegmentSlug: []string{"AVITO_TECH", "AVITO_DISCOUNT_11"},
+		Operation:   model.OperationAdding,
+		Source:      model.HistorySourceSchedule,
+	}
+	tests := []struct {
+		name              string
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().ActivateScheduledUserSegments(gomock.Any(), 10).Return(userSegments, 2, nil)
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), historyData).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "there are no scheduled user segments",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().ActivateScheduledUserSegments(gomock.Any(), 10).Return([]model.UsersSegments{}, 0, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "error from ActivateScheduledUserSegments()",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().ActivateScheduledUserSegments(gomock.Any(), 10).Return(nil, 0, errors.New(repoError))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from RecordUserMultipleSegmentsToHistory()",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().ActivateScheduledUserSegments(gomock.Any(), 10).Return(userSegments, 2, nil)
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), historyData).Return(errors.New(repoError))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+			}
+			if _, err := s.ActivateScheduledUserSegments(context.Background(), 10); (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.ActivateScheduledUserSegments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHistoryService_GetHistory(t *testing.T) {
+	userId := 100
+	operationTime := time.Date(2023, 8, 31, 22, 18, 10, 0, time.UTC)
+	entries := []model.History{
+		{ID: 1, UserID: &userId, SegmentSlug: "AVITO_TECH", Operation: model.OperationAdding, OperationTime: operationTime},
+		{ID: 2, UserID: &userId, SegmentSlug: "AVITO_TECH", Operation: model.OperationRemoval, OperationTime: operationTime},
+		{ID: 3, UserID: &userId, SegmentSlug: "AVITO_DISCOUNT_11", Operation: model.OperationAdding, OperationTime: operationTime},
+	}
+	tests := []struct {
+		name              string
+		filter            model.HistoryFilter
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              model.HistoryPage
+		wantErr           bool
+	}{
+		{
+			name:   "last page",
+			filter: model.HistoryFilter{UserIDs: []int{userId}, Limit: 5},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetHistory(gomock.Any(), model.HistoryFilter{UserIDs: []int{userId}, Limit: 6}).Return(entries, nil)
+			},
+			want: model.HistoryPage{
+				Entries: entries,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "has next page",
+			filter: model.HistoryFilter{Limit: 2},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetHistory(gomock.Any(), model.HistoryFilter{Limit: 3}).Return(entries, nil)
+			},
+			want: model.HistoryPage{
+				Entries: entries[:2],
+				NextCursor: &model.HistoryCursor{
+					OperationTime: operationTime,
+					ID:            2,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "no entries",
+			filter: model.HistoryFilter{Limit: 2},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetHistory(gomock.Any(), model.HistoryFilter{Limit: 3}).Return(nil, nil)
+			},
+			want: model.HistoryPage{
+				Entries: []model.History{},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unknown operation",
+			filter:  model.HistoryFilter{Operation: "unknown", Limit: 2},
+			wantErr: true,
+		},
+		{
+			name:   "error from GetHistory()",
+			filter: model.HistoryFilter{Limit: 2},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetHistory(gomock.Any(), model.HistoryFilter{Limit: 3}).Return(nil, errors.New("error from repo"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+			}
+
+			got, err := s.GetHistory(context.Background(), tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.GetHistory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryService.GetHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryService_WriteReport(t *testing.T) {
+	userId := 100
+	from := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.HistoryFilter{
+		UserIDs: []int{userId},
+		From:    &from,
+		To:      &to,
+	}
+	entries := []model.History{
+		{ID: 1, UserID: &userId, SegmentSlug: "AVITO_TECH", Operation: model.OperationAdding, OperationTime: time.Date(2023, 8, 12, 10, 0, 0, 0, time.UTC),
+			Source: model.HistorySourceAPI, ActorID: "admin", RequestID: "9f1c"},
+		{ID: 2, UserID: &userId, SegmentSlug: "AVITO_TECH", Operation: model.OperationRemoval, OperationTime: time.Date(2023, 8, 20, 18, 30, 0, 0, time.UTC),
+			Source: model.HistorySourceTTLExpiry},
+	}
+	streamEntries := func(entries []model.History) func(context.Context, model.HistoryFilter, func(model.History) error) error {
+		return func(_ context.Context, _ model.HistoryFilter, fn func(model.History) error) error {
+			for _, entry := range entries {
+				if err := fn(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	tests := []struct {
+		name              string
+		opts              report.Options
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              string
+		wantErr           bool
+	}{
+		{
+			name: "csv",
+			opts: report.Options{Format: report.FormatCSV},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(entries))
+			},
+			want:    "100;AVITO_TECH;adding;2023-08-12 10:00:00;api;admin;9f1c\n100;AVITO_TECH;removal;2023-08-20 18:30:00;ttl_expiry;;\n",
+			wantErr: false,
+		},
+		{
+			name: "csv with header and comma",
+			opts: report.Options{Format: report.FormatCSV, Delimiter: ',', Header: true},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(entries[:1]))
+			},
+			want:    "userId,segmentSlug,operation,operationTime,source,actorId,requestId\n100,AVITO_TECH,adding,2023-08-12 10:00:00,api,admin,9f1c\n",
+			wantErr: false,
+		},
+		{
+			name: "json",
+			opts: report.Options{Format: report.FormatJSON},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(entries))
+			},
+			want: `[{"userId":100,"segmentSlug":"AVITO_TECH","operation":"adding","operationTime":"2023-08-12T10:00:00Z","source":"api","actorId":"admin","requestId":"9f1c"},` +
+				`{"userId":100,"segmentSlug":"AVITO_TECH","operation":"removal","operationTime":"2023-08-20T18:30:00Z","source":"ttl_expiry"}]`,
+			wantErr: false,
+		},
+		{
+			name: "ndjson",
+			opts: report.Options{Format: report.FormatNDJSON},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(entries[:1]))
+			},
+			want:    `{"userId":100,"segmentSlug":"AVITO_TECH","operation":"adding","operationTime":"2023-08-12T10:00:00Z","source":"api","actorId":"admin","requestId":"9f1c"}` + "\n",
+			wantErr: false,
+		},
+		{
+			name:    "unknown format",
+			opts:    report.Options{Format: "pdf"},
+			wantErr: true,
+		},
+		{
+			name: "no data available",
+			opts: report.Options{Format: report.FormatParquet},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(nil))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from StreamHistory()",
+			opts: report.Options{Format: report.FormatCSV},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).Return(errors.New("error from repo"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+			}
+
+			var buf bytes.Buffer
+			err := s.WriteReport(context.Background(), 8, 2023, userId, tt.opts, &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.WriteReport() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if buf.String() != tt.want {
+				t.Errorf("HistoryService.WriteReport() = %q, want %q", buf.String(), tt.want)
 			}
 		})
 	}
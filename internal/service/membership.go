@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+)
+
+// GetUserSegmentsAt reconstructs the segments the user was in at the moment at by replaying the history.
+func (s *HistoryService) GetUserSegmentsAt(ctx context.Context, userId int, at time.Time) ([]string, error) {
+	slugs, err := s.historyRepo.GetUserSegmentsAt(ctx, userId, at)
+	if err != nil {
+		return nil, err
+	}
+
+	if slugs == nil {
+		return []string{}, nil
+	}
+
+	return slugs, nil
+}
+
+// GetSegmentUsersAt reconstructs the users that were in the segment at the moment at by replaying the history.
+func (s *HistoryService) GetSegmentUsersAt(ctx context.Context, slug string, at time.Time) ([]int, error) {
+	usersIDs, err := s.historyRepo.GetSegmentUsersAt(ctx, slug, at)
+	if err != nil {
+		return nil, err
+	}
+
+	if usersIDs == nil {
+		return []int{}, nil
+	}
+
+	return usersIDs, nil
+}
+
+// CheckMembershipConsistency diffs memberships replayed from the history against the live ones and returns up to limit mismatches.
+func (s *HistoryService) CheckMembershipConsistency(ctx context.Context, limit int) ([]model.MembershipMismatch, error) {
+	mismatches, err := s.historyRepo.GetMembershipMismatches(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if mismatches == nil {
+		return []model.MembershipMismatch{}, nil
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHistoryService_GetUserSegmentsAt(t *testing.T) {
+	userId := 42
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              []string
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetUserSegmentsAt(gomock.Any(), userId, at).Return([]string{"AVITO_TECH", "AVITO_VOICE"}, nil)
+			},
+			want:    []string{"AVITO_TECH", "AVITO_VOICE"},
+			wantErr: false,
+		},
+		{
+			name: "user was in no segments",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetUserSegmentsAt(gomock.Any(), userId, at).Return(nil, nil)
+			},
+			want:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "error from GetUserSegmentsAt()",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetUserSegmentsAt(gomock.Any(), userId, at).Return(nil, errors.New("error from repo"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			tt.historyRepoBehave(mockHistoryRepo)
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+			}
+
+			got, err := s.GetUserSegmentsAt(context.Background(), userId, at)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.GetUserSegmentsAt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryService.GetUserSegmentsAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryService_GetSegmentUsersAt(t *testing.T) {
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              []int
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetSegmentUsersAt(gomock.Any(), "AVITO_TECH", at).Return([]int{1, 42}, nil)
+			},
+			want:    []int{1, 42},
+			wantErr: false,
+		},
+		{
+			name: "segment was empty",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetSegmentUsersAt(gomock.Any(), "AVITO_TECH", at).Return(nil, nil)
+			},
+			want:    []int{},
+			wantErr: false,
+		},
+		{
+			name: "error from GetSegmentUsersAt()",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetSegmentUsersAt(gomock.Any(), "AVITO_TECH", at).Return(nil, errors.New("error from repo"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			tt.historyRepoBehave(mockHistoryRepo)
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+			}
+
+			got, err := s.GetSegmentUsersAt(context.Background(), "AVITO_TECH", at)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.GetSegmentUsersAt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryService.GetSegmentUsersAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryService_CheckMembershipConsistency(t *testing.T) {
+	tests := []struct {
+		name              string
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              []model.MembershipMismatch
+		wantErr           bool
+	}{
+		{
+			name: "mismatches found",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetMembershipMismatches(gomock.Any(), 100).Return([]model.MembershipMismatch{
+					{UserID: 42, SegmentSlug: "AVITO_TECH", InHistory: true, InLive: false},
+				}, nil)
+			},
+			want:    []model.MembershipMismatch{{UserID: 42, SegmentSlug: "AVITO_TECH", InHistory: true, InLive: false}},
+			wantErr: false,
+		},
+		{
+			name: "consistent",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetMembershipMismatches(gomock.Any(), 100).Return(nil, nil)
+			},
+			want:    []model.MembershipMismatch{},
+			wantErr: false,
+		},
+		{
+			name: "error from GetMembershipMismatches()",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetMembershipMismatches(gomock.Any(), 100).Return(nil, errors.New("error from repo"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			tt.historyRepoBehave(mockHistoryRepo)
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+			}
+
+			got, err := s.CheckMembershipConsistency(context.Background(), 100)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.CheckMembershipConsistency() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryService.CheckMembershipConsistency() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -13,6 +13,43 @@ import (
 	gomock "github.com/golang/mock/gomock"
 )
 
+// MockTransactor is a mock of Transactor interface.
+type MockTransactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactorMockRecorder
+}
+
+// MockTransactorMockRecorder is the mock recorder for MockTransactor.
+type MockTransactorMockRecorder struct {
+	mock *MockTransactor
+}
+
+// NewMockTransactor creates a new mock instance.
+func NewMockTransactor(ctrl *gomock.Controller) *MockTransactor {
+	mock := &MockTransactor{ctrl: ctrl}
+	mock.recorder = &MockTransactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactor) EXPECT() *MockTransactorMockRecorder {
+	return m.recorder
+}
+
+// WithinTransaction mocks base method.
+func (m *MockTransactor) WithinTransaction(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTransaction", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTransaction indicates an expected call of WithinTransaction.
+func (mr *MockTransactorMockRecorder) WithinTransaction(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTransaction", reflect.TypeOf((*MockTransactor)(nil).WithinTransaction), ctx, fn)
+}
+
 // MockSegmentRepo is a mock of SegmentRepo interface.
 type MockSegmentRepo struct {
 	ctrl     *gomock.Controller
@@ -50,19 +87,63 @@ func (mr *MockSegmentRepoMockRecorder) AddMultipleUsersToSegment(ctx, segmentId,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMultipleUsersToSegment", reflect.TypeOf((*MockSegmentRepo)(nil).AddMultipleUsersToSegment), ctx, segmentId, usersIDs)
 }
 
+// AddPercentUsersToSegment mocks base method.
+func (m *MockSegmentRepo) AddPercentUsersToSegment(ctx context.Context, segmentId, fromPercent, toPercent int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPercentUsersToSegment", ctx, segmentId, fromPercent, toPercent)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPercentUsersToSegment indicates an expected call of AddPercentUsersToSegment.
+func (mr *MockSegmentRepoMockRecorder) AddPercentUsersToSegment(ctx, segmentId, fromPercent, toPercent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPercentUsersToSegment", reflect.TypeOf((*MockSegmentRepo)(nil).AddPercentUsersToSegment), ctx, segmentId, fromPercent, toPercent)
+}
+
+// CountSegments mocks base method.
+func (m *MockSegmentRepo) CountSegments(ctx context.Context, filter model.SegmentFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountSegments", ctx, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountSegments indicates an expected call of CountSegments.
+func (mr *MockSegmentRepoMockRecorder) CountSegments(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountSegments", reflect.TypeOf((*MockSegmentRepo)(nil).CountSegments), ctx, filter)
+}
+
 // CreateSegment mocks base method.
-func (m *MockSegmentRepo) CreateSegment(ctx context.Context, slug string) (int, error) {
+func (m *MockSegmentRepo) CreateSegment(ctx context.Context, segmentData model.AddSegment) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSegment", ctx, slug)
+	ret := m.ctrl.Call(m, "CreateSegment", ctx, segmentData)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateSegment indicates an expected call of CreateSegment.
-func (mr *MockSegmentRepoMockRecorder) CreateSegment(ctx, slug interface{}) *gomock.Call {
+func (mr *MockSegmentRepoMockRecorder) CreateSegment(ctx, segmentData interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSegment", reflect.TypeOf((*MockSegmentRepo)(nil).CreateSegment), ctx, slug)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSegment", reflect.TypeOf((*MockSegmentRepo)(nil).CreateSegment), ctx, segmentData)
+}
+
+// CreateSegmentAlias mocks base method.
+func (m *MockSegmentRepo) CreateSegmentAlias(ctx context.Context, slug string, segmentId int, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSegmentAlias", ctx, slug, segmentId, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSegmentAlias indicates an expected call of CreateSegmentAlias.
+func (mr *MockSegmentRepoMockRecorder) CreateSegmentAlias(ctx, slug, segmentId, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSegmentAlias", reflect.TypeOf((*MockSegmentRepo)(nil).CreateSegmentAlias), ctx, slug, segmentId, expiresAt)
 }
 
 // DeleteSegment mocks base method.
@@ -80,6 +161,66 @@ func (mr *MockSegmentRepoMockRecorder) DeleteSegment(ctx, slug interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSegment", reflect.TypeOf((*MockSegmentRepo)(nil).DeleteSegment), ctx, slug)
 }
 
+// GetSegment mocks base method.
+func (m *MockSegmentRepo) GetSegment(ctx context.Context, slug string) (*model.Segment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSegment", ctx, slug)
+	ret0, _ := ret[0].(*model.Segment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegment indicates an expected call of GetSegment.
+func (mr *MockSegmentRepoMockRecorder) GetSegment(ctx, slug interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegment", reflect.TypeOf((*MockSegmentRepo)(nil).GetSegment), ctx, slug)
+}
+
+// GetSegmentMembers mocks base method.
+func (m *MockSegmentRepo) GetSegmentMembers(ctx context.Context, segmentId, afterUserId, limit int) ([]model.SegmentMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSegmentMembers", ctx, segmentId, afterUserId, limit)
+	ret0, _ := ret[0].([]model.SegmentMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegmentMembers indicates an expected call of GetSegmentMembers.
+func (mr *MockSegmentRepoMockRecorder) GetSegmentMembers(ctx, segmentId, afterUserId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegmentMembers", reflect.TypeOf((*MockSegmentRepo)(nil).GetSegmentMembers), ctx, segmentId, afterUserId, limit)
+}
+
+// GetSegmentUsers mocks base method.
+func (m *MockSegmentRepo) GetSegmentUsers(ctx context.Context, segmentId int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSegmentUsers", ctx, segmentId)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegmentUsers indicates an expected call of GetSegmentUsers.
+func (mr *MockSegmentRepoMockRecorder) GetSegmentUsers(ctx, segmentId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegmentUsers", reflect.TypeOf((*MockSegmentRepo)(nil).GetSegmentUsers), ctx, segmentId)
+}
+
+// GetSegments mocks base method.
+func (m *MockSegmentRepo) GetSegments(ctx context.Context, filter model.SegmentFilter) ([]model.Segment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSegments", ctx, filter)
+	ret0, _ := ret[0].([]model.Segment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegments indicates an expected call of GetSegments.
+func (mr *MockSegmentRepoMockRecorder) GetSegments(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegments", reflect.TypeOf((*MockSegmentRepo)(nil).GetSegments), ctx, filter)
+}
+
 // GetSegmentsBySlug mocks base method.
 func (m *MockSegmentRepo) GetSegmentsBySlug(ctx context.Context, slugs []string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -95,19 +236,109 @@ func (mr *MockSegmentRepoMockRecorder) GetSegmentsBySlug(ctx, slugs interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegmentsBySlug", reflect.TypeOf((*MockSegmentRepo)(nil).GetSegmentsBySlug), ctx, slugs)
 }
 
-// RemoveUsersFromDeletedSegment mocks base method.
-func (m *MockSegmentRepo) RemoveUsersFromDeletedSegment(ctx context.Context, sigmentId int) ([]int, error) {
+// GetSegmentsPage mocks base method.
+func (m *MockSegmentRepo) GetSegmentsPage(ctx context.Context, params model.SegmentsPageParams) ([]model.SegmentWithMembersCount, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveUsersFromDeletedSegment", ctx, sigmentId)
+	ret := m.ctrl.Call(m, "GetSegmentsPage", ctx, params)
+	ret0, _ := ret[0].([]model.SegmentWithMembersCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegmentsPage indicates an expected call of GetSegmentsPage.
+func (mr *MockSegmentRepoMockRecorder) GetSegmentsPage(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegmentsPage", reflect.TypeOf((*MockSegmentRepo)(nil).GetSegmentsPage), ctx, params)
+}
+
+// PurgeDeletedSegments mocks base method.
+func (m *MockSegmentRepo) PurgeDeletedSegments(ctx context.Context, deletedBefore time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedSegments", ctx, deletedBefore)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedSegments indicates an expected call of PurgeDeletedSegments.
+func (mr *MockSegmentRepoMockRecorder) PurgeDeletedSegments(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedSegments", reflect.TypeOf((*MockSegmentRepo)(nil).PurgeDeletedSegments), ctx, deletedBefore)
+}
+
+// RemovePercentUsersFromSegment mocks base method.
+func (m *MockSegmentRepo) RemovePercentUsersFromSegment(ctx context.Context, segmentId, fromPercent, toPercent int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePercentUsersFromSegment", ctx, segmentId, fromPercent, toPercent)
 	ret0, _ := ret[0].([]int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RemoveUsersFromDeletedSegment indicates an expected call of RemoveUsersFromDeletedSegment.
-func (mr *MockSegmentRepoMockRecorder) RemoveUsersFromDeletedSegment(ctx, sigmentId interface{}) *gomock.Call {
+// RemovePercentUsersFromSegment indicates an expected call of RemovePercentUsersFromSegment.
+func (mr *MockSegmentRepoMockRecorder) RemovePercentUsersFromSegment(ctx, segmentId, fromPercent, toPercent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUsersFromDeletedSegment", reflect.TypeOf((*MockSegmentRepo)(nil).RemoveUsersFromDeletedSegment), ctx, sigmentId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePercentUsersFromSegment", reflect.TypeOf((*MockSegmentRepo)(nil).RemovePercentUsersFromSegment), ctx, segmentId, fromPercent, toPercent)
+}
+
+// RenameSegment mocks base method.
+func (m *MockSegmentRepo) RenameSegment(ctx context.Context, segmentId int, newSlug string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameSegment", ctx, segmentId, newSlug)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameSegment indicates an expected call of RenameSegment.
+func (mr *MockSegmentRepoMockRecorder) RenameSegment(ctx, segmentId, newSlug interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameSegment", reflect.TypeOf((*MockSegmentRepo)(nil).RenameSegment), ctx, segmentId, newSlug)
+}
+
+// ResolveSegmentAliases mocks base method.
+func (m *MockSegmentRepo) ResolveSegmentAliases(ctx context.Context, slugs []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveSegmentAliases", ctx, slugs)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveSegmentAliases indicates an expected call of ResolveSegmentAliases.
+func (mr *MockSegmentRepoMockRecorder) ResolveSegmentAliases(ctx, slugs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveSegmentAliases", reflect.TypeOf((*MockSegmentRepo)(nil).ResolveSegmentAliases), ctx, slugs)
+}
+
+// RestoreSegment mocks base method.
+func (m *MockSegmentRepo) RestoreSegment(ctx context.Context, slug string, deletedAfter time.Time) (*int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreSegment", ctx, slug, deletedAfter)
+	ret0, _ := ret[0].(*int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreSegment indicates an expected call of RestoreSegment.
+func (mr *MockSegmentRepoMockRecorder) RestoreSegment(ctx, slug, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSegment", reflect.TypeOf((*MockSegmentRepo)(nil).RestoreSegment), ctx, slug, deletedAfter)
+}
+
+// UpdateSegment mocks base method.
+func (m *MockSegmentRepo) UpdateSegment(ctx context.Context, slug string, segmentData model.UpdateSegment) (*model.Segment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSegment", ctx, slug, segmentData)
+	ret0, _ := ret[0].(*model.Segment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSegment indicates an expected call of UpdateSegment.
+func (mr *MockSegmentRepoMockRecorder) UpdateSegment(ctx, slug, segmentData interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSegment", reflect.TypeOf((*MockSegmentRepo)(nil).UpdateSegment), ctx, slug, segmentData)
 }
 
 // MockHistoryRepo is a mock of HistoryRepo interface.
@@ -133,34 +364,126 @@ func (m *MockHistoryRepo) EXPECT() *MockHistoryRepoMockRecorder {
 	return m.recorder
 }
 
-// DeleteExpiredUserSegments mocks base method.
-func (m *MockHistoryRepo) DeleteExpiredUserSegments(ctx context.Context) ([]model.UsersSegments, error) {
+// ActivateScheduledUserSegments mocks base method.
+func (m *MockHistoryRepo) ActivateScheduledUserSegments(ctx context.Context, limit int) ([]model.UsersSegments, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteExpiredUserSegments", ctx)
+	ret := m.ctrl.Call(m, "ActivateScheduledUserSegments", ctx, limit)
 	ret0, _ := ret[0].([]model.UsersSegments)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ActivateScheduledUserSegments indicates an expected call of ActivateScheduledUserSegments.
+func (mr *MockHistoryRepoMockRecorder) ActivateScheduledUserSegments(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActivateScheduledUserSegments", reflect.TypeOf((*MockHistoryRepo)(nil).ActivateScheduledUserSegments), ctx, limit)
+}
+
+// CountHistory mocks base method.
+func (m *MockHistoryRepo) CountHistory(ctx context.Context, filter model.HistoryFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountHistory", ctx, filter)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// CountHistory indicates an expected call of CountHistory.
+func (mr *MockHistoryRepoMockRecorder) CountHistory(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountHistory", reflect.TypeOf((*MockHistoryRepo)(nil).CountHistory), ctx, filter)
+}
+
+// DeleteExpiredUserSegments mocks base method.
+func (m *MockHistoryRepo) DeleteExpiredUserSegments(ctx context.Context, limit int) ([]model.UsersSegments, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredUserSegments", ctx, limit)
+	ret0, _ := ret[0].([]model.UsersSegments)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // DeleteExpiredUserSegments indicates an expected call of DeleteExpiredUserSegments.
-func (mr *MockHistoryRepoMockRecorder) DeleteExpiredUserSegments(ctx interface{}) *gomock.Call {
+func (mr *MockHistoryRepoMockRecorder) DeleteExpiredUserSegments(ctx, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredUserSegments", reflect.TypeOf((*MockHistoryRepo)(nil).DeleteExpiredUserSegments), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredUserSegments", reflect.TypeOf((*MockHistoryRepo)(nil).DeleteExpiredUserSegments), ctx, limit)
 }
 
 // GetHistory mocks base method.
-func (m *MockHistoryRepo) GetHistory(ctx context.Context, month, year, userId int) ([]model.History, error) {
+func (m *MockHistoryRepo) GetHistory(ctx context.Context, filter model.HistoryFilter) ([]model.History, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetHistory", ctx, month, year, userId)
+	ret := m.ctrl.Call(m, "GetHistory", ctx, filter)
 	ret0, _ := ret[0].([]model.History)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetHistory indicates an expected call of GetHistory.
-func (mr *MockHistoryRepoMockRecorder) GetHistory(ctx, month, year, userId interface{}) *gomock.Call {
+func (mr *MockHistoryRepoMockRecorder) GetHistory(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockHistoryRepo)(nil).GetHistory), ctx, filter)
+}
+
+// GetMembershipMismatches mocks base method.
+func (m *MockHistoryRepo) GetMembershipMismatches(ctx context.Context, limit int) ([]model.MembershipMismatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembershipMismatches", ctx, limit)
+	ret0, _ := ret[0].([]model.MembershipMismatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembershipMismatches indicates an expected call of GetMembershipMismatches.
+func (mr *MockHistoryRepoMockRecorder) GetMembershipMismatches(ctx, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockHistoryRepo)(nil).GetHistory), ctx, month, year, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembershipMismatches", reflect.TypeOf((*MockHistoryRepo)(nil).GetMembershipMismatches), ctx, limit)
+}
+
+// GetSegmentUsersAt mocks base method.
+func (m *MockHistoryRepo) GetSegmentUsersAt(ctx context.Context, slug string, at time.Time) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSegmentUsersAt", ctx, slug, at)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSegmentUsersAt indicates an expected call of GetSegmentUsersAt.
+func (mr *MockHistoryRepoMockRecorder) GetSegmentUsersAt(ctx, slug, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSegmentUsersAt", reflect.TypeOf((*MockHistoryRepo)(nil).GetSegmentUsersAt), ctx, slug, at)
+}
+
+// GetTopChurnSegments mocks base method.
+func (m *MockHistoryRepo) GetTopChurnSegments(ctx context.Context, filter model.HistoryFilter, limit int) ([]model.SegmentChurn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopChurnSegments", ctx, filter, limit)
+	ret0, _ := ret[0].([]model.SegmentChurn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopChurnSegments indicates an expected call of GetTopChurnSegments.
+func (mr *MockHistoryRepoMockRecorder) GetTopChurnSegments(ctx, filter, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopChurnSegments", reflect.TypeOf((*MockHistoryRepo)(nil).GetTopChurnSegments), ctx, filter, limit)
+}
+
+// GetUserSegmentsAt mocks base method.
+func (m *MockHistoryRepo) GetUserSegmentsAt(ctx context.Context, userId int, at time.Time) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSegmentsAt", ctx, userId, at)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSegmentsAt indicates an expected call of GetUserSegmentsAt.
+func (mr *MockHistoryRepoMockRecorder) GetUserSegmentsAt(ctx, userId, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSegmentsAt", reflect.TypeOf((*MockHistoryRepo)(nil).GetUserSegmentsAt), ctx, userId, at)
 }
 
 // RecordMultipleUsersToHistory mocks base method.
@@ -177,6 +500,20 @@ func (mr *MockHistoryRepoMockRecorder) RecordMultipleUsersToHistory(ctx, history
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordMultipleUsersToHistory", reflect.TypeOf((*MockHistoryRepo)(nil).RecordMultipleUsersToHistory), ctx, historyData)
 }
 
+// RecordSegmentEvent mocks base method.
+func (m *MockHistoryRepo) RecordSegmentEvent(ctx context.Context, event model.SegmentEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordSegmentEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordSegmentEvent indicates an expected call of RecordSegmentEvent.
+func (mr *MockHistoryRepoMockRecorder) RecordSegmentEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordSegmentEvent", reflect.TypeOf((*MockHistoryRepo)(nil).RecordSegmentEvent), ctx, event)
+}
+
 // RecordUserMultipleSegmentsToHistory mocks base method.
 func (m *MockHistoryRepo) RecordUserMultipleSegmentsToHistory(ctx context.Context, historyData model.HistoryDataMultipleSegments) error {
 	m.ctrl.T.Helper()
@@ -191,6 +528,300 @@ func (mr *MockHistoryRepoMockRecorder) RecordUserMultipleSegmentsToHistory(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordUserMultipleSegmentsToHistory", reflect.TypeOf((*MockHistoryRepo)(nil).RecordUserMultipleSegmentsToHistory), ctx, historyData)
 }
 
+// StreamHistory mocks base method.
+func (m *MockHistoryRepo) StreamHistory(ctx context.Context, filter model.HistoryFilter, fn func(model.History) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamHistory", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamHistory indicates an expected call of StreamHistory.
+func (mr *MockHistoryRepoMockRecorder) StreamHistory(ctx, filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamHistory", reflect.TypeOf((*MockHistoryRepo)(nil).StreamHistory), ctx, filter, fn)
+}
+
+// StreamSegmentDailyStats mocks base method.
+func (m *MockHistoryRepo) StreamSegmentDailyStats(ctx context.Context, filter model.HistoryFilter, fn func(model.SegmentDailyStats) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamSegmentDailyStats", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamSegmentDailyStats indicates an expected call of StreamSegmentDailyStats.
+func (mr *MockHistoryRepoMockRecorder) StreamSegmentDailyStats(ctx, filter, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamSegmentDailyStats", reflect.TypeOf((*MockHistoryRepo)(nil).StreamSegmentDailyStats), ctx, filter, fn)
+}
+
+// MockReportJobRepo is a mock of ReportJobRepo interface.
+type MockReportJobRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportJobRepoMockRecorder
+}
+
+// MockReportJobRepoMockRecorder is the mock recorder for MockReportJobRepo.
+type MockReportJobRepoMockRecorder struct {
+	mock *MockReportJobRepo
+}
+
+// NewMockReportJobRepo creates a new mock instance.
+func NewMockReportJobRepo(ctrl *gomock.Controller) *MockReportJobRepo {
+	mock := &MockReportJobRepo{ctrl: ctrl}
+	mock.recorder = &MockReportJobRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportJobRepo) EXPECT() *MockReportJobRepoMockRecorder {
+	return m.recorder
+}
+
+// CancelReportJob mocks base method.
+func (m *MockReportJobRepo) CancelReportJob(ctx context.Context, id int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelReportJob", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelReportJob indicates an expected call of CancelReportJob.
+func (mr *MockReportJobRepoMockRecorder) CancelReportJob(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReportJob", reflect.TypeOf((*MockReportJobRepo)(nil).CancelReportJob), ctx, id)
+}
+
+// ClaimReportJob mocks base method.
+func (m *MockReportJobRepo) ClaimReportJob(ctx context.Context, staleAfter time.Duration, maxAttempts int) (*model.ReportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimReportJob", ctx, staleAfter, maxAttempts)
+	ret0, _ := ret[0].(*model.ReportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimReportJob indicates an expected call of ClaimReportJob.
+func (mr *MockReportJobRepoMockRecorder) ClaimReportJob(ctx, staleAfter, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimReportJob", reflect.TypeOf((*MockReportJobRepo)(nil).ClaimReportJob), ctx, staleAfter, maxAttempts)
+}
+
+// CompleteReportJob mocks base method.
+func (m *MockReportJobRepo) CompleteReportJob(ctx context.Context, id int64, fileName string, rowsWritten int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteReportJob", ctx, id, fileName, rowsWritten)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteReportJob indicates an expected call of CompleteReportJob.
+func (mr *MockReportJobRepoMockRecorder) CompleteReportJob(ctx, id, fileName, rowsWritten interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteReportJob", reflect.TypeOf((*MockReportJobRepo)(nil).CompleteReportJob), ctx, id, fileName, rowsWritten)
+}
+
+// CreateReportJob mocks base method.
+func (m *MockReportJobRepo) CreateReportJob(ctx context.Context, params model.ReportJobParams) (*model.ReportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReportJob", ctx, params)
+	ret0, _ := ret[0].(*model.ReportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReportJob indicates an expected call of CreateReportJob.
+func (mr *MockReportJobRepoMockRecorder) CreateReportJob(ctx, params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReportJob", reflect.TypeOf((*MockReportJobRepo)(nil).CreateReportJob), ctx, params)
+}
+
+// FailReportJob mocks base method.
+func (m *MockReportJobRepo) FailReportJob(ctx context.Context, id int64, errMessage string, retryAt *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailReportJob", ctx, id, errMessage, retryAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailReportJob indicates an expected call of FailReportJob.
+func (mr *MockReportJobRepoMockRecorder) FailReportJob(ctx, id, errMessage, retryAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailReportJob", reflect.TypeOf((*MockReportJobRepo)(nil).FailReportJob), ctx, id, errMessage, retryAt)
+}
+
+// GetReportJob mocks base method.
+func (m *MockReportJobRepo) GetReportJob(ctx context.Context, id int64) (*model.ReportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReportJob", ctx, id)
+	ret0, _ := ret[0].(*model.ReportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReportJob indicates an expected call of GetReportJob.
+func (mr *MockReportJobRepoMockRecorder) GetReportJob(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportJob", reflect.TypeOf((*MockReportJobRepo)(nil).GetReportJob), ctx, id)
+}
+
+// SetReportJobRowsTotal mocks base method.
+func (m *MockReportJobRepo) SetReportJobRowsTotal(ctx context.Context, id, rowsTotal int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReportJobRowsTotal", ctx, id, rowsTotal)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetReportJobRowsTotal indicates an expected call of SetReportJobRowsTotal.
+func (mr *MockReportJobRepoMockRecorder) SetReportJobRowsTotal(ctx, id, rowsTotal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReportJobRowsTotal", reflect.TypeOf((*MockReportJobRepo)(nil).SetReportJobRowsTotal), ctx, id, rowsTotal)
+}
+
+// UpdateReportJobProgress mocks base method.
+func (m *MockReportJobRepo) UpdateReportJobProgress(ctx context.Context, id, rowsWritten int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateReportJobProgress", ctx, id, rowsWritten)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateReportJobProgress indicates an expected call of UpdateReportJobProgress.
+func (mr *MockReportJobRepoMockRecorder) UpdateReportJobProgress(ctx, id, rowsWritten interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateReportJobProgress", reflect.TypeOf((*MockReportJobRepo)(nil).UpdateReportJobProgress), ctx, id, rowsWritten)
+}
+
+// MockReportFileRepo is a mock of ReportFileRepo interface.
+type MockReportFileRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportFileRepoMockRecorder
+}
+
+// MockReportFileRepoMockRecorder is the mock recorder for MockReportFileRepo.
+type MockReportFileRepoMockRecorder struct {
+	mock *MockReportFileRepo
+}
+
+// NewMockReportFileRepo creates a new mock instance.
+func NewMockReportFileRepo(ctrl *gomock.Controller) *MockReportFileRepo {
+	mock := &MockReportFileRepo{ctrl: ctrl}
+	mock.recorder = &MockReportFileRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportFileRepo) EXPECT() *MockReportFileRepoMockRecorder {
+	return m.recorder
+}
+
+// AddReportFilePart mocks base method.
+func (m *MockReportFileRepo) AddReportFilePart(ctx context.Context, name string, part int, data []byte) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReportFilePart", ctx, name, part, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddReportFilePart indicates an expected call of AddReportFilePart.
+func (mr *MockReportFileRepoMockRecorder) AddReportFilePart(ctx, name, part, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReportFilePart", reflect.TypeOf((*MockReportFileRepo)(nil).AddReportFilePart), ctx, name, part, data)
+}
+
+// CompleteReportFile mocks base method.
+func (m *MockReportFileRepo) CompleteReportFile(ctx context.Context, name string, size int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteReportFile", ctx, name, size)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteReportFile indicates an expected call of CompleteReportFile.
+func (mr *MockReportFileRepoMockRecorder) CompleteReportFile(ctx, name, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteReportFile", reflect.TypeOf((*MockReportFileRepo)(nil).CompleteReportFile), ctx, name, size)
+}
+
+// CreateReportFile mocks base method.
+func (m *MockReportFileRepo) CreateReportFile(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReportFile", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateReportFile indicates an expected call of CreateReportFile.
+func (mr *MockReportFileRepoMockRecorder) CreateReportFile(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReportFile", reflect.TypeOf((*MockReportFileRepo)(nil).CreateReportFile), ctx, name)
+}
+
+// DeleteReportFile mocks base method.
+func (m *MockReportFileRepo) DeleteReportFile(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReportFile", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReportFile indicates an expected call of DeleteReportFile.
+func (mr *MockReportFileRepoMockRecorder) DeleteReportFile(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReportFile", reflect.TypeOf((*MockReportFileRepo)(nil).DeleteReportFile), ctx, name)
+}
+
+// DeleteReportFilesUpdatedBefore mocks base method.
+func (m *MockReportFileRepo) DeleteReportFilesUpdatedBefore(ctx context.Context, updatedBefore time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReportFilesUpdatedBefore", ctx, updatedBefore)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteReportFilesUpdatedBefore indicates an expected call of DeleteReportFilesUpdatedBefore.
+func (mr *MockReportFileRepoMockRecorder) DeleteReportFilesUpdatedBefore(ctx, updatedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReportFilesUpdatedBefore", reflect.TypeOf((*MockReportFileRepo)(nil).DeleteReportFilesUpdatedBefore), ctx, updatedBefore)
+}
+
+// GetReportFile mocks base method.
+func (m *MockReportFileRepo) GetReportFile(ctx context.Context, name string) (*model.ReportFile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReportFile", ctx, name)
+	ret0, _ := ret[0].(*model.ReportFile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReportFile indicates an expected call of GetReportFile.
+func (mr *MockReportFileRepoMockRecorder) GetReportFile(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReportFile", reflect.TypeOf((*MockReportFileRepo)(nil).GetReportFile), ctx, name)
+}
+
+// StreamReportFile mocks base method.
+func (m *MockReportFileRepo) StreamReportFile(ctx context.Context, name string, fn func([]byte) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamReportFile", ctx, name, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamReportFile indicates an expected call of StreamReportFile.
+func (mr *MockReportFileRepoMockRecorder) StreamReportFile(ctx, name, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamReportFile", reflect.TypeOf((*MockReportFileRepo)(nil).StreamReportFile), ctx, name, fn)
+}
+
 // MockUserRepo is a mock of UserRepo interface.
 type MockUserRepo struct {
 	ctrl     *gomock.Controller
@@ -215,18 +846,79 @@ func (m *MockUserRepo) EXPECT() *MockUserRepoMockRecorder {
 }
 
 // AddUserToMultipleSegments mocks base method.
-func (m *MockUserRepo) AddUserToMultipleSegments(ctx context.Context, expirationTime *time.Time, segmentsSlugs []string, userId int) ([]string, error) {
+func (m *MockUserRepo) AddUserToMultipleSegments(ctx context.Context, userId int, segments []model.SegmentExpiration, upsert bool) ([]string, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUserToMultipleSegments", ctx, userId, segments, upsert)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddUserToMultipleSegments indicates an expected call of AddUserToMultipleSegments.
+func (mr *MockUserRepoMockRecorder) AddUserToMultipleSegments(ctx, userId, segments, upsert interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserToMultipleSegments", reflect.TypeOf((*MockUserRepo)(nil).AddUserToMultipleSegments), ctx, userId, segments, upsert)
+}
+
+// AddUserToPercentSegments mocks base method.
+func (m *MockUserRepo) AddUserToPercentSegments(ctx context.Context, userId int) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddUserToMultipleSegments", ctx, expirationTime, segmentsSlugs, userId)
+	ret := m.ctrl.Call(m, "AddUserToPercentSegments", ctx, userId)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddUserToMultipleSegments indicates an expected call of AddUserToMultipleSegments.
-func (mr *MockUserRepoMockRecorder) AddUserToMultipleSegments(ctx, expirationTime, segmentsSlugs, userId interface{}) *gomock.Call {
+// AddUserToPercentSegments indicates an expected call of AddUserToPercentSegments.
+func (mr *MockUserRepoMockRecorder) AddUserToPercentSegments(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserToPercentSegments", reflect.TypeOf((*MockUserRepo)(nil).AddUserToPercentSegments), ctx, userId)
+}
+
+// CreateUser mocks base method.
+func (m *MockUserRepo) CreateUser(ctx context.Context, userId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserRepoMockRecorder) CreateUser(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepo)(nil).CreateUser), ctx, userId)
+}
+
+// CreateUsersWithAutoJoin mocks base method.
+func (m *MockUserRepo) CreateUsersWithAutoJoin(ctx context.Context, userIds []int) (map[int][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUsersWithAutoJoin", ctx, userIds)
+	ret0, _ := ret[0].(map[int][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUsersWithAutoJoin indicates an expected call of CreateUsersWithAutoJoin.
+func (mr *MockUserRepoMockRecorder) CreateUsersWithAutoJoin(ctx, userIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUsersWithAutoJoin", reflect.TypeOf((*MockUserRepo)(nil).CreateUsersWithAutoJoin), ctx, userIds)
+}
+
+// DeleteUser mocks base method.
+func (m *MockUserRepo) DeleteUser(ctx context.Context, userId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockUserRepoMockRecorder) DeleteUser(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserToMultipleSegments", reflect.TypeOf((*MockUserRepo)(nil).AddUserToMultipleSegments), ctx, expirationTime, segmentsSlugs, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepo)(nil).DeleteUser), ctx, userId)
 }
 
 // GetActiveUserSegments mocks base method.
@@ -244,19 +936,49 @@ func (mr *MockUserRepoMockRecorder) GetActiveUserSegments(ctx, userId interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveUserSegments", reflect.TypeOf((*MockUserRepo)(nil).GetActiveUserSegments), ctx, userId)
 }
 
+// GetActiveUsersSegments mocks base method.
+func (m *MockUserRepo) GetActiveUsersSegments(ctx context.Context, userIds []int) (map[int][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveUsersSegments", ctx, userIds)
+	ret0, _ := ret[0].(map[int][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveUsersSegments indicates an expected call of GetActiveUsersSegments.
+func (mr *MockUserRepoMockRecorder) GetActiveUsersSegments(ctx, userIds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveUsersSegments", reflect.TypeOf((*MockUserRepo)(nil).GetActiveUsersSegments), ctx, userIds)
+}
+
 // GetPercentUsers mocks base method.
-func (m *MockUserRepo) GetPercentUsers(ctx context.Context, usersPercent int) ([]int, error) {
+func (m *MockUserRepo) GetPercentUsers(ctx context.Context, segmentId, usersPercent int) ([]int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPercentUsers", ctx, usersPercent)
+	ret := m.ctrl.Call(m, "GetPercentUsers", ctx, segmentId, usersPercent)
 	ret0, _ := ret[0].([]int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetPercentUsers indicates an expected call of GetPercentUsers.
-func (mr *MockUserRepoMockRecorder) GetPercentUsers(ctx, usersPercent interface{}) *gomock.Call {
+func (mr *MockUserRepoMockRecorder) GetPercentUsers(ctx, segmentId, usersPercent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPercentUsers", reflect.TypeOf((*MockUserRepo)(nil).GetPercentUsers), ctx, usersPercent)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPercentUsers", reflect.TypeOf((*MockUserRepo)(nil).GetPercentUsers), ctx, segmentId, usersPercent)
+}
+
+// RemoveUserFromAllSegments mocks base method.
+func (m *MockUserRepo) RemoveUserFromAllSegments(ctx context.Context, userId int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserFromAllSegments", ctx, userId)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUserFromAllSegments indicates an expected call of RemoveUserFromAllSegments.
+func (mr *MockUserRepoMockRecorder) RemoveUserFromAllSegments(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserFromAllSegments", reflect.TypeOf((*MockUserRepo)(nil).RemoveUserFromAllSegments), ctx, userId)
 }
 
 // RemoveUserFromMultipleSegments mocks base method.
@@ -273,3 +995,18 @@ func (mr *MockUserRepoMockRecorder) RemoveUserFromMultipleSegments(ctx, segments
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserFromMultipleSegments", reflect.TypeOf((*MockUserRepo)(nil).RemoveUserFromMultipleSegments), ctx, segmentsSlugsToRemove, userId)
 }
+
+// UserExists mocks base method.
+func (m *MockUserRepo) UserExists(ctx context.Context, userId int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserExists", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserExists indicates an expected call of UserExists.
+func (mr *MockUserRepoMockRecorder) UserExists(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserExists", reflect.TypeOf((*MockUserRepo)(nil).UserExists), ctx, userId)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
+	"github.com/google/uuid"
+)
+
+const (
+	// reportFilePartSize is the size of the parts report files are saved in.
+	reportFilePartSize = 1 << 20
+	// reportFileDeleteTimeout bounds deleting an unfinished file after its context is done.
+	reportFileDeleteTimeout = 5 * time.Second
+)
+
+var errReportFileDeleted = errors.New("report file was deleted while it was written")
+
+// createReportFile stores the file filled by write and returns the file name. The file is saved in parts as it is written,
+// and is deleted if write fails.
+func (s *HistoryService) createReportFile(ctx context.Context, format string, write func(w io.Writer) error) (string, error) {
+	w := &reportFileWriter{
+		ctx:      ctx,
+		repo:     s.reportFileRepo,
+		fileName: uuid.NewString() + report.Extension(format),
+	}
+
+	err := write(w)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		if w.created {
+			s.deleteReportFile(ctx, w.fileName)
+		}
+		return "", err
+	}
+
+	return w.fileName, nil
+}
+
+// deleteReportFile deletes a file that will not be linked to. The file is deleted even if ctx is done, and a file that
+// cannot be deleted is left to DeleteExpiredReportFiles.
+func (s *HistoryService) deleteReportFile(ctx context.Context, fileName string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportFileDeleteTimeout)
+	defer cancel()
+
+	_ = s.reportFileRepo.DeleteReportFile(ctx, fileName)
+}
+
+// reportFileWriter saves what is written to it as parts of reportFilePartSize bytes. The file is created with its first part,
+// so a report that fails before writing anything does not touch the database.
+type reportFileWriter struct {
+	ctx      context.Context
+	repo     ReportFileRepo
+	fileName string
+	created  bool
+	buf      []byte
+	parts    int
+	size     int64
+}
+
+func (w *reportFileWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		if len(w.buf) == reportFilePartSize {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+
+		n := min(len(p)-written, reportFilePartSize-len(w.buf))
+		w.buf = append(w.buf, p[written:written+n]...)
+		written += n
+		w.size += int64(n)
+	}
+
+	return written, nil
+}
+
+// Close saves the rest of the file and makes it readable.
+func (w *reportFileWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	completed, err := w.repo.CompleteReportFile(w.ctx, w.fileName, w.size)
+	if err != nil {
+		return err
+	}
+	if !completed {
+		return errReportFileDeleted
+	}
+
+	return nil
+}
+
+func (w *reportFileWriter) flush() error {
+	if !w.created {
+		if err := w.repo.CreateReportFile(w.ctx, w.fileName); err != nil {
+			return err
+		}
+		w.created = true
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	added, err := w.repo.AddReportFilePart(w.ctx, w.fileName, w.parts, w.buf)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errReportFileDeleted
+	}
+
+	w.parts++
+	w.buf = w.buf[:0]
+
+	return nil
+}
+
+// reportLink returns a link to the report file signed until expiresAt.
+func (s *HistoryService) reportLink(fileName string, expiresAt time.Time) string {
+	link := url.Values{}
+	link.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	link.Set("signature", s.signReportLink(fileName, expiresAt.Unix()))
+
+	return s.cfg.ServerEndpoint + reportFilesPath + fileName + "?" + link.Encode()
+}
+
+// GetReportFile checks the signed link to a report file and returns the file.
+func (s *HistoryService) GetReportFile(ctx context.Context, fileName string, expiresAt int64, signature string) (model.ReportFile, error) {
+	expectedSignature := s.signReportLink(fileName, expiresAt)
+	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+		return model.ReportFile{}, ErrInvalidReportLink
+	}
+
+	if time.Now().Unix() > expiresAt {
+		return model.ReportFile{}, ErrReportLinkExpired
+	}
+
+	file, err := s.reportFileRepo.GetReportFile(ctx, fileName)
+	if err != nil {
+		return model.ReportFile{}, err
+	}
+	if file == nil {
+		return model.ReportFile{}, ErrReportLinkExpired
+	}
+
+	return *file, nil
+}
+
+// WriteReportFile writes the content of the report file to w.
+func (s *HistoryService) WriteReportFile(ctx context.Context, fileName string, w io.Writer) error {
+	return s.reportFileRepo.StreamReportFile(ctx, fileName, func(data []byte) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// DeleteExpiredReportFiles deletes report files whose links have expired, and files abandoned while being written.
+func (s *HistoryService) DeleteExpiredReportFiles(ctx context.Context) (int, error) {
+	return s.reportFileRepo.DeleteReportFilesUpdatedBefore(ctx, time.Now().Add(-s.cfg.LinkTTL))
+}
+
+func (s *HistoryService) signReportLink(fileName string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, s.cfg.LinkSecret)
+	mac.Write([]byte(fileName + ":" + strconv.FormatInt(expiresAt, 10)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
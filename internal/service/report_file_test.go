@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/config"
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHistoryService_createReportFile(t *testing.T) {
+	repoError := errors.New("error from repo")
+	content := bytes.Repeat([]byte("0123456789"), reportFilePartSize/10+1)
+	writeContent := func(w io.Writer) error {
+		// written in uneven pieces, so that some of them are split between parts
+		for rest := content; len(rest) > 0; {
+			n := min(len(rest), 3000)
+			if _, err := w.Write(rest[:n]); err != nil {
+				return err
+			}
+			rest = rest[n:]
+		}
+		return nil
+	}
+	tests := []struct {
+		name                 string
+		write                func(w io.Writer) error
+		reportFileRepoBehave func(repository *MockReportFileRepo)
+		wantErr              error
+	}{
+		{
+			name:  "file is saved in parts",
+			write: writeContent,
+			reportFileRepoBehave: func(repository *MockReportFileRepo) {
+				gomock.InOrder(
+					repository.EXPECT().CreateReportFile(gomock.Any(), gomock.Any()).Return(nil),
+					repository.EXPECT().AddReportFilePart(gomock.Any(), gomock.Any(), 0, content[:reportFilePartSize]).Return(true, nil),
+					repository.EXPECT().AddReportFilePart(gomock.Any(), gomock.Any(), 1, content[reportFilePartSize:]).Return(true, nil),
+					repository.EXPECT().CompleteReportFile(gomock.Any(), gomock.Any(), int64(len(content))).Return(true, nil),
+				)
+			},
+			wantErr: nil,
+		},
+		{
+			name: "nothing is saved if write fails before writing",
+			write: func(io.Writer) error {
+				return ErrNoDataAvailable
+			},
+			wantErr: ErrNoDataAvailable,
+		},
+		{
+			name: "file is deleted if write fails",
+			write: func(w io.Writer) error {
+				if err := writeContent(w); err != nil {
+					return err
+				}
+				return repoError
+			},
+			reportFileRepoBehave: func(repository *MockReportFileRepo) {
+				repository.EXPECT().CreateReportFile(gomock.Any(), gomock.Any()).Return(nil)
+				repository.EXPECT().AddReportFilePart(gomock.Any(), gomock.Any(), 0, gomock.Any()).Return(true, nil)
+				repository.EXPECT().DeleteReportFile(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantErr: repoError,
+		},
+		{
+			name:  "file is deleted while it is written",
+			write: writeContent,
+			reportFileRepoBehave: func(repository *MockReportFileRepo) {
+				repository.EXPECT().CreateReportFile(gomock.Any(), gomock.Any()).Return(nil)
+				repository.EXPECT().AddReportFilePart(gomock.Any(), gomock.Any(), 0, gomock.Any()).Return(false, nil)
+				repository.EXPECT().DeleteReportFile(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantErr: errReportFileDeleted,
+		},
+		{
+			name:  "error from CompleteReportFile()",
+			write: writeContent,
+			reportFileRepoBehave: func(repository *MockReportFileRepo) {
+				repository.EXPECT().CreateReportFile(gomock.Any(), gomock.Any()).Return(nil)
+				repository.EXPECT().AddReportFilePart(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil).Times(2)
+				repository.EXPECT().CompleteReportFile(gomock.Any(), gomock.Any(), int64(len(content))).Return(false, repoError)
+				repository.EXPECT().DeleteReportFile(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantErr: repoError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockReportFileRepo := NewMockReportFileRepo(ctrl)
+			if tt.reportFileRepoBehave != nil {
+				tt.reportFileRepoBehave(mockReportFileRepo)
+			}
+			s := &HistoryService{
+				reportFileRepo: mockReportFileRepo,
+			}
+
+			fileName, err := s.createReportFile(context.Background(), "csv", tt.write)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("HistoryService.createReportFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && fileName == "" {
+				t.Errorf("HistoryService.createReportFile() returned an empty file name")
+			}
+		})
+	}
+}
+
+func TestHistoryService_GetReportFile(t *testing.T) {
+	cfg := config.ReportConfig{
+		LinkSecret: []byte("secret"),
+		LinkTTL:    time.Hour,
+	}
+	signer := &HistoryService{cfg: cfg}
+	fileName := "0e666515-c657-4e49-b195-431c682563f7.csv"
+	file := &model.ReportFile{Name: fileName, Size: 10}
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	expiredAt := time.Now().Add(-time.Hour).Unix()
+	tests := []struct {
+		name                 string
+		fileName             string
+		expiresAt            int64
+		signature            string
+		reportFileRepoBehave func(repository *MockReportFileRepo)
+		want                 model.ReportFile
+		wantErr              error
+	}{
+		{
+			name:      "success",
+			fileName:  fileName,
+			expiresAt: expiresAt,
+			signature: signer.signReportLink(fileName, expiresAt),
+			reportFileRepoBehave: func(repository *MockReportFileRepo) {
+				repository.EXPECT().GetReportFile(gomock.Any(), fileName).Return(file, nil)
+			},
+			want:    *file,
+			wantErr: nil,
+		},
+		{
+			name:      "wrong signature",
+			fileName:  fileName,
+			expiresAt: expiresAt,
+			signature: "wrong",
+			wantErr:   ErrInvalidReportLink,
+		},
+		{
+			name:      "signature of another file",
+			fileName:  "another.csv",
+			expiresAt: expiresAt,
+			signature: signer.signReportLink(fileName, expiresAt),
+			wantErr:   ErrInvalidReportLink,
+		},
+		{
+			name:      "prolonged link",
+			fileName:  fileName,
+			expiresAt: expiresAt,
+			signature: signer.signReportLink(fileName, expiredAt),
+			wantErr:   ErrInvalidReportLink,
+		},
+		{
+			name:      "expired link",
+			fileName:  fileName,
+			expiresAt: expiredAt,
+			signature: signer.signReportLink(fileName, expiredAt),
+			wantErr:   ErrReportLinkExpired,
+		},
+		{
+			name:      "file was deleted",
+			fileName:  fileName,
+			expiresAt: expiresAt,
+			signature: signer.signReportLink(fileName, expiresAt),
+			reportFileRepoBehave: func(repository *MockReportFileRepo) {
+				repository.EXPECT().GetReportFile(gomock.Any(), fileName).Return(nil, nil)
+			},
+			wantErr: ErrReportLinkExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockReportFileRepo := NewMockReportFileRepo(ctrl)
+			if tt.reportFileRepoBehave != nil {
+				tt.reportFileRepoBehave(mockReportFileRepo)
+			}
+			s := &HistoryService{
+				reportFileRepo: mockReportFileRepo,
+				cfg:            cfg,
+			}
+
+			got, err := s.GetReportFile(context.Background(), tt.fileName, tt.expiresAt, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("HistoryService.GetReportFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryService.GetReportFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
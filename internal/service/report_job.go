@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
+)
+
+const (
+	// reportJobStaleHeartbeats is how many heartbeats a running job may miss before another worker takes it over.
+	reportJobStaleHeartbeats = 3
+	reportJobRetryDelay      = 30 * time.Second
+	// reportJobFailTimeout bounds recording the error of a job, which also happens when the worker is stopping.
+	reportJobFailTimeout = 5 * time.Second
+)
+
+var errReportJobCancelled = errors.New("report job cancelled")
+
+// CreateReportJob queues generation of a report file. Workers pick the job up with ProcessReportJob.
+func (s *HistoryService) CreateReportJob(ctx context.Context, params model.ReportJobParams) (model.ReportJobState, error) {
+	if _, err := reportJobOptions(params); err != nil {
+		return model.ReportJobState{}, err
+	}
+	if params.Operation != "" && !params.Operation.IsValid() {
+		return model.ReportJobState{}, ErrUnknownOperation
+	}
+	if params.Kind != "" && params.Kind != model.ReportKindHistory && !isSegmentReportKind(params.Kind) {
+		return model.ReportJobState{}, ErrUnknownReportKind
+	}
+
+	job, err := s.reportJobRepo.CreateReportJob(ctx, params)
+	if err != nil {
+		return model.ReportJobState{}, err
+	}
+
+	return s.reportJobState(*job), nil
+}
+
+func (s *HistoryService) GetReportJob(ctx context.Context, id int64) (model.ReportJobState, error) {
+	job, err := s.reportJobRepo.GetReportJob(ctx, id)
+	if err != nil {
+		return model.ReportJobState{}, err
+	}
+
+	if job == nil {
+		return model.ReportJobState{}, ErrReportJobDoesNotExist
+	}
+
+	return s.reportJobState(*job), nil
+}
+
+// CancelReportJob stops a pending or running job. A running job is stopped at its next heartbeat.
+func (s *HistoryService) CancelReportJob(ctx context.Context, id int64) error {
+	cancelled, err := s.reportJobRepo.CancelReportJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if cancelled {
+		return nil
+	}
+
+	job, err := s.reportJobRepo.GetReportJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if job == nil {
+		return ErrReportJobDoesNotExist
+	}
+
+	return ErrReportJobFinished
+}
+
+// ProcessReportJob claims one due report job and generates its file. It reports false if there was no job to process.
+// Failed jobs are retried with a growing delay until they run out of attempts, business errors are not retried.
+func (s *HistoryService) ProcessReportJob(ctx context.Context) (bool, error) {
+	job, err := s.reportJobRepo.ClaimReportJob(ctx, reportJobStaleHeartbeats*s.cfg.JobHeartbeat, s.cfg.JobMaxAttempts)
+	if err != nil {
+		return false, err
+	}
+
+	if job == nil {
+		return false, nil
+	}
+
+	err = s.runReportJob(ctx, job)
+	if err == nil || errors.Is(err, errReportJobCancelled) {
+		return true, nil
+	}
+
+	var retryAt *time.Time
+	var bErr app_err.BusinessError
+	if !errors.As(err, &bErr) && job.Attempts < s.cfg.JobMaxAttempts {
+		retryTime := time.Now().Add(time.Duration(job.Attempts) * reportJobRetryDelay)
+		retryAt = &retryTime
+	}
+
+	// ctx is already cancelled when the worker is stopping, the error still has to be recorded
+	failCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reportJobFailTimeout)
+	defer cancel()
+
+	if failErr := s.reportJobRepo.FailReportJob(failCtx, job.ID, err.Error(), retryAt); failErr != nil {
+		return true, failErr
+	}
+
+	return true, err
+}
+
+// ProcessReportJobs processes due report jobs one after another until there are none left and returns how many it processed.
+// A failed job does not hold up the next ones, the error of the last failed job is returned.
+func (s *HistoryService) ProcessReportJobs(ctx context.Context) (int, error) {
+	var processed int
+	var jobErr error
+	for ctx.Err() == nil {
+		ok, err := s.ProcessReportJob(ctx)
+		if !ok {
+			if err != nil {
+				return processed, err
+			}
+			break
+		}
+
+		processed++
+		if err != nil {
+			jobErr = err
+		}
+	}
+
+	return processed, jobErr
+}
+
+func (s *HistoryService) runReportJob(ctx context.Context, job *model.ReportJob) (err error) {
+	opts, err := reportJobOptions(job.Params)
+	if err != nil {
+		return err
+	}
+
+	filter := model.HistoryFilter{
+		UserIDs:      job.Params.UserIDs,
+		SegmentSlugs: job.Params.SegmentSlugs,
+		Operation:    job.Params.Operation,
+		From:         job.Params.From,
+		To:           job.Params.To,
+	}
+
+	var progress atomic.Int64
+	jobCtx, stopHeartbeat := s.startReportJobHeartbeat(ctx, job.ID, &progress)
+	defer func() {
+		if err != nil && jobCtx.Err() != nil && ctx.Err() == nil {
+			// the heartbeat stopped the job, it was cancelled or its progress could not be saved
+			err = context.Cause(jobCtx)
+		}
+		stopHeartbeat()
+	}()
+
+	onRow := func(rowsWritten int64) error {
+		progress.Store(rowsWritten)
+		return context.Cause(jobCtx)
+	}
+
+	var rowsWritten int64
+	var write func(w io.Writer) error
+	switch job.Params.Kind {
+	case "", model.ReportKindHistory:
+		rowsTotal, err := s.historyRepo.CountHistory(jobCtx, filter)
+		if err != nil {
+			return err
+		}
+
+		err = s.reportJobRepo.SetReportJobRowsTotal(jobCtx, job.ID, rowsTotal)
+		if err != nil {
+			return err
+		}
+
+		write = func(w io.Writer) (err error) {
+			rowsWritten, err = s.writeReport(jobCtx, filter, opts, w, onRow)
+			return err
+		}
+	default:
+		// the number of rows of aggregate reports is not known in advance
+		write = func(w io.Writer) (err error) {
+			rowsWritten, err = s.writeSegmentReport(jobCtx, job.Params.Kind, filter, job.Params.Limit, opts, w, onRow)
+			return err
+		}
+	}
+
+	fileName, err := s.createReportFile(jobCtx, opts.Format, write)
+	if err != nil {
+		return err
+	}
+
+	completed, err := s.reportJobRepo.CompleteReportJob(ctx, job.ID, fileName, rowsWritten)
+	if err != nil || !completed {
+		s.deleteReportFile(ctx, fileName)
+	}
+
+	return err
+}
+
+// startReportJobHeartbeat saves the progress of the job every JobHeartbeat, however slowly its rows are produced, so that a job
+// busy with a long query is not taken for abandoned. The returned context is cancelled with errReportJobCancelled once the job
+// is cancelled, or with the error of saving the progress. stop ends the heartbeat and waits for it.
+func (s *HistoryService) startReportJobHeartbeat(ctx context.Context, jobId int64, progress *atomic.Int64) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(s.cfg.JobHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			status, err := s.reportJobRepo.UpdateReportJobProgress(jobCtx, jobId, progress.Load())
+			if err != nil {
+				cancel(err)
+				return
+			}
+			if status != model.ReportJobStatusRunning {
+				cancel(errReportJobCancelled)
+				return
+			}
+		}
+	}()
+
+	return jobCtx, func() {
+		cancel(nil)
+		<-done
+	}
+}
+
+func reportJobOptions(params model.ReportJobParams) (report.Options, error) {
+	if !report.IsFormat(params.Format) {
+		return report.Options{}, ErrUnknownReportFormat
+	}
+
+	delimiter, err := report.ParseDelimiter(params.Delimiter)
+	if err != nil {
+		return report.Options{}, ErrInvalidDelimiter
+	}
+
+	return report.Options{
+		Format:    params.Format,
+		Delimiter: delimiter,
+		Header:    params.Header,
+	}, nil
+}
+
+// reportJobState adds progress and, for done jobs, a download link that expires the link TTL after the job has finished.
+func (s *HistoryService) reportJobState(job model.ReportJob) model.ReportJobState {
+	state := model.ReportJobState{
+		ReportJob: job,
+	}
+
+	if job.RowsTotal != nil && *job.RowsTotal > 0 {
+		state.Progress = float64(job.RowsWritten) * 100 / float64(*job.RowsTotal)
+	}
+
+	if job.Status == model.ReportJobStatusDone && job.FileName != nil && job.FinishedAt != nil {
+		state.Progress = 100
+
+		expiresAt := job.FinishedAt.Add(s.cfg.LinkTTL)
+		if time.Now().Before(expiresAt) {
+			state.URL = s.reportLink(*job.FileName, expiresAt)
+		}
+	}
+
+	return state
+}
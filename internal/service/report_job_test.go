@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/config"
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHistoryService_ProcessReportJob(t *testing.T) {
+	userId := 100
+	params := model.ReportJobParams{
+		UserIDs: []int{userId},
+		Format:  "csv",
+	}
+	filter := model.HistoryFilter{
+		UserIDs: []int{userId},
+	}
+	repoError := errors.New("error from repo")
+	streamEntries := func(count int) func(context.Context, model.HistoryFilter, func(model.History) error) error {
+		return func(_ context.Context, _ model.HistoryFilter, fn func(model.History) error) error {
+			for i := 0; i < count; i++ {
+				if err := fn(model.History{UserID: &userId, SegmentSlug: "AVITO_TECH", Operation: model.OperationAdding}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	// waitCancel stands for a query that runs until the job is stopped
+	waitCancel := func(ctx context.Context, _ model.HistoryFilter, _ func(model.History) error) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	tests := []struct {
+		name                string
+		historyRepoBehave   func(repository *MockHistoryRepo)
+		reportJobRepoBehave func(repository *MockReportJobRepo)
+		// saveFile expects the report file to be saved
+		saveFile bool
+		// heartbeat defaults to an interval the test never reaches
+		heartbeat     time.Duration
+		stopping      bool
+		wantProcessed bool
+		wantErr       bool
+	}{
+		{
+			name: "no due jobs",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(nil, nil)
+			},
+			wantProcessed: false,
+			wantErr:       false,
+		},
+		{
+			name: "success",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(2), nil)
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(2))
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 1}, nil)
+				repository.EXPECT().SetReportJobRowsTotal(gomock.Any(), int64(1), int64(2)).Return(nil)
+				repository.EXPECT().CompleteReportJob(gomock.Any(), int64(1), gomock.Any(), int64(2)).Return(true, nil)
+			},
+			saveFile:      true,
+			wantProcessed: true,
+			wantErr:       false,
+		},
+		{
+			name: "segment churn report",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetTopChurnSegments(gomock.Any(), model.HistoryFilter{}, 5).Return([]model.SegmentChurn{
+					{SegmentSlug: "AVITO_TECH", Added: 2, Churn: 2, NetChange: 2},
+				}, nil)
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{
+					ID:       1,
+					Params:   model.ReportJobParams{Kind: model.ReportKindSegmentChurn, Limit: 5, Format: "csv"},
+					Attempts: 1,
+				}, nil)
+				repository.EXPECT().CompleteReportJob(gomock.Any(), int64(1), gomock.Any(), int64(1)).Return(true, nil)
+			},
+			saveFile:      true,
+			wantProcessed: true,
+			wantErr:       false,
+		},
+		{
+			name: "cancelled while running",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(2), nil)
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, filter model.HistoryFilter, fn func(model.History) error) error {
+						if err := streamEntries(1)(ctx, filter, fn); err != nil {
+							return err
+						}
+						return waitCancel(ctx, filter, fn)
+					})
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Millisecond, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 1}, nil)
+				repository.EXPECT().SetReportJobRowsTotal(gomock.Any(), int64(1), int64(2)).Return(nil)
+				repository.EXPECT().UpdateReportJobProgress(gomock.Any(), int64(1), int64(1)).Return(model.ReportJobStatusCancelled, nil)
+			},
+			heartbeat:     time.Millisecond,
+			wantProcessed: true,
+			wantErr:       false,
+		},
+		{
+			name: "cancelled during a slow query",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetTopChurnSegments(gomock.Any(), model.HistoryFilter{}, 5).DoAndReturn(
+					func(ctx context.Context, _ model.HistoryFilter, _ int) ([]model.SegmentChurn, error) {
+						<-ctx.Done()
+						return nil, ctx.Err()
+					})
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Millisecond, 3).Return(&model.ReportJob{
+					ID:       1,
+					Params:   model.ReportJobParams{Kind: model.ReportKindSegmentChurn, Limit: 5, Format: "csv"},
+					Attempts: 1,
+				}, nil)
+				repository.EXPECT().UpdateReportJobProgress(gomock.Any(), int64(1), int64(0)).Return(model.ReportJobStatusRunning, nil)
+				repository.EXPECT().UpdateReportJobProgress(gomock.Any(), int64(1), int64(0)).Return(model.ReportJobStatusCancelled, nil)
+			},
+			heartbeat:     time.Millisecond,
+			wantProcessed: true,
+			wantErr:       false,
+		},
+		{
+			name: "progress cannot be saved",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(2), nil)
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(waitCancel)
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Millisecond, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 1}, nil)
+				repository.EXPECT().SetReportJobRowsTotal(gomock.Any(), int64(1), int64(2)).Return(nil)
+				repository.EXPECT().UpdateReportJobProgress(gomock.Any(), int64(1), int64(0)).Return("", repoError)
+				repository.EXPECT().FailReportJob(gomock.Any(), int64(1), repoError.Error(), gomock.Not(gomock.Nil())).Return(nil)
+			},
+			heartbeat:     time.Millisecond,
+			wantProcessed: true,
+			wantErr:       true,
+		},
+		{
+			name: "worker is stopping",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(0), context.Canceled)
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 1}, nil)
+				repository.EXPECT().FailReportJob(notCancelled(), int64(1), context.Canceled.Error(), gomock.Not(gomock.Nil())).Return(nil)
+			},
+			stopping:      true,
+			wantProcessed: true,
+			wantErr:       true,
+		},
+		{
+			name: "error is retried",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(0), repoError)
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 1}, nil)
+				repository.EXPECT().FailReportJob(gomock.Any(), int64(1), repoError.Error(), gomock.Not(gomock.Nil())).Return(nil)
+			},
+			wantProcessed: true,
+			wantErr:       true,
+		},
+		{
+			name: "job fails after the last attempt",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(0), repoError)
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 3}, nil)
+				repository.EXPECT().FailReportJob(gomock.Any(), int64(1), repoError.Error(), gomock.Nil()).Return(nil)
+			},
+			wantProcessed: true,
+			wantErr:       true,
+		},
+		{
+			name: "no data is not retried",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().CountHistory(gomock.Any(), filter).Return(int64(0), nil)
+				repository.EXPECT().StreamHistory(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamEntries(0))
+			},
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{ID: 1, Params: params, Attempts: 1}, nil)
+				repository.EXPECT().SetReportJobRowsTotal(gomock.Any(), int64(1), int64(0)).Return(nil)
+				repository.EXPECT().FailReportJob(gomock.Any(), int64(1), ErrNoDataAvailable.Error(), gomock.Nil()).Return(nil)
+			},
+			wantProcessed: true,
+			wantErr:       true,
+		},
+		{
+			name: "error from ClaimReportJob()",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(nil, repoError)
+			},
+			wantProcessed: false,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			mockReportJobRepo := NewMockReportJobRepo(ctrl)
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+			if tt.reportJobRepoBehave != nil {
+				tt.reportJobRepoBehave(mockReportJobRepo)
+			}
+			mockReportFileRepo := NewMockReportFileRepo(ctrl)
+			if tt.saveFile {
+				mockReportFileRepo.EXPECT().CreateReportFile(gomock.Any(), gomock.Any()).Return(nil)
+				mockReportFileRepo.EXPECT().AddReportFilePart(gomock.Any(), gomock.Any(), 0, gomock.Any()).Return(true, nil)
+				mockReportFileRepo.EXPECT().CompleteReportFile(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+			}
+			heartbeat := tt.heartbeat
+			if heartbeat == 0 {
+				heartbeat = time.Hour
+			}
+			s := &HistoryService{
+				historyRepo:    mockHistoryRepo,
+				reportJobRepo:  mockReportJobRepo,
+				reportFileRepo: mockReportFileRepo,
+				cfg: config.ReportConfig{
+					LinkTTL:        time.Hour,
+					JobMaxAttempts: 3,
+					JobHeartbeat:   heartbeat,
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.stopping {
+				cancel()
+			}
+
+			processed, err := s.ProcessReportJob(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.ProcessReportJob() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if processed != tt.wantProcessed {
+				t.Errorf("HistoryService.ProcessReportJob() = %v, want %v", processed, tt.wantProcessed)
+			}
+		})
+	}
+}
+
+func TestHistoryService_ProcessReportJobs(t *testing.T) {
+	repoError := errors.New("error from repo")
+	badJob := &model.ReportJob{ID: 1, Params: model.ReportJobParams{Format: "xml"}, Attempts: 1}
+	tests := []struct {
+		name                string
+		reportJobRepoBehave func(repository *MockReportJobRepo)
+		wantProcessed       int
+		wantErr             error
+	}{
+		{
+			name: "no due jobs",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(nil, nil)
+			},
+			wantProcessed: 0,
+			wantErr:       nil,
+		},
+		{
+			name: "a failed job does not stop the next one",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				gomock.InOrder(
+					repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(badJob, nil),
+					repository.EXPECT().FailReportJob(gomock.Any(), int64(1), ErrUnknownReportFormat.Error(), gomock.Nil()).Return(nil),
+					repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(&model.ReportJob{ID: 2, Params: badJob.Params, Attempts: 1}, nil),
+					repository.EXPECT().FailReportJob(gomock.Any(), int64(2), ErrUnknownReportFormat.Error(), gomock.Nil()).Return(nil),
+					repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(nil, nil),
+				)
+			},
+			wantProcessed: 2,
+			wantErr:       ErrUnknownReportFormat,
+		},
+		{
+			name: "error from ClaimReportJob()",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				gomock.InOrder(
+					repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(badJob, nil),
+					repository.EXPECT().FailReportJob(gomock.Any(), int64(1), ErrUnknownReportFormat.Error(), gomock.Nil()).Return(nil),
+					repository.EXPECT().ClaimReportJob(gomock.Any(), 3*time.Hour, 3).Return(nil, repoError),
+				)
+			},
+			wantProcessed: 1,
+			wantErr:       repoError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockReportJobRepo := NewMockReportJobRepo(ctrl)
+			tt.reportJobRepoBehave(mockReportJobRepo)
+			s := &HistoryService{
+				reportJobRepo: mockReportJobRepo,
+				cfg: config.ReportConfig{
+					JobMaxAttempts: 3,
+					JobHeartbeat:   time.Hour,
+				},
+			}
+
+			processed, err := s.ProcessReportJobs(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("HistoryService.ProcessReportJobs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if processed != tt.wantProcessed {
+				t.Errorf("HistoryService.ProcessReportJobs() = %v, want %v", processed, tt.wantProcessed)
+			}
+		})
+	}
+}
+
+// notCancelled matches a context that is not done.
+func notCancelled() gomock.Matcher {
+	return notCancelledMatcher{}
+}
+
+type notCancelledMatcher struct{}
+
+func (notCancelledMatcher) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && ctx.Err() == nil
+}
+
+func (notCancelledMatcher) String() string {
+	return "is a context that is not cancelled"
+}
+
+func TestHistoryService_CancelReportJob(t *testing.T) {
+	var jobId int64 = 1
+	tests := []struct {
+		name                string
+		reportJobRepoBehave func(repository *MockReportJobRepo)
+		wantErr             bool
+	}{
+		{
+			name: "success",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().CancelReportJob(gomock.Any(), jobId).Return(true, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "job is already finished",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().CancelReportJob(gomock.Any(), jobId).Return(false, nil)
+				repository.EXPECT().GetReportJob(gomock.Any(), jobId).Return(&model.ReportJob{ID: jobId, Status: model.ReportJobStatusDone}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "job does not exist",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().CancelReportJob(gomock.Any(), jobId).Return(false, nil)
+				repository.EXPECT().GetReportJob(gomock.Any(), jobId).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from CancelReportJob()",
+			reportJobRepoBehave: func(repository *MockReportJobRepo) {
+				repository.EXPECT().CancelReportJob(gomock.Any(), jobId).Return(false, errors.New("error from repo"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockReportJobRepo := NewMockReportJobRepo(ctrl)
+			if tt.reportJobRepoBehave != nil {
+				tt.reportJobRepoBehave(mockReportJobRepo)
+			}
+			s := &HistoryService{
+				reportJobRepo: mockReportJobRepo,
+			}
+
+			if err := s.CancelReportJob(context.Background(), jobId); (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.CancelReportJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,66 +2,285 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/elgntt/segmentation-service/internal/config"
 	"github.com/elgntt/segmentation-service/internal/model"
-	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
 )
 
 type SegmentService struct {
 	segmentRepo SegmentRepo
 	historyRepo HistoryRepo
 	userRepo    UserRepo
+	transactor  Transactor
+	cfg         config.SegmentConfig
 }
 
-func NewSegmentService(segmentRepo SegmentRepo, historyRepo HistoryRepo, userRepo UserRepo) *SegmentService {
+func NewSegmentService(segmentRepo SegmentRepo, historyRepo HistoryRepo, userRepo UserRepo, transactor Transactor, cfg config.SegmentConfig) *SegmentService {
 	return &SegmentService{
 		segmentRepo: segmentRepo,
 		historyRepo: historyRepo,
 		userRepo:    userRepo,
+		transactor:  transactor,
+		cfg:         cfg,
 	}
 }
+
 func (s *SegmentService) CreateSegment(ctx context.Context, segmentData model.AddSegment) error {
-	addedSegmentId, err := s.segmentRepo.CreateSegment(ctx, segmentData.SegmentSlug)
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		addedSegmentId, err := s.segmentRepo.CreateSegment(ctx, segmentData)
+		if err != nil {
+			return err
+		}
+
+		err = s.recordSegmentEvent(ctx, segmentData.SegmentSlug, model.OperationSegmentCreated)
+		if err != nil {
+			return err
+		}
+
+		if segmentData.AutoJoinPercent == 0 {
+			return nil
+		}
+
+		usersIDs, err := s.userRepo.GetPercentUsers(ctx, addedSegmentId, segmentData.AutoJoinPercent)
+		if err != nil {
+			return err
+		}
+
+		if usersIDs == nil {
+			return nil
+		}
+
+		return s.AddMultipleUsersToSegment(ctx, addedSegmentId, segmentData.SegmentSlug, usersIDs)
+	})
+}
+
+func (s *SegmentService) DeleteSegment(ctx context.Context, segmentSlug string) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		removedSegmentId, err := s.segmentRepo.DeleteSegment(ctx, segmentSlug)
+		if err != nil {
+			return err
+		}
+
+		if removedSegmentId == nil {
+			return ErrSegmentDoesNotExist
+		}
+
+		err = s.recordSegmentEvent(ctx, segmentSlug, model.OperationSegmentDeleted)
+		if err != nil {
+			return err
+		}
+
+		usersIDs, err := s.segmentRepo.GetSegmentUsers(ctx, *removedSegmentId)
+		if err != nil {
+			return err
+		}
+
+		if usersIDs == nil {
+			return nil
+		}
+
+		return s.RecordMultipleUsersToHistory(ctx, segmentSlug, model.OperationRemoval, model.HistorySourceSegmentDeleted, usersIDs)
+	})
+}
+
+// RenameSegment changes the segment slug. The old slug stays an alias of the segment until the alias TTL is over.
+func (s *SegmentService) RenameSegment(ctx context.Context, segmentSlug, newSlug string) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		segment, err := s.segmentRepo.GetSegment(ctx, segmentSlug)
+		if err != nil {
+			return err
+		}
+
+		if segment == nil {
+			return ErrSegmentDoesNotExist
+		}
+
+		renamed, err := s.segmentRepo.RenameSegment(ctx, segment.ID, newSlug)
+		if err != nil {
+			return err
+		}
+
+		if !renamed {
+			return ErrSegmentDoesNotExist
+		}
+
+		err = s.segmentRepo.CreateSegmentAlias(ctx, segmentSlug, segment.ID, time.Now().Add(s.cfg.AliasTTL))
+		if err != nil {
+			return err
+		}
+
+		return s.recordSegmentEvent(ctx, newSlug, model.OperationRenamed)
+	})
+}
+
+// recordSegmentEvent records a change of the segment itself made through the API.
+func (s *SegmentService) recordSegmentEvent(ctx context.Context, segmentSlug string, operation model.Operation) error {
+	meta := audit.FromContext(ctx)
+	return s.historyRepo.RecordSegmentEvent(ctx, model.SegmentEvent{
+		SegmentSlug: segmentSlug,
+		Operation:   operation,
+		Source:      model.HistorySourceAPI,
+		ActorID:     meta.ActorID,
+		RequestID:   meta.RequestID,
+	})
+}
+
+// RestoreSegment brings back a segment archived within the grace period together with its memberships.
+func (s *SegmentService) RestoreSegment(ctx context.Context, segmentSlug string) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		restoredSegmentId, err := s.segmentRepo.RestoreSegment(ctx, segmentSlug, time.Now().Add(-s.cfg.RestoreGracePeriod))
+		if err != nil {
+			return err
+		}
+
+		if restoredSegmentId == nil {
+			return ErrNothingToRestore
+		}
+
+		usersIDs, err := s.segmentRepo.GetSegmentUsers(ctx, *restoredSegmentId)
+		if err != nil {
+			return err
+		}
+
+		if usersIDs == nil {
+			return nil
+		}
+
+		return s.RecordMultipleUsersToHistory(ctx, segmentSlug, model.OperationRestored, model.HistorySourceAPI, usersIDs)
+	})
+}
+
+// PurgeDeletedSegments permanently deletes segments whose grace period is over.
+func (s *SegmentService) PurgeDeletedSegments(ctx context.Context) (int, error) {
+	return s.segmentRepo.PurgeDeletedSegments(ctx, time.Now().Add(-s.cfg.RestoreGracePeriod))
+}
+
+func (s *SegmentService) GetSegment(ctx context.Context, segmentSlug string) (model.Segment, error) {
+	segment, err := s.segmentRepo.GetSegment(ctx, segmentSlug)
 	if err != nil {
-		return err
+		return model.Segment{}, err
 	}
 
-	if segmentData.AutoJoinPercent == 0 {
-		return nil
+	if segment == nil {
+		return model.Segment{}, ErrSegmentDoesNotExist
 	}
 
-	usersIDs, err := s.userRepo.GetPercentUsers(ctx, segmentData.AutoJoinPercent)
+	return *segment, nil
+}
+
+func (s *SegmentService) GetSegments(ctx context.Context, filter model.SegmentFilter) ([]model.Segment, error) {
+	return s.segmentRepo.GetSegments(ctx, filter)
+}
+
+func (s *SegmentService) GetSegmentsPage(ctx context.Context, params model.SegmentsPageParams) (model.SegmentsPage, error) {
+	total, err := s.segmentRepo.CountSegments(ctx, params.SegmentFilter)
 	if err != nil {
-		return err
+		return model.SegmentsPage{}, err
 	}
 
-	if usersIDs == nil {
-		return nil
+	segments, err := s.segmentRepo.GetSegmentsPage(ctx, params)
+	if err != nil {
+		return model.SegmentsPage{}, err
 	}
 
-	return s.AddMultipleUsersToSegment(ctx, addedSegmentId, segmentData.SegmentSlug, usersIDs)
+	return model.SegmentsPage{
+		Segments: segments,
+		Page:     params.Page,
+		PerPage:  params.PerPage,
+		Total:    total,
+	}, nil
 }
 
-func (s *SegmentService) DeleteSegment(ctx context.Context, segmentSlug string) error {
-	removedSegmentId, err := s.segmentRepo.DeleteSegment(ctx, segmentSlug)
+// GetSegmentMembers returns a page of active segment members ordered by user id. The cursor is the
+// last user id of the previous page, NextCursor is nil on the last page.
+func (s *SegmentService) GetSegmentMembers(ctx context.Context, segmentSlug string, cursor, limit int) (model.SegmentMembersPage, error) {
+	segment, err := s.segmentRepo.GetSegment(ctx, segmentSlug)
 	if err != nil {
-		return err
+		return model.SegmentMembersPage{}, err
 	}
 
-	if removedSegmentId == nil {
-		return app_err.NewBusinessError("segment does not exists")
+	if segment == nil {
+		return model.SegmentMembersPage{}, ErrSegmentDoesNotExist
 	}
 
-	usersIDs, err := s.segmentRepo.RemoveUsersFromDeletedSegment(ctx, *removedSegmentId)
+	members, err := s.segmentRepo.GetSegmentMembers(ctx, segment.ID, cursor, limit+1)
 	if err != nil {
-		return err
+		return model.SegmentMembersPage{}, err
+	}
+
+	page := model.SegmentMembersPage{
+		Members: members,
+	}
+	if len(members) > limit {
+		page.Members = members[:limit]
+		page.NextCursor = &page.Members[limit-1].UserID
 	}
 
-	if usersIDs == nil {
-		return nil
+	return page, nil
+}
+
+func (s *SegmentService) UpdateSegment(ctx context.Context, segmentSlug string, segmentData model.UpdateSegment) (model.Segment, error) {
+	var segment model.Segment
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		currentSegment, err := s.segmentRepo.GetSegment(ctx, segmentSlug)
+		if err != nil {
+			return err
+		}
+
+		if currentSegment == nil {
+			return ErrSegmentDoesNotExist
+		}
+
+		updatedSegment, err := s.segmentRepo.UpdateSegment(ctx, segmentSlug, segmentData)
+		if err != nil {
+			return err
+		}
+
+		if updatedSegment == nil {
+			return ErrSegmentDoesNotExist
+		}
+		segment = *updatedSegment
+
+		return s.changeAutoJoinPercent(ctx, segment.ID, segmentSlug, currentSegment.AutoJoinPercent, segment.AutoJoinPercent)
+	})
+	if err != nil {
+		return model.Segment{}, err
+	}
+
+	return segment, nil
+}
+
+// changeAutoJoinPercent moves the rollout boundary: raising the percent only adds the users of the
+// newly covered buckets, lowering it only removes the users of the uncovered ones.
+func (s *SegmentService) changeAutoJoinPercent(ctx context.Context, segmentId int, segmentSlug string, fromPercent, toPercent int) error {
+	switch {
+	case toPercent > fromPercent:
+		usersIDs, err := s.segmentRepo.AddPercentUsersToSegment(ctx, segmentId, fromPercent, toPercent)
+		if err != nil {
+			return err
+		}
+
+		if usersIDs == nil {
+			return nil
+		}
+
+		return s.RecordMultipleUsersToHistory(ctx, segmentSlug, model.OperationAdding, model.HistorySourceAutoJoin, usersIDs)
+	case toPercent < fromPercent:
+		usersIDs, err := s.segmentRepo.RemovePercentUsersFromSegment(ctx, segmentId, toPercent, fromPercent)
+		if err != nil {
+			return err
+		}
+
+		if usersIDs == nil {
+			return nil
+		}
+
+		return s.RecordMultipleUsersToHistory(ctx, segmentSlug, model.OperationRemoval, model.HistorySourceAutoJoin, usersIDs)
 	}
 
-	return s.RecordMultipleUsersToHistory(ctx, segmentSlug, removeOperationStr, usersIDs)
+	return nil
 }
 
 func (s *SegmentService) AddMultipleUsersToSegment(ctx context.Context, segmentId int, segmentSlug string, usersIDs []int) error {
@@ -70,14 +289,18 @@ func (s *SegmentService) AddMultipleUsersToSegment(ctx context.Context, segmentI
 		return err
 	}
 
-	return s.RecordMultipleUsersToHistory(ctx, segmentSlug, addOperationStr, usersIDs)
+	return s.RecordMultipleUsersToHistory(ctx, segmentSlug, model.OperationAdding, model.HistorySourceAutoJoin, usersIDs)
 }
 
-func (s *SegmentService) RecordMultipleUsersToHistory(ctx context.Context, segmentSlug, operation string, usersIDs []int) error {
+func (s *SegmentService) RecordMultipleUsersToHistory(ctx context.Context, segmentSlug string, operation model.Operation, source string, usersIDs []int) error {
+	meta := audit.FromContext(ctx)
 	historyData := model.HistoryDataMultipleUsers{
 		UsersIDs:    usersIDs,
 		SegmentSlug: segmentSlug,
 		Operation:   operation,
+		Source:      source,
+		ActorID:     meta.ActorID,
+		RequestID:   meta.RequestID,
 	}
 
 	return s.historyRepo.RecordMultipleUsersToHistory(ctx, historyData)
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
+)
+
+var segmentDailyReportColumns = []report.Column{
+	{Name: "day", Type: report.ColumnDate},
+	{Name: "segmentSlug", Type: report.ColumnString},
+	{Name: "added", Type: report.ColumnInt},
+	{Name: "removed", Type: report.ColumnInt},
+	{Name: "netChange", Type: report.ColumnInt},
+}
+
+var segmentChurnReportColumns = []report.Column{
+	{Name: "segmentSlug", Type: report.ColumnString},
+	{Name: "added", Type: report.ColumnInt},
+	{Name: "removed", Type: report.ColumnInt},
+	{Name: "netChange", Type: report.ColumnInt},
+	{Name: "churn", Type: report.ColumnInt},
+}
+
+func isSegmentReportKind(kind string) bool {
+	return kind == model.ReportKindSegmentDaily || kind == model.ReportKindSegmentChurn
+}
+
+// WriteSegmentReport streams a report of the given kind that aggregates membership changes of all users by segment.
+// Filter selects the segments and the time range, limit is the number of segments in a segmentChurn report, all if zero.
+func (s *HistoryService) WriteSegmentReport(ctx context.Context, kind string, filter model.HistoryFilter, limit int, opts report.Options, w io.Writer) error {
+	if !report.IsFormat(opts.Format) {
+		return ErrUnknownReportFormat
+	}
+	if !isSegmentReportKind(kind) {
+		return ErrUnknownReportKind
+	}
+
+	_, err := s.writeSegmentReport(ctx, kind, filter, limit, opts, w, nil)
+
+	return err
+}
+
+// writeSegmentReport is writeReport for segment reports.
+func (s *HistoryService) writeSegmentReport(ctx context.Context, kind string, filter model.HistoryFilter, limit int, opts report.Options, w io.Writer, onRow func(rowsWritten int64) error) (int64, error) {
+	switch kind {
+	case model.ReportKindSegmentDaily:
+		return writeReportRows(w, segmentDailyReportColumns, opts, func(emit func(values []any) error) error {
+			return s.historyRepo.StreamSegmentDailyStats(ctx, filter, func(stats model.SegmentDailyStats) error {
+				return emit([]any{stats.Day, stats.SegmentSlug, stats.Added, stats.Removed, stats.NetChange})
+			})
+		}, onRow)
+	case model.ReportKindSegmentChurn:
+		return writeReportRows(w, segmentChurnReportColumns, opts, func(emit func(values []any) error) error {
+			segments, err := s.historyRepo.GetTopChurnSegments(ctx, filter, limit)
+			if err != nil {
+				return err
+			}
+
+			for _, segment := range segments {
+				err = emit([]any{segment.SegmentSlug, segment.Added, segment.Removed, segment.NetChange, segment.Churn})
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}, onRow)
+	}
+
+	return 0, ErrUnknownReportKind
+}
+
+// GenerateSegmentReportFile writes the segment report into a file and returns a signed link to it that expires after the link TTL.
+func (s *HistoryService) GenerateSegmentReportFile(ctx context.Context, kind string, filter model.HistoryFilter, limit int, opts report.Options) (string, error) {
+	if !report.IsFormat(opts.Format) {
+		return "", ErrUnknownReportFormat
+	}
+	if !isSegmentReportKind(kind) {
+		return "", ErrUnknownReportKind
+	}
+
+	fileName, err := s.createReportFile(ctx, opts.Format, func(w io.Writer) error {
+		_, err := s.writeSegmentReport(ctx, kind, filter, limit, opts, w, nil)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.reportLink(fileName, time.Now().Add(s.cfg.LinkTTL)), nil
+}
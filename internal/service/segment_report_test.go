@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/report"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHistoryService_WriteSegmentReport(t *testing.T) {
+	from := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.HistoryFilter{
+		SegmentSlugs: []string{"AVITO_TECH", "AVITO_VOICE"},
+		From:         &from,
+		To:           &to,
+	}
+	dailyStats := []model.SegmentDailyStats{
+		{Day: time.Date(2023, 8, 12, 0, 0, 0, 0, time.UTC), SegmentSlug: "AVITO_TECH", Added: 5, Removed: 1, NetChange: 4},
+		{Day: time.Date(2023, 8, 13, 0, 0, 0, 0, time.UTC), SegmentSlug: "AVITO_VOICE", Added: 0, Removed: 3, NetChange: -3},
+	}
+	streamStats := func(stats []model.SegmentDailyStats) func(context.Context, model.HistoryFilter, func(model.SegmentDailyStats) error) error {
+		return func(_ context.Context, _ model.HistoryFilter, fn func(model.SegmentDailyStats) error) error {
+			for _, s := range stats {
+				if err := fn(s); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	tests := []struct {
+		name              string
+		kind              string
+		limit             int
+		opts              report.Options
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              string
+		wantErr           bool
+	}{
+		{
+			name: "daily csv with header",
+			kind: model.ReportKindSegmentDaily,
+			opts: report.Options{Format: report.FormatCSV, Header: true},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamSegmentDailyStats(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamStats(dailyStats))
+			},
+			want:    "day;segmentSlug;added;removed;netChange\n2023-08-12;AVITO_TECH;5;1;4\n2023-08-13;AVITO_VOICE;0;3;-3\n",
+			wantErr: false,
+		},
+		{
+			name: "daily ndjson",
+			kind: model.ReportKindSegmentDaily,
+			opts: report.Options{Format: report.FormatNDJSON},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamSegmentDailyStats(gomock.Any(), filter, gomock.Any()).DoAndReturn(streamStats(dailyStats[:1]))
+			},
+			want:    `{"day":"2023-08-12","segmentSlug":"AVITO_TECH","added":5,"removed":1,"netChange":4}` + "\n",
+			wantErr: false,
+		},
+		{
+			name:  "churn json",
+			kind:  model.ReportKindSegmentChurn,
+			limit: 2,
+			opts:  report.Options{Format: report.FormatJSON},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetTopChurnSegments(gomock.Any(), filter, 2).Return([]model.SegmentChurn{
+					{SegmentSlug: "AVITO_TECH", Added: 5, Removed: 4, NetChange: 1, Churn: 9},
+					{SegmentSlug: "AVITO_VOICE", Added: 0, Removed: 3, NetChange: -3, Churn: 3},
+				}, nil)
+			},
+			want: `[{"segmentSlug":"AVITO_TECH","added":5,"removed":4,"netChange":1,"churn":9},` +
+				`{"segmentSlug":"AVITO_VOICE","added":0,"removed":3,"netChange":-3,"churn":3}]`,
+			wantErr: false,
+		},
+		{
+			name: "no data available",
+			kind: model.ReportKindSegmentChurn,
+			opts: report.Options{Format: report.FormatXLSX},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().GetTopChurnSegments(gomock.Any(), filter, 0).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown kind",
+			kind:    model.ReportKindHistory,
+			opts:    report.Options{Format: report.FormatCSV},
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			kind:    model.ReportKindSegmentDaily,
+			opts:    report.Options{Format: "pdf"},
+			wantErr: true,
+		},
+		{
+			name: "error from StreamSegmentDailyStats()",
+			kind: model.ReportKindSegmentDaily,
+			opts: report.Options{Format: report.FormatCSV},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().StreamSegmentDailyStats(gomock.Any(), filter, gomock.Any()).Return(errors.New("error from repo"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+			s := &HistoryService{
+				historyRepo: mockHistoryRepo,
+			}
+
+			var buf bytes.Buffer
+			err := s.WriteSegmentReport(context.Background(), tt.kind, filter, tt.limit, tt.opts, &buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HistoryService.WriteSegmentReport() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if buf.String() != tt.want {
+				t.Errorf("HistoryService.WriteSegmentReport() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
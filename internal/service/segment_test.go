@@ -3,8 +3,11 @@ package service
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/elgntt/segmentation-service/internal/config"
 	"github.com/elgntt/segmentation-service/internal/model"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -12,6 +15,15 @@ import (
 func TestSegmentService_CreateSegment(t *testing.T) {
 	autoJoinPercent := 80
 	segmentSlug := "test"
+	segmentData := model.AddSegment{
+		SegmentSlug:     segmentSlug,
+		AutoJoinPercent: autoJoinPercent,
+	}
+	createdEvent := model.SegmentEvent{
+		SegmentSlug: segmentSlug,
+		Operation:   model.OperationSegmentCreated,
+		Source:      model.HistorySourceAPI,
+	}
 	tests := []struct {
 		name              string
 		segmentData       model.AddSegment
@@ -27,17 +39,19 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 				AutoJoinPercent: autoJoinPercent,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
-				repository.EXPECT().CreateSegment(gomock.Any(), segmentSlug).Return(1, nil)
+				repository.EXPECT().CreateSegment(gomock.Any(), segmentData).Return(1, nil)
 				repository.EXPECT().AddMultipleUsersToSegment(gomock.Any(), 1, []int{1}).Return(nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().GetPercentUsers(gomock.Any(), autoJoinPercent).Return([]int{1}, nil)
+				repository.EXPECT().GetPercentUsers(gomock.Any(), 1, autoJoinPercent).Return([]int{1}, nil)
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), createdEvent).Return(nil)
 				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
 					UsersIDs:    []int{1},
 					SegmentSlug: segmentSlug,
-					Operation:   addOperationStr,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAutoJoin,
 				}).Return(nil)
 			},
 			wantErr: false,
@@ -49,7 +63,10 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 				AutoJoinPercent: 0,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
-				repository.EXPECT().CreateSegment(gomock.Any(), segmentSlug).Return(1, nil)
+				repository.EXPECT().CreateSegment(gomock.Any(), model.AddSegment{SegmentSlug: segmentSlug}).Return(1, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), createdEvent).Return(nil)
 			},
 			wantErr: false,
 		},
@@ -60,10 +77,13 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 				AutoJoinPercent: autoJoinPercent,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
-				repository.EXPECT().CreateSegment(gomock.Any(), segmentSlug).Return(1, nil)
+				repository.EXPECT().CreateSegment(gomock.Any(), segmentData).Return(1, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), createdEvent).Return(nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().GetPercentUsers(gomock.Any(), autoJoinPercent).Return(nil, nil)
+				repository.EXPECT().GetPercentUsers(gomock.Any(), 1, autoJoinPercent).Return(nil, nil)
 			},
 			wantErr: false,
 		},
@@ -74,7 +94,21 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 				AutoJoinPercent: autoJoinPercent,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
-				repository.EXPECT().CreateSegment(gomock.Any(), segmentSlug).Return(0, errors.New("sql error"))
+				repository.EXPECT().CreateSegment(gomock.Any(), segmentData).Return(0, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from RecordSegmentEvent()",
+			segmentData: model.AddSegment{
+				SegmentSlug:     segmentSlug,
+				AutoJoinPercent: autoJoinPercent,
+			},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().CreateSegment(gomock.Any(), segmentData).Return(1, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), createdEvent).Return(errors.New("sql error"))
 			},
 			wantErr: true,
 		},
@@ -85,10 +119,13 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 				AutoJoinPercent: autoJoinPercent,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
-				repository.EXPECT().CreateSegment(gomock.Any(), segmentSlug).Return(1, nil)
+				repository.EXPECT().CreateSegment(gomock.Any(), segmentData).Return(1, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), createdEvent).Return(nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().GetPercentUsers(gomock.Any(), autoJoinPercent).Return(nil, errors.New("sql error"))
+				repository.EXPECT().GetPercentUsers(gomock.Any(), 1, autoJoinPercent).Return(nil, errors.New("sql error"))
 			},
 			wantErr: true,
 		},
@@ -114,6 +151,7 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 				segmentRepo: mockSegmentRepo,
 				historyRepo: mockHistoryRepo,
 				userRepo:    mockUserRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
 			}
 
 			err := s.CreateSegment(context.Background(), tt.segmentData)
@@ -124,9 +162,119 @@ func TestSegmentService_CreateSegment(t *testing.T) {
 	}
 }
 
+func TestSegmentService_changeAutoJoinPercent(t *testing.T) {
+	segmentId := 1
+	segmentSlug := "test"
+	tests := []struct {
+		name              string
+		fromPercent       int
+		toPercent         int
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name:        "raising the percent adds the users of the newly covered buckets",
+			fromPercent: 20,
+			toPercent:   30,
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().AddPercentUsersToSegment(gomock.Any(), segmentId, 20, 30).Return([]int{5}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
+					UsersIDs:    []int{5},
+					SegmentSlug: segmentSlug,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:        "lowering the percent removes the users of the uncovered buckets",
+			fromPercent: 20,
+			toPercent:   10,
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().RemovePercentUsersFromSegment(gomock.Any(), segmentId, 10, 20).Return([]int{7}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
+					UsersIDs:    []int{7},
+					SegmentSlug: segmentSlug,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:        "no users in the covered buckets",
+			fromPercent: 20,
+			toPercent:   30,
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().AddPercentUsersToSegment(gomock.Any(), segmentId, 20, 30).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:        "unchanged percent",
+			fromPercent: 20,
+			toPercent:   20,
+			wantErr:     false,
+		},
+		{
+			name:        "error from AddPercentUsersToSegment()",
+			fromPercent: 20,
+			toPercent:   30,
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().AddPercentUsersToSegment(gomock.Any(), segmentId, 20, 30).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+		{
+			name:        "error from RemovePercentUsersFromSegment()",
+			fromPercent: 20,
+			toPercent:   10,
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().RemovePercentUsersFromSegment(gomock.Any(), segmentId, 10, 20).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.segmentRepoBehave != nil {
+				tt.segmentRepoBehave(mockSegmentRepo)
+			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+				historyRepo: mockHistoryRepo,
+			}
+
+			err := s.changeAutoJoinPercent(context.Background(), segmentId, segmentSlug, tt.fromPercent, tt.toPercent)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SegmentService.changeAutoJoinPercent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestSegmentService_DeleteSegment(t *testing.T) {
 	deletedSegmentId := 1
 	segmentSlug := "test"
+	deletedEvent := model.SegmentEvent{
+		SegmentSlug: segmentSlug,
+		Operation:   model.OperationSegmentDeleted,
+		Source:      model.HistorySourceAPI,
+	}
 	tests := []struct {
 		name              string
 		segmentSlug       string
@@ -139,13 +287,15 @@ func TestSegmentService_DeleteSegment(t *testing.T) {
 			segmentSlug: segmentSlug,
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
 				repository.EXPECT().DeleteSegment(gomock.Any(), segmentSlug).Return(&deletedSegmentId, nil)
-				repository.EXPECT().RemoveUsersFromDeletedSegment(gomock.Any(), deletedSegmentId).Return([]int{1}, nil)
+				repository.EXPECT().GetSegmentUsers(gomock.Any(), deletedSegmentId).Return([]int{1}, nil)
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), deletedEvent).Return(nil)
 				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
 					UsersIDs:    []int{1},
 					SegmentSlug: segmentSlug,
-					Operation:   removeOperationStr,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceSegmentDeleted,
 				}).Return(nil)
 			},
 			wantErr: false,
@@ -163,7 +313,10 @@ func TestSegmentService_DeleteSegment(t *testing.T) {
 			segmentSlug: segmentSlug,
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
 				repository.EXPECT().DeleteSegment(context.Background(), segmentSlug).Return(&deletedSegmentId, nil)
-				repository.EXPECT().RemoveUsersFromDeletedSegment(gomock.Any(), deletedSegmentId)
+				repository.EXPECT().GetSegmentUsers(gomock.Any(), deletedSegmentId)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), deletedEvent).Return(nil)
 			},
 			wantErr: false,
 		},
@@ -176,11 +329,14 @@ func TestSegmentService_DeleteSegment(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:        "error from accessing the RemoveUsersFromDeletedSegment() repository",
+			name:        "error from accessing the GetSegmentUsers() repository",
 			segmentSlug: segmentSlug,
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
 				repository.EXPECT().DeleteSegment(context.Background(), segmentSlug).Return(&deletedSegmentId, nil)
-				repository.EXPECT().RemoveUsersFromDeletedSegment(gomock.Any(), deletedSegmentId).Return(nil, errors.New("sql error"))
+				repository.EXPECT().GetSegmentUsers(gomock.Any(), deletedSegmentId).Return(nil, errors.New("sql error"))
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), deletedEvent).Return(nil)
 			},
 			wantErr: true,
 		},
@@ -201,6 +357,7 @@ func TestSegmentService_DeleteSegment(t *testing.T) {
 			s := &SegmentService{
 				segmentRepo: mockSegmentRepo,
 				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
 			}
 
 			err := s.DeleteSegment(context.Background(), tt.segmentSlug)
@@ -210,3 +367,492 @@ func TestSegmentService_DeleteSegment(t *testing.T) {
 		})
 	}
 }
+
+func TestSegmentService_UpdateSegment(t *testing.T) {
+	segmentSlug := "test"
+	description := "new description"
+	raisedPercent := 30
+	loweredPercent := 10
+	currentSegment := &model.Segment{
+		ID:              1,
+		Slug:            segmentSlug,
+		AutoJoinPercent: 20,
+	}
+	tests := []struct {
+		name              string
+		segmentData       model.UpdateSegment
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name:        "metadata only",
+			segmentData: model.UpdateSegment{Description: &description},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(currentSegment, nil)
+				repository.EXPECT().UpdateSegment(gomock.Any(), segmentSlug, model.UpdateSegment{Description: &description}).
+					Return(&model.Segment{ID: 1, Slug: segmentSlug, AutoJoinPercent: 20, Description: description}, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:        "raise auto-join percent",
+			segmentData: model.UpdateSegment{AutoJoinPercent: &raisedPercent},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(currentSegment, nil)
+				repository.EXPECT().UpdateSegment(gomock.Any(), segmentSlug, model.UpdateSegment{AutoJoinPercent: &raisedPercent}).
+					Return(&model.Segment{ID: 1, Slug: segmentSlug, AutoJoinPercent: raisedPercent}, nil)
+				repository.EXPECT().AddPercentUsersToSegment(gomock.Any(), 1, 20, raisedPercent).Return([]int{5}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
+					UsersIDs:    []int{5},
+					SegmentSlug: segmentSlug,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:        "lower auto-join percent",
+			segmentData: model.UpdateSegment{AutoJoinPercent: &loweredPercent},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(currentSegment, nil)
+				repository.EXPECT().UpdateSegment(gomock.Any(), segmentSlug, model.UpdateSegment{AutoJoinPercent: &loweredPercent}).
+					Return(&model.Segment{ID: 1, Slug: segmentSlug, AutoJoinPercent: loweredPercent}, nil)
+				repository.EXPECT().RemovePercentUsersFromSegment(gomock.Any(), 1, loweredPercent, 20).Return([]int{7}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
+					UsersIDs:    []int{7},
+					SegmentSlug: segmentSlug,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name:        "segment does not exist",
+			segmentData: model.UpdateSegment{Description: &description},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:        "error from UpdateSegment()",
+			segmentData: model.UpdateSegment{Description: &description},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(currentSegment, nil)
+				repository.EXPECT().UpdateSegment(gomock.Any(), segmentSlug, model.UpdateSegment{Description: &description}).
+					Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.segmentRepoBehave != nil {
+				tt.segmentRepoBehave(mockSegmentRepo)
+			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+			}
+
+			_, err := s.UpdateSegment(context.Background(), segmentSlug, tt.segmentData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SegmentService.UpdateSegment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSegmentService_GetSegment(t *testing.T) {
+	segmentSlug := "AVITO_TECH"
+	segment := &model.Segment{ID: 2, Slug: segmentSlug, AutoJoinPercent: 10}
+	tests := []struct {
+		name              string
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		want              model.Segment
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+			},
+			want:    *segment,
+			wantErr: false,
+		},
+		{
+			name: "segment does not exist",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from GetSegment()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			tt.segmentRepoBehave(mockSegmentRepo)
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+			}
+
+			got, err := s.GetSegment(context.Background(), segmentSlug)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SegmentService.GetSegment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SegmentService.GetSegment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentService_GetSegmentsPage(t *testing.T) {
+	params := model.SegmentsPageParams{
+		Page:    2,
+		PerPage: 1,
+		SortBy:  model.SegmentsSortByMembersCount,
+	}
+	segments := []model.SegmentWithMembersCount{
+		{Segment: model.Segment{ID: 2, Slug: "AVITO_TECH"}, MembersCount: 10},
+	}
+	tests := []struct {
+		name              string
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		want              model.SegmentsPage
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().CountSegments(gomock.Any(), params.SegmentFilter).Return(3, nil)
+				repository.EXPECT().GetSegmentsPage(gomock.Any(), params).Return(segments, nil)
+			},
+			want: model.SegmentsPage{
+				Segments: segments,
+				Page:     2,
+				PerPage:  1,
+				Total:    3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "error from CountSegments()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().CountSegments(gomock.Any(), params.SegmentFilter).Return(0, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from GetSegmentsPage()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().CountSegments(gomock.Any(), params.SegmentFilter).Return(3, nil)
+				repository.EXPECT().GetSegmentsPage(gomock.Any(), params).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			if tt.segmentRepoBehave != nil {
+				tt.segmentRepoBehave(mockSegmentRepo)
+			}
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+			}
+
+			got, err := s.GetSegmentsPage(context.Background(), params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SegmentService.GetSegmentsPage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SegmentService.GetSegmentsPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentService_GetSegmentMembers(t *testing.T) {
+	segmentSlug := "test"
+	segment := &model.Segment{ID: 1, Slug: segmentSlug}
+	nextCursor := 20
+	tests := []struct {
+		name              string
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		want              model.SegmentMembersPage
+		wantErr           bool
+	}{
+		{
+			name: "has next page",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().GetSegmentMembers(gomock.Any(), 1, 0, 3).
+					Return([]model.SegmentMember{{UserID: 10}, {UserID: 20}, {UserID: 30}}, nil)
+			},
+			want: model.SegmentMembersPage{
+				Members:    []model.SegmentMember{{UserID: 10}, {UserID: 20}},
+				NextCursor: &nextCursor,
+			},
+			wantErr: false,
+		},
+		{
+			name: "last page",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().GetSegmentMembers(gomock.Any(), 1, 0, 3).
+					Return([]model.SegmentMember{{UserID: 10}}, nil)
+			},
+			want: model.SegmentMembersPage{
+				Members: []model.SegmentMember{{UserID: 10}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "segment does not exist",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from GetSegmentMembers()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().GetSegmentMembers(gomock.Any(), 1, 0, 3).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			if tt.segmentRepoBehave != nil {
+				tt.segmentRepoBehave(mockSegmentRepo)
+			}
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+			}
+
+			got, err := s.GetSegmentMembers(context.Background(), segmentSlug, 0, 2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SegmentService.GetSegmentMembers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SegmentService.GetSegmentMembers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentService_RestoreSegment(t *testing.T) {
+	restoredSegmentId := 1
+	segmentSlug := "test"
+	tests := []struct {
+		name              string
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().RestoreSegment(gomock.Any(), segmentSlug, gomock.Any()).Return(&restoredSegmentId, nil)
+				repository.EXPECT().GetSegmentUsers(gomock.Any(), restoredSegmentId).Return([]int{1, 2}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordMultipleUsersToHistory(gomock.Any(), model.HistoryDataMultipleUsers{
+					UsersIDs:    []int{1, 2},
+					SegmentSlug: segmentSlug,
+					Operation:   model.OperationRestored,
+					Source:      model.HistorySourceAPI,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "segment without members",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().RestoreSegment(gomock.Any(), segmentSlug, gomock.Any()).Return(&restoredSegmentId, nil)
+				repository.EXPECT().GetSegmentUsers(gomock.Any(), restoredSegmentId).Return(nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "nothing to restore",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().RestoreSegment(gomock.Any(), segmentSlug, gomock.Any()).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from RestoreSegment()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().RestoreSegment(gomock.Any(), segmentSlug, gomock.Any()).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.segmentRepoBehave != nil {
+				tt.segmentRepoBehave(mockSegmentRepo)
+			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+				cfg: config.SegmentConfig{
+					RestoreGracePeriod: time.Hour,
+				},
+			}
+
+			err := s.RestoreSegment(context.Background(), segmentSlug)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SegmentService.RestoreSegment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSegmentService_RenameSegment(t *testing.T) {
+	segmentSlug := "test"
+	newSlug := "test_renamed"
+	segment := &model.Segment{ID: 1, Slug: segmentSlug}
+	sqlErr := errors.New("sql error")
+	aliasErr := errors.New("slug is an alias of another segment")
+	tests := []struct {
+		name              string
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           error
+	}{
+		{
+			name: "success",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().RenameSegment(gomock.Any(), segment.ID, newSlug).Return(true, nil)
+				repository.EXPECT().CreateSegmentAlias(gomock.Any(), segmentSlug, segment.ID, gomock.Any()).Return(nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordSegmentEvent(gomock.Any(), model.SegmentEvent{
+					SegmentSlug: newSlug,
+					Operation:   model.OperationRenamed,
+					Source:      model.HistorySourceAPI,
+				}).Return(nil)
+			},
+			wantErr: nil,
+		},
+		{
+			name: "segment does not exist",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(nil, nil)
+			},
+			wantErr: ErrSegmentDoesNotExist,
+		},
+		{
+			name: "segment deleted before the rename",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().RenameSegment(gomock.Any(), segment.ID, newSlug).Return(false, nil)
+			},
+			wantErr: ErrSegmentDoesNotExist,
+		},
+		{
+			name: "new slug is an alias of another segment",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().RenameSegment(gomock.Any(), segment.ID, newSlug).Return(false, aliasErr)
+			},
+			wantErr: aliasErr,
+		},
+		{
+			name: "error from RenameSegment()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().RenameSegment(gomock.Any(), segment.ID, newSlug).Return(false, sqlErr)
+			},
+			wantErr: sqlErr,
+		},
+		{
+			name: "error from CreateSegmentAlias()",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegment(gomock.Any(), segmentSlug).Return(segment, nil)
+				repository.EXPECT().RenameSegment(gomock.Any(), segment.ID, newSlug).Return(true, nil)
+				repository.EXPECT().CreateSegmentAlias(gomock.Any(), segmentSlug, segment.ID, gomock.Any()).Return(sqlErr)
+			},
+			wantErr: sqlErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			if tt.segmentRepoBehave != nil {
+				tt.segmentRepoBehave(mockSegmentRepo)
+			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &SegmentService{
+				segmentRepo: mockSegmentRepo,
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+				cfg: config.SegmentConfig{
+					AliasTTL: time.Hour,
+				},
+			}
+
+			err := s.RenameSegment(context.Background(), segmentSlug, newSlug)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SegmentService.RenameSegment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"github.com/golang/mock/gomock"
+)
+
+// newMockTransactorPassThrough returns a Transactor mock that simply runs fn with the caller's context.
+func newMockTransactorPassThrough(ctrl *gomock.Controller) *MockTransactor {
+	transactor := NewMockTransactor(ctrl)
+	transactor.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		}).AnyTimes()
+
+	return transactor
+}
+
+type fakeTxKey struct{}
+
+// fakeTransactor stands for a database transaction: the repository calls made inside it get a marked context,
+// it is committed if fn succeeds and rolled back otherwise.
+type fakeTransactor struct {
+	committed  bool
+	rolledBack bool
+}
+
+func (t *fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(context.WithValue(ctx, fakeTxKey{}, t)); err != nil {
+		t.rolledBack = true
+		return err
+	}
+	t.committed = true
+
+	return nil
+}
+
+// inTransaction matches a context of a call made inside a fakeTransactor transaction.
+func inTransaction() gomock.Matcher {
+	return inTransactionMatcher{}
+}
+
+type inTransactionMatcher struct{}
+
+func (inTransactionMatcher) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && ctx.Value(fakeTxKey{}) != nil
+}
+
+func (inTransactionMatcher) String() string {
+	return "is a context inside a transaction"
+}
@@ -3,30 +3,43 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
 	"github.com/elgntt/segmentation-service/internal/pkg/app_err"
-	"golang.org/x/exp/slices"
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
 )
 
 type UserService struct {
 	userRepo    UserRepo
 	segmentRepo SegmentRepo
 	historyRepo HistoryRepo
+	transactor  Transactor
 }
 
-func NewUserService(userRepo UserRepo, segmentRepo SegmentRepo, historyRepo HistoryRepo) *UserService {
+func NewUserService(userRepo UserRepo, segmentRepo SegmentRepo, historyRepo HistoryRepo, transactor Transactor) *UserService {
 	return &UserService{
 		userRepo:    userRepo,
 		segmentRepo: segmentRepo,
 		historyRepo: historyRepo,
+		transactor:  transactor,
 	}
 }
 
 func (s *UserService) GetActiveUserSegments(ctx context.Context, userId int) ([]string, error) {
-	userSegments, err := s.userRepo.GetActiveUserSegments(ctx, userId)
+	var userSegments []string
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.registerUser(ctx, userId); err != nil {
+			return err
+		}
+
+		var err error
+		userSegments, err = s.userRepo.GetActiveUserSegments(ctx, userId)
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -34,28 +47,148 @@ func (s *UserService) GetActiveUserSegments(ctx context.Context, userId int) ([]
 	return userSegments, nil
 }
 
-func (s *UserService) UserSegmentAction(ctx context.Context, userSegment model.UserSegmentAction) error {
-	err := s.validateSegments(ctx, userSegment)
-	if err != nil {
+// GetActiveUsersSegments returns the active segments of each of the users, users without active segments get an empty list.
+// Like GetActiveUserSegments it registers unknown users first, all of them with one query.
+func (s *UserService) GetActiveUsersSegments(ctx context.Context, userIds []int) ([]model.UsersSegments, error) {
+	var segmentsByUser map[int][]string
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.registerUsers(ctx, userIds); err != nil {
+			return err
+		}
+
+		var err error
+		segmentsByUser, err = s.userRepo.GetActiveUsersSegments(ctx, userIds)
+
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if len(userSegment.SegmentsSlugsToAdd) != 0 {
-		err = s.AddUserToMultipleSegments(ctx, userSegment.SegmentExpirationTime, userSegment.SegmentsSlugsToAdd, userSegment.UserID)
+	usersSegments := make([]model.UsersSegments, 0, len(userIds))
+	for _, userId := range userIds {
+		segmentSlugs := segmentsByUser[userId]
+		if segmentSlugs == nil {
+			segmentSlugs = []string{}
+		}
+		usersSegments = append(usersSegments, model.UsersSegments{
+			UserId:       userId,
+			SegmentSlugs: segmentSlugs,
+		})
+	}
+
+	return usersSegments, nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, userId int) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		created, err := s.userRepo.CreateUser(ctx, userId)
 		if err != nil {
 			return err
 		}
+
+		if !created {
+			return ErrUserAlreadyExists
+		}
+
+		return s.addUserToPercentSegments(ctx, userId, model.HistorySourceAutoJoin)
+	})
+}
+
+func (s *UserService) ImportUsers(ctx context.Context, usersIDs []int) (model.ImportUsersResult, error) {
+	result := model.ImportUsersResult{}
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, userId := range usersIDs {
+			created, err := s.userRepo.CreateUser(ctx, userId)
+			if err != nil {
+				return err
+			}
+
+			if !created {
+				result.AlreadyExisted++
+				continue
+			}
+
+			result.Imported++
+			if err := s.addUserToPercentSegments(ctx, userId, model.HistorySourceImport); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return model.ImportUsersResult{}, err
 	}
 
-	if len(userSegment.SegmentsSlugsToRemove) != 0 {
-		return s.RemoveUserFromMultipleSegments(ctx, userSegment.SegmentsSlugsToRemove, userSegment.UserID)
+	return result, nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, userId int) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		deletedSegmentsSlugs, err := s.userRepo.RemoveUserFromAllSegments(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := s.userRepo.DeleteUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrUserDoesNotExist
+		}
+
+		if deletedSegmentsSlugs == nil {
+			return nil
+		}
+
+		return s.RecordUserMultipleSegmentsToHistory(ctx, deletedSegmentsSlugs, model.OperationRemoval, model.HistorySourceAPI, userId)
+	})
+}
+
+// registerUser stores a user seen for the first time and evaluates it against every auto-join segment.
+func (s *UserService) registerUser(ctx context.Context, userId int) error {
+	created, err := s.userRepo.CreateUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		return nil
+	}
+
+	return s.addUserToPercentSegments(ctx, userId, model.HistorySourceAutoJoin)
+}
+
+// registerUsers is registerUser for many users at once.
+func (s *UserService) registerUsers(ctx context.Context, userIds []int) error {
+	joinedSegments, err := s.userRepo.CreateUsersWithAutoJoin(ctx, userIds)
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range userIds {
+		addedSlugs := joinedSegments[userId]
+		if addedSlugs == nil {
+			continue
+		}
+
+		// a user listed twice is recorded once
+		delete(joinedSegments, userId)
+		err = s.RecordUserMultipleSegmentsToHistory(ctx, addedSlugs, model.OperationAdding, model.HistorySourceAutoJoin, userId)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (s *UserService) AddUserToMultipleSegments(ctx context.Context, expirationTime *time.Time, segmentsSlugs []string, userId int) error {
-	addedSlugs, err := s.userRepo.AddUserToMultipleSegments(ctx, expirationTime, segmentsSlugs, userId)
+// addUserToPercentSegments records the memberships with the given source, the users get there by auto-join either way.
+func (s *UserService) addUserToPercentSegments(ctx context.Context, userId int, source string) error {
+	addedSlugs, err := s.userRepo.AddUserToPercentSegments(ctx, userId)
 	if err != nil {
 		return err
 	}
@@ -64,7 +197,117 @@ func (s *UserService) AddUserToMultipleSegments(ctx context.Context, expirationT
 		return nil
 	}
 
-	return s.RecordUserMultipleSegmentsToHistory(ctx, addedSlugs, addOperationStr, userId)
+	return s.RecordUserMultipleSegmentsToHistory(ctx, addedSlugs, model.OperationAdding, source, userId)
+}
+
+// UserSegmentAction checks the user and the segments in the same transaction that changes the memberships,
+// so a user or a segment deleted meanwhile fails the whole action.
+func (s *UserService) UserSegmentAction(ctx context.Context, userSegment model.UserSegmentAction) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		exists, err := s.userRepo.UserExists(ctx, userSegment.UserID)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return ErrUserDoesNotExist
+		}
+
+		userSegment, err = s.resolveSegmentAliases(ctx, userSegment)
+		if err != nil {
+			return err
+		}
+
+		err = s.validateSegments(ctx, userSegment)
+		if err != nil {
+			return err
+		}
+
+		if len(userSegment.SegmentsSlugsToAdd) != 0 {
+			err := s.AddUserToMultipleSegments(ctx, userSegment.UserID, segmentExpirations(userSegment, time.Now()), userSegment.Upsert)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(userSegment.SegmentsSlugsToRemove) != 0 {
+			return s.RemoveUserFromMultipleSegments(ctx, userSegment.SegmentsSlugsToRemove, userSegment.UserID)
+		}
+
+		return nil
+	})
+}
+
+// AddUserToMultipleSegments adds the user to the segments. With upsert the expiration time of the memberships the user already has is replaced
+// and the memberships that have not started yet are rescheduled. Adding of scheduled memberships is recorded when they are activated.
+func (s *UserService) AddUserToMultipleSegments(ctx context.Context, userId int, segments []model.SegmentExpiration, upsert bool) error {
+	addedSlugs, changedSlugs, err := s.userRepo.AddUserToMultipleSegments(ctx, userId, segments, upsert)
+	if err != nil {
+		return err
+	}
+
+	scheduledSlugs := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment.StartTime != nil {
+			scheduledSlugs = append(scheduledSlugs, segment.SegmentSlug)
+		}
+	}
+	addedSlugs = findAbsenceInSecondSlice(addedSlugs, scheduledSlugs)
+
+	if len(addedSlugs) != 0 {
+		err = s.RecordUserMultipleSegmentsToHistory(ctx, addedSlugs, model.OperationAdding, model.HistorySourceAPI, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changedSlugs) != 0 {
+		return s.RecordUserMultipleSegmentsToHistory(ctx, changedSlugs, model.OperationExpirationChanged, model.HistorySourceAPI, userId)
+	}
+
+	return nil
+}
+
+// segmentExpirations pairs the segments to add with their start and expiration times. An expiration time or a ttl of the segment
+// takes precedence over the common ones of the action, ttls count from the start time. Segments without any get the default ttl of the segment
+// unless the action asks for no expiration.
+// A start time that is not in the future starts the memberships right away.
+func segmentExpirations(userSegment model.UserSegmentAction, now time.Time) []model.SegmentExpiration {
+	startTime := userSegment.StartTime
+	if startTime != nil && !startTime.After(now) {
+		startTime = nil
+	}
+
+	expiresAfter := func(ttl model.TTL) *time.Time {
+		from := now
+		if startTime != nil {
+			from = *startTime
+		}
+		expirationTime := from.Add(time.Duration(ttl))
+		return &expirationTime
+	}
+
+	segments := make([]model.SegmentExpiration, 0, len(userSegment.SegmentsSlugsToAdd))
+	for _, slug := range userSegment.SegmentsSlugsToAdd {
+		segment := model.SegmentExpiration{
+			SegmentSlug: slug,
+			StartTime:   startTime,
+		}
+		if expirationTime, ok := userSegment.SegmentExpirations[slug]; ok {
+			segment.ExpirationTime = expirationTime
+		} else if ttl, ok := userSegment.SegmentTTLs[slug]; ok {
+			segment.ExpirationTime = expiresAfter(ttl)
+		} else if userSegment.SegmentExpirationTime != nil {
+			segment.ExpirationTime = userSegment.SegmentExpirationTime
+		} else if userSegment.TTL != nil {
+			segment.ExpirationTime = expiresAfter(*userSegment.TTL)
+		} else if !userSegment.NoExpiration {
+			segment.UseDefaultTTL = true
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments
 }
 
 func (s *UserService) RemoveUserFromMultipleSegments(ctx context.Context, segmentsSlugs []string, userId int) error {
@@ -76,19 +319,74 @@ func (s *UserService) RemoveUserFromMultipleSegments(ctx context.Context, segmen
 		return nil
 	}
 
-	return s.RecordUserMultipleSegmentsToHistory(ctx, deletedSegmentsSlugs, removeOperationStr, userId)
+	return s.RecordUserMultipleSegmentsToHistory(ctx, deletedSegmentsSlugs, model.OperationRemoval, model.HistorySourceAPI, userId)
 }
 
-func (s *UserService) RecordUserMultipleSegmentsToHistory(ctx context.Context, segmentsSlugs []string, operation string, userId int) error {
+func (s *UserService) RecordUserMultipleSegmentsToHistory(ctx context.Context, segmentsSlugs []string, operation model.Operation, source string, userId int) error {
+	meta := audit.FromContext(ctx)
 	historyData := model.HistoryDataMultipleSegments{
 		UserId:      userId,
 		SegmentSlug: segmentsSlugs,
 		Operation:   operation,
+		Source:      source,
+		ActorID:     meta.ActorID,
+		RequestID:   meta.RequestID,
 	}
 
 	return s.historyRepo.RecordUserMultipleSegmentsToHistory(ctx, historyData)
 }
 
+// resolveSegmentAliases replaces old slugs of renamed segments with their current slugs.
+func (s *UserService) resolveSegmentAliases(ctx context.Context, userSegment model.UserSegmentAction) (model.UserSegmentAction, error) {
+	totalUserSegments := make([]string, 0, len(userSegment.SegmentsSlugsToAdd)+len(userSegment.SegmentsSlugsToRemove))
+	totalUserSegments = append(totalUserSegments, userSegment.SegmentsSlugsToAdd...)
+	totalUserSegments = append(totalUserSegments, userSegment.SegmentsSlugsToRemove...)
+
+	aliases, err := s.segmentRepo.ResolveSegmentAliases(ctx, totalUserSegments)
+	if err != nil {
+		return model.UserSegmentAction{}, err
+	}
+
+	if len(aliases) == 0 {
+		return userSegment, nil
+	}
+
+	userSegment.SegmentsSlugsToAdd = replaceAliases(userSegment.SegmentsSlugsToAdd, aliases)
+	userSegment.SegmentsSlugsToRemove = replaceAliases(userSegment.SegmentsSlugsToRemove, aliases)
+	userSegment.SegmentExpirations = replaceAliasKeys(userSegment.SegmentExpirations, aliases)
+	userSegment.SegmentTTLs = replaceAliasKeys(userSegment.SegmentTTLs, aliases)
+
+	return userSegment, nil
+}
+
+func replaceAliases(slugs []string, aliases map[string]string) []string {
+	resolvedSlugs := make([]string, 0, len(slugs))
+	for _, slug := range slugs {
+		if resolvedSlug, ok := aliases[slug]; ok {
+			slug = resolvedSlug
+		}
+		resolvedSlugs = append(resolvedSlugs, slug)
+	}
+
+	return resolvedSlugs
+}
+
+func replaceAliasKeys[V any](values map[string]V, aliases map[string]string) map[string]V {
+	if len(values) == 0 {
+		return values
+	}
+
+	resolvedValues := make(map[string]V, len(values))
+	for slug, value := range values {
+		if resolvedSlug, ok := aliases[slug]; ok {
+			slug = resolvedSlug
+		}
+		resolvedValues[slug] = value
+	}
+
+	return resolvedValues
+}
+
 func findAbsenceInSecondSlice(first, second []string) []string {
 	var hash = make(map[string]bool, len(second))
 	for _, elem := range second {
@@ -113,7 +411,7 @@ func (s *UserService) validateSegments(ctx context.Context, userSegment model.Us
 
 	missingSegments := findAbsenceInSecondSlice(totalUserSegments, segments)
 	if missingSegments != nil {
-		return app_err.NewBusinessError(fmt.Sprintf("these segments do not exist: [%s]", (strings.Join(slices.Compact(missingSegments), ", "))))
+		return app_err.NewNotFoundError(app_err.Code(ErrSegmentDoesNotExist), fmt.Sprintf("these segments do not exist: [%s]", (strings.Join(slices.Compact(missingSegments), ", "))))
 	}
 
 	return nil
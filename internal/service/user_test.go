@@ -8,22 +8,25 @@ import (
 	"time"
 
 	"github.com/elgntt/segmentation-service/internal/model"
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
 	gomock "github.com/golang/mock/gomock"
 )
 
 func TestUserService_GetActiveUserSegments(t *testing.T) {
 	userId := 100
 	tests := []struct {
-		name           string
-		userRepoBehave func(repository *MockUserRepo)
-		userId         int
-		want           []string
-		wantErr        bool
+		name              string
+		userRepoBehave    func(repository *MockUserRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		userId            int
+		want              []string
+		wantErr           bool
 	}{
 		{
 			name:   "success",
 			userId: userId,
 			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(false, nil)
 				repository.EXPECT().GetActiveUserSegments(gomock.Any(), userId).Return([]string{"AVITO_TECH", "AVITO_DISCOUNT_30"}, nil)
 			},
 
@@ -34,16 +37,69 @@ func TestUserService_GetActiveUserSegments(t *testing.T) {
 			name:   "no segments to user",
 			userId: userId,
 			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(false, nil)
+				repository.EXPECT().GetActiveUserSegments(gomock.Any(), userId).Return([]string{}, nil)
+			},
+
+			want:    []string{},
+			wantErr: false,
+		},
+		{
+			name:   "new user joins auto-join segments",
+			userId: userId,
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToPercentSegments(gomock.Any(), userId).Return([]string{"AVITO_DISCOUNT_30"}, nil)
+				repository.EXPECT().GetActiveUserSegments(gomock.Any(), userId).Return([]string{"AVITO_DISCOUNT_30"}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      userId,
+					SegmentSlug: []string{"AVITO_DISCOUNT_30"},
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+
+			want:    []string{"AVITO_DISCOUNT_30"},
+			wantErr: false,
+		},
+		{
+			name:   "new user without auto-join segments",
+			userId: userId,
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToPercentSegments(gomock.Any(), userId).Return(nil, nil)
 				repository.EXPECT().GetActiveUserSegments(gomock.Any(), userId).Return([]string{}, nil)
 			},
 
 			want:    []string{},
 			wantErr: false,
 		},
+		{
+			name:   "error from accessing the CreateUser() repository",
+			userId: userId,
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(false, errors.New("sql error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:   "error from accessing the AddUserToPercentSegments() repository",
+			userId: userId,
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToPercentSegments(gomock.Any(), userId).Return(nil, errors.New("sql error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:   "error from accessing the GetActiveUserSegments() repository",
 			userId: userId,
 			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(false, nil)
 				repository.EXPECT().GetActiveUserSegments(gomock.Any(), userId).Return(nil, errors.New("sql error"))
 			},
 			want:    nil,
@@ -61,11 +117,15 @@ func TestUserService_GetActiveUserSegments(t *testing.T) {
 			if tt.userRepoBehave != nil {
 				tt.userRepoBehave(mockUserRepo)
 			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
 
 			s := &UserService{
 				userRepo:    mockUserRepo,
 				segmentRepo: mockSegmentRepo,
 				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
 			}
 			got, err := s.GetActiveUserSegments(context.Background(), tt.userId)
 			if (err != nil) != tt.wantErr {
@@ -79,6 +139,116 @@ func TestUserService_GetActiveUserSegments(t *testing.T) {
 	}
 }
 
+func TestUserService_GetActiveUsersSegments(t *testing.T) {
+	userIds := []int{100, 200, 300}
+	tests := []struct {
+		name              string
+		userRepoBehave    func(repository *MockUserRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		want              []model.UsersSegments
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUsersWithAutoJoin(gomock.Any(), userIds).Return(map[int][]string{}, nil)
+				repository.EXPECT().GetActiveUsersSegments(gomock.Any(), userIds).Return(map[int][]string{
+					100: {"AVITO_TECH", "AVITO_DISCOUNT_30"},
+					300: {"AVITO_DISCOUNT_30"},
+				}, nil)
+			},
+			want: []model.UsersSegments{
+				{UserId: 100, SegmentSlugs: []string{"AVITO_TECH", "AVITO_DISCOUNT_30"}},
+				{UserId: 200, SegmentSlugs: []string{}},
+				{UserId: 300, SegmentSlugs: []string{"AVITO_DISCOUNT_30"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "new users are auto-joined",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUsersWithAutoJoin(gomock.Any(), userIds).Return(map[int][]string{
+					200: {"AVITO_TECH", "AVITO_DISCOUNT_30"},
+				}, nil)
+				repository.EXPECT().GetActiveUsersSegments(gomock.Any(), userIds).Return(map[int][]string{
+					200: {"AVITO_TECH", "AVITO_DISCOUNT_30"},
+				}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      200,
+					SegmentSlug: []string{"AVITO_TECH", "AVITO_DISCOUNT_30"},
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+			want: []model.UsersSegments{
+				{UserId: 100, SegmentSlugs: []string{}},
+				{UserId: 200, SegmentSlugs: []string{"AVITO_TECH", "AVITO_DISCOUNT_30"}},
+				{UserId: 300, SegmentSlugs: []string{}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "error from accessing the CreateUsersWithAutoJoin() repository",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUsersWithAutoJoin(gomock.Any(), userIds).Return(nil, errors.New("sql error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "error from recording the auto-join",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUsersWithAutoJoin(gomock.Any(), userIds).Return(map[int][]string{
+					100: {"AVITO_TECH"},
+				}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), gomock.Any()).Return(errors.New("sql error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "error from accessing the GetActiveUsersSegments() repository",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUsersWithAutoJoin(gomock.Any(), userIds).Return(map[int][]string{}, nil)
+				repository.EXPECT().GetActiveUsersSegments(gomock.Any(), userIds).Return(nil, errors.New("sql error"))
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUserRepo := NewMockUserRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			tt.userRepoBehave(mockUserRepo)
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &UserService{
+				userRepo:    mockUserRepo,
+				segmentRepo: NewMockSegmentRepo(ctrl),
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+			}
+			got, err := s.GetActiveUsersSegments(context.Background(), userIds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserService.GetActiveUsersSegments() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UserService.GetActiveUsersSegments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestUserService_UserSegmentAction(t *testing.T) {
 	userId := 100
 	segmentsToAdd := []string{"AVITO_TECH", "AVITO_DISCOUNT_30", "AVITO_DISCOUNT_11"}
@@ -86,6 +256,12 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 	notExistsSegments := []string{"RANDOM", "TEST", "SEGMENT"}
 	allSegments := append(segmentsToAdd, segmentsToRemove...)
 	expirationTime := time.Now().Add(10 * time.Hour)
+	longerExpirationTime := expirationTime.Add(24 * time.Hour)
+	startTime := expirationTime.Add(time.Hour)
+	expiringSegmentsToAdd := make([]model.SegmentExpiration, 0, len(segmentsToAdd))
+	for _, slug := range segmentsToAdd {
+		expiringSegmentsToAdd = append(expiringSegmentsToAdd, model.SegmentExpiration{SegmentSlug: slug, ExpirationTime: &expirationTime})
+	}
 
 	repoError := "repo error"
 	tests := []struct {
@@ -105,22 +281,26 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return(allSegments, nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), &expirationTime, segmentsToAdd, userId).Return(segmentsToAdd, nil)
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, expiringSegmentsToAdd, false).Return(segmentsToAdd, nil, nil)
 				repository.EXPECT().RemoveUserFromMultipleSegments(gomock.Any(), segmentsToRemove, userId).Return(segmentsToRemove, nil)
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToAdd,
-					Operation:   addOperationStr,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAPI,
 				}).Return(nil)
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToRemove,
-					Operation:   removeOperationStr,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceAPI,
 				}).Return(nil)
 			},
 			wantErr: false,
@@ -134,16 +314,19 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), segmentsToAdd).Return(segmentsToAdd, nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), &expirationTime, segmentsToAdd, userId).Return(segmentsToAdd, nil)
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, expiringSegmentsToAdd, false).Return(segmentsToAdd, nil, nil)
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToAdd,
-					Operation:   addOperationStr,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAPI,
 				}).Return(nil)
 			},
 			wantErr: false,
@@ -157,16 +340,19 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), segmentsToRemove).Return(segmentsToRemove, nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
 				repository.EXPECT().RemoveUserFromMultipleSegments(gomock.Any(), segmentsToRemove, userId).Return(segmentsToRemove, nil)
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToRemove,
-					Operation:   removeOperationStr,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceAPI,
 				}).Return(nil)
 			},
 			wantErr: false,
@@ -180,8 +366,12 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return(allSegments, errors.New(repoError))
 			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+			},
 			wantErr: true,
 		},
 		{
@@ -193,10 +383,12 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return(allSegments, nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), &expirationTime, segmentsToAdd, userId).Return(nil, errors.New(repoError))
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, expiringSegmentsToAdd, false).Return(nil, nil, errors.New(repoError))
 			},
 			wantErr: true,
 		},
@@ -209,17 +401,20 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return(allSegments, nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), &expirationTime, segmentsToAdd, userId).Return(segmentsToAdd, nil)
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, expiringSegmentsToAdd, false).Return(segmentsToAdd, nil, nil)
 				repository.EXPECT().RemoveUserFromMultipleSegments(gomock.Any(), segmentsToRemove, userId).Return(segmentsToRemove, errors.New(repoError))
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToAdd,
-					Operation:   addOperationStr,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAPI,
 				}).Return(nil)
 			},
 			wantErr: true,
@@ -233,22 +428,26 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return(allSegments, nil)
 			},
 			userRepoBehave: func(repository *MockUserRepo) {
-				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), &expirationTime, segmentsToAdd, userId).Return(segmentsToAdd, nil)
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, expiringSegmentsToAdd, false).Return(segmentsToAdd, nil, nil)
 				repository.EXPECT().RemoveUserFromMultipleSegments(gomock.Any(), segmentsToRemove, userId).Return(segmentsToRemove, nil)
 			},
 			historyRepoBehave: func(repository *MockHistoryRepo) {
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToAdd,
-					Operation:   addOperationStr,
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAPI,
 				}).Return(nil)
 				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
 					UserId:      userId,
 					SegmentSlug: segmentsToRemove,
-					Operation:   removeOperationStr,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceAPI,
 				}).Return(errors.New(repoError))
 			},
 			wantErr: true,
@@ -262,8 +461,12 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), append(notExistsSegments, segmentsToRemove...)).Return(nil, errors.New(repoError))
 			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+			},
 			wantErr: true,
 		},
 		{
@@ -275,8 +478,167 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				SegmentExpirationTime: &expirationTime,
 			},
 			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
 				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), append(segmentsToAdd, notExistsSegments...)).Return(nil, errors.New(repoError))
 			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "old slug of renamed segment",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    []string{"AVITO_OLD_TECH"},
+				SegmentsSlugsToRemove: []string{},
+				SegmentExpirationTime: &expirationTime,
+			},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), []string{"AVITO_OLD_TECH"}).Return(map[string]string{
+					"AVITO_OLD_TECH": "AVITO_TECH",
+				}, nil)
+				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), []string{"AVITO_TECH"}).Return([]string{"AVITO_TECH"}, nil)
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, []model.SegmentExpiration{
+					{SegmentSlug: "AVITO_TECH", ExpirationTime: &expirationTime},
+				}, false).Return([]string{"AVITO_TECH"}, nil, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      userId,
+					SegmentSlug: []string{"AVITO_TECH"},
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAPI,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "upsert with expiration per segment",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    []string{"AVITO_TECH", "AVITO_OLD_DISCOUNT_30", "AVITO_DISCOUNT_11"},
+				SegmentsSlugsToRemove: []string{},
+				SegmentExpirationTime: &expirationTime,
+				SegmentExpirations: map[string]*time.Time{
+					"AVITO_OLD_DISCOUNT_30": &longerExpirationTime,
+					"AVITO_DISCOUNT_11":     nil,
+				},
+				Upsert: true,
+			},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), []string{"AVITO_TECH", "AVITO_OLD_DISCOUNT_30", "AVITO_DISCOUNT_11"}).Return(map[string]string{
+					"AVITO_OLD_DISCOUNT_30": "AVITO_DISCOUNT_30",
+				}, nil)
+				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), segmentsToAdd).Return(segmentsToAdd, nil)
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, []model.SegmentExpiration{
+					{SegmentSlug: "AVITO_TECH", ExpirationTime: &expirationTime},
+					{SegmentSlug: "AVITO_DISCOUNT_30", ExpirationTime: &longerExpirationTime},
+					{SegmentSlug: "AVITO_DISCOUNT_11", ExpirationTime: nil},
+				}, true).Return([]string{"AVITO_TECH"}, []string{"AVITO_DISCOUNT_30", "AVITO_DISCOUNT_11"}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      userId,
+					SegmentSlug: []string{"AVITO_TECH"},
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAPI,
+				}).Return(nil)
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      userId,
+					SegmentSlug: []string{"AVITO_DISCOUNT_30", "AVITO_DISCOUNT_11"},
+					Operation:   model.OperationExpirationChanged,
+					Source:      model.HistorySourceAPI,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "scheduled memberships",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    []string{"AVITO_TECH"},
+				SegmentsSlugsToRemove: []string{},
+				StartTime:             &startTime,
+				SegmentExpirationTime: &longerExpirationTime,
+			},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
+				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), []string{"AVITO_TECH"}).Return([]string{"AVITO_TECH"}, nil)
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, []model.SegmentExpiration{
+					{SegmentSlug: "AVITO_TECH", ExpirationTime: &longerExpirationTime, StartTime: &startTime},
+				}, false).Return([]string{"AVITO_TECH"}, nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "nothing changed",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    segmentsToAdd,
+				SegmentsSlugsToRemove: []string{},
+				SegmentExpirationTime: &expirationTime,
+				Upsert:                true,
+			},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), gomock.Any()).Return(nil, nil)
+				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), segmentsToAdd).Return(segmentsToAdd, nil)
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToMultipleSegments(gomock.Any(), userId, expiringSegmentsToAdd, true).Return([]string{}, nil, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "error from ResolveSegmentAliases()",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    segmentsToAdd,
+				SegmentsSlugsToRemove: segmentsToRemove,
+				SegmentExpirationTime: &expirationTime,
+			},
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().ResolveSegmentAliases(gomock.Any(), allSegments).Return(nil, errors.New(repoError))
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(true, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "user does not exist",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    segmentsToAdd,
+				SegmentsSlugsToRemove: segmentsToRemove,
+				SegmentExpirationTime: &expirationTime,
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(false, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from UserExists()",
+			userSegments: model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    segmentsToAdd,
+				SegmentsSlugsToRemove: segmentsToRemove,
+				SegmentExpirationTime: &expirationTime,
+			},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().UserExists(gomock.Any(), userId).Return(false, errors.New(repoError))
+			},
 			wantErr: true,
 		},
 	}
@@ -303,6 +665,7 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 				userRepo:    mockUserRepo,
 				segmentRepo: mockSegmentRepo,
 				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
 			}
 			if err := s.UserSegmentAction(context.Background(), tt.userSegments); (err != nil) != tt.wantErr {
 				t.Errorf("UserService.UserSegmentAction() error = %v, wantErr %v", err, tt.wantErr)
@@ -311,6 +674,383 @@ func TestUserService_UserSegmentAction(t *testing.T) {
 	}
 }
 
+func TestUserService_UserSegmentActionRollback(t *testing.T) {
+	userId := 100
+	segmentsToAdd := []string{"AVITO_TECH"}
+	segmentsToRemove := []string{"AVITO_DISCOUNT_5"}
+	allSegments := append(segmentsToAdd, segmentsToRemove...)
+	segmentsToAddExpirations := []model.SegmentExpiration{{SegmentSlug: "AVITO_TECH", UseDefaultTTL: true}}
+	addingHistory := model.HistoryDataMultipleSegments{
+		UserId:      userId,
+		SegmentSlug: segmentsToAdd,
+		Operation:   model.OperationAdding,
+		Source:      model.HistorySourceAPI,
+	}
+	removalHistory := model.HistoryDataMultipleSegments{
+		UserId:      userId,
+		SegmentSlug: segmentsToRemove,
+		Operation:   model.OperationRemoval,
+		Source:      model.HistorySourceAPI,
+	}
+
+	tests := []struct {
+		name              string
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name: "history written, the transaction is committed",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(inTransaction(), addingHistory).Return(nil)
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(inTransaction(), removalHistory).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "history of the removal fails, the added and removed memberships are rolled back",
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(inTransaction(), addingHistory).Return(nil)
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(inTransaction(), removalHistory).Return(errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			mockSegmentRepo.EXPECT().ResolveSegmentAliases(inTransaction(), allSegments).Return(nil, nil)
+			mockSegmentRepo.EXPECT().GetSegmentsBySlug(inTransaction(), allSegments).Return(allSegments, nil)
+
+			mockUserRepo := NewMockUserRepo(ctrl)
+			mockUserRepo.EXPECT().UserExists(inTransaction(), userId).Return(true, nil)
+			mockUserRepo.EXPECT().AddUserToMultipleSegments(inTransaction(), userId, segmentsToAddExpirations, false).Return(segmentsToAdd, nil, nil)
+			mockUserRepo.EXPECT().RemoveUserFromMultipleSegments(inTransaction(), segmentsToRemove, userId).Return(segmentsToRemove, nil)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			tt.historyRepoBehave(mockHistoryRepo)
+
+			transactor := &fakeTransactor{}
+			s := &UserService{
+				userRepo:    mockUserRepo,
+				segmentRepo: mockSegmentRepo,
+				historyRepo: mockHistoryRepo,
+				transactor:  transactor,
+			}
+			err := s.UserSegmentAction(context.Background(), model.UserSegmentAction{
+				UserID:                userId,
+				SegmentsSlugsToAdd:    segmentsToAdd,
+				SegmentsSlugsToRemove: segmentsToRemove,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserService.UserSegmentAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if transactor.committed == tt.wantErr || transactor.rolledBack != tt.wantErr {
+				t.Errorf("UserService.UserSegmentAction() committed = %v, rolled back = %v, wantErr %v", transactor.committed, transactor.rolledBack, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserService_CreateUser(t *testing.T) {
+	userId := 100
+	tests := []struct {
+		name              string
+		userRepoBehave    func(repository *MockUserRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(true, nil)
+				repository.EXPECT().AddUserToPercentSegments(gomock.Any(), userId).Return([]string{"AVITO_DISCOUNT_30"}, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      userId,
+					SegmentSlug: []string{"AVITO_DISCOUNT_30"},
+					Operation:   model.OperationAdding,
+					Source:      model.HistorySourceAutoJoin,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "user already exists",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(false, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from CreateUser()",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), userId).Return(false, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUserRepo := NewMockUserRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+
+			if tt.userRepoBehave != nil {
+				tt.userRepoBehave(mockUserRepo)
+			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &UserService{
+				userRepo:    mockUserRepo,
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+			}
+			if err := s.CreateUser(context.Background(), userId); (err != nil) != tt.wantErr {
+				t.Errorf("UserService.CreateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserService_ImportUsers(t *testing.T) {
+	tests := []struct {
+		name           string
+		usersIDs       []int
+		userRepoBehave func(repository *MockUserRepo)
+		want           model.ImportUsersResult
+		wantErr        bool
+	}{
+		{
+			name:     "new and existing users",
+			usersIDs: []int{1, 2},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), 1).Return(true, nil)
+				repository.EXPECT().AddUserToPercentSegments(gomock.Any(), 1).Return(nil, nil)
+				repository.EXPECT().CreateUser(gomock.Any(), 2).Return(false, nil)
+			},
+			want:    model.ImportUsersResult{Imported: 1, AlreadyExisted: 1},
+			wantErr: false,
+		},
+		{
+			name:     "error from CreateUser()",
+			usersIDs: []int{1, 2},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), 1).Return(false, errors.New("sql error"))
+			},
+			want:    model.ImportUsersResult{},
+			wantErr: true,
+		},
+		{
+			name:     "error from AddUserToPercentSegments()",
+			usersIDs: []int{1},
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().CreateUser(gomock.Any(), 1).Return(true, nil)
+				repository.EXPECT().AddUserToPercentSegments(gomock.Any(), 1).Return(nil, errors.New("sql error"))
+			},
+			want:    model.ImportUsersResult{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUserRepo := NewMockUserRepo(ctrl)
+			if tt.userRepoBehave != nil {
+				tt.userRepoBehave(mockUserRepo)
+			}
+
+			s := &UserService{
+				userRepo:    mockUserRepo,
+				historyRepo: NewMockHistoryRepo(ctrl),
+				transactor:  newMockTransactorPassThrough(ctrl),
+			}
+			got, err := s.ImportUsers(context.Background(), tt.usersIDs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UserService.ImportUsers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UserService.ImportUsers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserService_DeleteUser(t *testing.T) {
+	userId := 100
+	segmentsSlugs := []string{"AVITO_TECH", "AVITO_DISCOUNT_30"}
+	tests := []struct {
+		name              string
+		userRepoBehave    func(repository *MockUserRepo)
+		historyRepoBehave func(repository *MockHistoryRepo)
+		wantErr           bool
+	}{
+		{
+			name: "success",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().RemoveUserFromAllSegments(gomock.Any(), userId).Return(segmentsSlugs, nil)
+				repository.EXPECT().DeleteUser(gomock.Any(), userId).Return(true, nil)
+			},
+			historyRepoBehave: func(repository *MockHistoryRepo) {
+				repository.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), model.HistoryDataMultipleSegments{
+					UserId:      userId,
+					SegmentSlug: segmentsSlugs,
+					Operation:   model.OperationRemoval,
+					Source:      model.HistorySourceAPI,
+				}).Return(nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "user without segments",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().RemoveUserFromAllSegments(gomock.Any(), userId).Return(nil, nil)
+				repository.EXPECT().DeleteUser(gomock.Any(), userId).Return(true, nil)
+			},
+			wantErr: false,
+		},
+		{
+			name: "user does not exist",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().RemoveUserFromAllSegments(gomock.Any(), userId).Return(nil, nil)
+				repository.EXPECT().DeleteUser(gomock.Any(), userId).Return(false, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "error from RemoveUserFromAllSegments()",
+			userRepoBehave: func(repository *MockUserRepo) {
+				repository.EXPECT().RemoveUserFromAllSegments(gomock.Any(), userId).Return(nil, errors.New("sql error"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockUserRepo := NewMockUserRepo(ctrl)
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+
+			if tt.userRepoBehave != nil {
+				tt.userRepoBehave(mockUserRepo)
+			}
+			if tt.historyRepoBehave != nil {
+				tt.historyRepoBehave(mockHistoryRepo)
+			}
+
+			s := &UserService{
+				userRepo:    mockUserRepo,
+				historyRepo: mockHistoryRepo,
+				transactor:  newMockTransactorPassThrough(ctrl),
+			}
+			if err := s.DeleteUser(context.Background(), userId); (err != nil) != tt.wantErr {
+				t.Errorf("UserService.DeleteUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserService_RecordUserMultipleSegmentsToHistory(t *testing.T) {
+	userId := 1000
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want model.HistoryDataMultipleSegments
+	}{
+		{
+			name: "actor and request from context",
+			ctx:  audit.WithMeta(context.Background(), audit.Meta{ActorID: "admin", RequestID: "9f1c"}),
+			want: model.HistoryDataMultipleSegments{
+				UserId:      userId,
+				SegmentSlug: []string{"AVITO_TECH"},
+				Operation:   model.OperationAdding,
+				Source:      model.HistorySourceAPI,
+				ActorID:     "admin",
+				RequestID:   "9f1c",
+			},
+		},
+		{
+			name: "no meta in context",
+			ctx:  context.Background(),
+			want: model.HistoryDataMultipleSegments{
+				UserId:      userId,
+				SegmentSlug: []string{"AVITO_TECH"},
+				Operation:   model.OperationAdding,
+				Source:      model.HistorySourceAPI,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockHistoryRepo := NewMockHistoryRepo(ctrl)
+			mockHistoryRepo.EXPECT().RecordUserMultipleSegmentsToHistory(gomock.Any(), tt.want).Return(nil)
+
+			s := &UserService{
+				historyRepo: mockHistoryRepo,
+			}
+			err := s.RecordUserMultipleSegmentsToHistory(tt.ctx, []string{"AVITO_TECH"}, model.OperationAdding, model.HistorySourceAPI, userId)
+			if err != nil {
+				t.Errorf("UserService.RecordUserMultipleSegmentsToHistory() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestUserService_validateSegments(t *testing.T) {
+	userSegment := model.UserSegmentAction{
+		UserID:                1000,
+		SegmentsSlugsToAdd:    []string{"AVITO_TECH"},
+		SegmentsSlugsToRemove: []string{"AVITO_VOICE"},
+	}
+	allSegments := []string{"AVITO_TECH", "AVITO_VOICE"}
+	tests := []struct {
+		name              string
+		segmentRepoBehave func(repository *MockSegmentRepo)
+		wantErr           error
+	}{
+		{
+			name: "all segments exist",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return(allSegments, nil)
+			},
+			wantErr: nil,
+		},
+		{
+			name: "segment does not exist",
+			segmentRepoBehave: func(repository *MockSegmentRepo) {
+				repository.EXPECT().GetSegmentsBySlug(gomock.Any(), allSegments).Return([]string{"AVITO_TECH"}, nil)
+			},
+			wantErr: ErrSegmentDoesNotExist,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockSegmentRepo := NewMockSegmentRepo(ctrl)
+			tt.segmentRepoBehave(mockSegmentRepo)
+
+			s := &UserService{
+				segmentRepo: mockSegmentRepo,
+			}
+			if err := s.validateSegments(context.Background(), userSegment); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UserService.validateSegments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_findAbsenceInSecondSlice(t *testing.T) {
 	longer := []string{"a", "b", "c"}
 	smaller := []string{"a", "b"}
@@ -341,3 +1081,103 @@ func Test_findAbsenceInSecondSlice(t *testing.T) {
 		})
 	}
 }
+
+func Test_segmentExpirations(t *testing.T) {
+	now := time.Date(2023, 8, 31, 12, 0, 0, 0, time.UTC)
+	expirationTime := now.Add(time.Hour)
+	dayTTL := model.TTL(24 * time.Hour)
+	weekTTL := model.TTL(7 * 24 * time.Hour)
+	inDay := now.Add(24 * time.Hour)
+	inWeek := now.Add(7 * 24 * time.Hour)
+	inTwoDays := now.Add(48 * time.Hour)
+	hourAgo := now.Add(-time.Hour)
+	tests := []struct {
+		name        string
+		userSegment model.UserSegmentAction
+		want        []model.SegmentExpiration
+	}{
+		{
+			name: "default ttl of segment",
+			userSegment: model.UserSegmentAction{
+				SegmentsSlugsToAdd: []string{"AVITO_TECH"},
+			},
+			want: []model.SegmentExpiration{
+				{SegmentSlug: "AVITO_TECH", UseDefaultTTL: true},
+			},
+		},
+		{
+			name: "common ttl",
+			userSegment: model.UserSegmentAction{
+				SegmentsSlugsToAdd: []string{"AVITO_TECH"},
+				TTL:                &dayTTL,
+			},
+			want: []model.SegmentExpiration{
+				{SegmentSlug: "AVITO_TECH", ExpirationTime: &inDay},
+			},
+		},
+		{
+			name: "ttl counts from start time",
+			userSegment: model.UserSegmentAction{
+				SegmentsSlugsToAdd: []string{"AVITO_TECH", "AVITO_DISCOUNT_30"},
+				StartTime:          &inDay,
+				SegmentTTLs: map[string]model.TTL{
+					"AVITO_DISCOUNT_30": dayTTL,
+				},
+			},
+			want: []model.SegmentExpiration{
+				{SegmentSlug: "AVITO_TECH", UseDefaultTTL: true, StartTime: &inDay},
+				{SegmentSlug: "AVITO_DISCOUNT_30", ExpirationTime: &inTwoDays, StartTime: &inDay},
+			},
+		},
+		{
+			name: "start time in the past",
+			userSegment: model.UserSegmentAction{
+				SegmentsSlugsToAdd: []string{"AVITO_TECH"},
+				StartTime:          &hourAgo,
+				TTL:                &dayTTL,
+			},
+			want: []model.SegmentExpiration{
+				{SegmentSlug: "AVITO_TECH", ExpirationTime: &inDay},
+			},
+		},
+		{
+			name: "segment expirations take precedence",
+			userSegment: model.UserSegmentAction{
+				SegmentsSlugsToAdd:    []string{"AVITO_TECH", "AVITO_DISCOUNT_30", "AVITO_DISCOUNT_11"},
+				SegmentExpirationTime: &expirationTime,
+				SegmentExpirations: map[string]*time.Time{
+					"AVITO_DISCOUNT_11": nil,
+				},
+				SegmentTTLs: map[string]model.TTL{
+					"AVITO_DISCOUNT_30": weekTTL,
+				},
+			},
+			want: []model.SegmentExpiration{
+				{SegmentSlug: "AVITO_TECH", ExpirationTime: &expirationTime},
+				{SegmentSlug: "AVITO_DISCOUNT_30", ExpirationTime: &inWeek},
+				{SegmentSlug: "AVITO_DISCOUNT_11", ExpirationTime: nil},
+			},
+		},
+		{
+			name: "no expiration instead of the default ttl",
+			userSegment: model.UserSegmentAction{
+				SegmentsSlugsToAdd: []string{"AVITO_TECH", "AVITO_DISCOUNT_30"},
+				SegmentTTLs: map[string]model.TTL{
+					"AVITO_DISCOUNT_30": dayTTL,
+				},
+				NoExpiration: true,
+			},
+			want: []model.SegmentExpiration{
+				{SegmentSlug: "AVITO_TECH", ExpirationTime: nil},
+				{SegmentSlug: "AVITO_DISCOUNT_30", ExpirationTime: &inDay},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := segmentExpirations(tt.userSegment, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("segmentExpirations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+	// resultSkipped counts ticks on which another replica held the leader lock.
+	resultSkipped = "skipped"
+)
+
+var metrics = struct {
+	runs        *prometheus.CounterVec
+	processed   *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	leader      *prometheus.GaugeVec
+	lastSuccess *prometheus.GaugeVec
+}{
+	runs: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "segmentation_worker_runs_total",
+		Help: "Number of worker runs by result.",
+	}, []string{"worker", "result"}),
+	processed: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "segmentation_worker_processed_total",
+		Help: "Number of items processed by the worker.",
+	}, []string{"worker"}),
+	duration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "segmentation_worker_run_duration_seconds",
+		Help: "Duration of worker runs.",
+	}, []string{"worker"}),
+	leader: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "segmentation_worker_leader",
+		Help: "1 if this replica holds the leader lock of the worker.",
+	}, []string{"worker"}),
+	lastSuccess: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "segmentation_worker_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful run of the worker.",
+	}, []string{"worker"}),
+}
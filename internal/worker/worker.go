@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/elgntt/segmentation-service/internal/pkg/audit"
+	"github.com/google/uuid"
+)
+
+// unlockTimeout bounds releasing the leader lock when the worker stops.
+const unlockTimeout = 5 * time.Second
+
+// Locker elects the replica that runs a worker.
+type Locker interface {
+	// TryLock reports whether this replica holds the lock, taking it if it is free.
+	TryLock(ctx context.Context) (bool, error)
+	Unlock(ctx context.Context) error
+}
+
+// RunFunc does one run of a worker and returns the number of processed items.
+type RunFunc func(ctx context.Context) (int, error)
+
+// Worker calls its RunFunc when it starts and then every interval until the context is cancelled.
+type Worker struct {
+	name     string
+	interval time.Duration
+	locker   Locker
+	run      RunFunc
+}
+
+// New creates a worker. With a locker the worker runs only on the replica holding the lock, a nil locker runs it on every replica.
+func New(name string, interval time.Duration, locker Locker, run RunFunc) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		locker:   locker,
+		run:      run,
+	}
+}
+
+// Run blocks until ctx is cancelled. A run in progress gets the cancelled context and Run returns after it is over.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	if w.locker != nil {
+		defer w.unlock()
+	}
+
+	w.runOnce(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.runOnce(ctx)
+		}
+	}
+}
+
+func (w *Worker) runOnce(ctx context.Context) {
+	if w.locker != nil {
+		leader, err := w.locker.TryLock(ctx)
+		if err != nil {
+			log.Printf("Worker %s leader election err: %v", w.name, err)
+		}
+		if !leader {
+			metrics.leader.WithLabelValues(w.name).Set(0)
+			metrics.runs.WithLabelValues(w.name, resultSkipped).Inc()
+			return
+		}
+		metrics.leader.WithLabelValues(w.name).Set(1)
+	}
+
+	// everything changed in one run shares a correlation id
+	runCtx := audit.WithMeta(ctx, audit.Meta{RequestID: uuid.NewString()})
+
+	startedAt := time.Now()
+	processed, err := w.run(runCtx)
+	metrics.duration.WithLabelValues(w.name).Observe(time.Since(startedAt).Seconds())
+	metrics.processed.WithLabelValues(w.name).Add(float64(processed))
+	if err != nil {
+		metrics.runs.WithLabelValues(w.name, resultError).Inc()
+		log.Printf("Worker %s err: %v", w.name, err)
+		return
+	}
+
+	metrics.runs.WithLabelValues(w.name, resultSuccess).Inc()
+	metrics.lastSuccess.WithLabelValues(w.name).SetToCurrentTime()
+	if processed > 0 {
+		log.Printf("Worker %s processed: %d", w.name, processed)
+	}
+}
+
+func (w *Worker) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancel()
+
+	if err := w.locker.Unlock(ctx); err != nil {
+		log.Printf("Worker %s unlock err: %v", w.name, err)
+	}
+	metrics.leader.WithLabelValues(w.name).Set(0)
+}